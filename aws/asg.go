@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// launchTemplateAddresses returns the addresses of the launch templates of launch_template blocks
+// (Auto Scaling Groups, EKS node groups)
+func launchTemplateAddresses(blocks []ASGLaunchTemplate) []string {
+	var launchTemplates []string
+	for _, lt := range blocks {
+		if lt.ID != nil && *lt.ID != "" {
+			launchTemplates = append(launchTemplates, referencedResource(*lt.ID))
+		} else if lt.Name != nil && *lt.Name != "" {
+			launchTemplates = append(launchTemplates, referencedResource(*lt.Name))
+		}
+	}
+	return launchTemplates
+}
+
+// asgLaunchTemplates returns the addresses of the launch templates used by an Auto Scaling Group
+func asgLaunchTemplates(asg AutoscalingGroup) []string {
+	launchTemplates := launchTemplateAddresses(asg.LaunchTemplate)
+	for _, policy := range asg.MixedInstancesPolicy {
+		for _, lt := range policy.LaunchTemplate {
+			for _, spec := range lt.LaunchTemplateSpecification {
+				if spec.LaunchTemplateID != nil && *spec.LaunchTemplateID != "" {
+					launchTemplates = append(launchTemplates, referencedResource(*spec.LaunchTemplateID))
+				} else if spec.LaunchTemplateName != nil && *spec.LaunchTemplateName != "" {
+					launchTemplates = append(launchTemplates, referencedResource(*spec.LaunchTemplateName))
+				}
+			}
+		}
+	}
+	return launchTemplates
+}
+
+// asgSecurityGroups returns the SGs of the instances launched by an Auto Scaling Group, from its launch
+// templates or its launch configuration
+func (a *Data) asgSecurityGroups(asg AutoscalingGroup) []string {
+	SGs := a.launchTemplateSecurityGroups(asgLaunchTemplates(asg))
+	if asg.LaunchConfiguration != nil {
+		if lc, found := a.LaunchConfiguration[referencedResource(*asg.LaunchConfiguration)]; found && lc.SecurityGroups != nil {
+			SGs = append(SGs, *lc.SecurityGroups...)
+		}
+	}
+	return utils.RemoveDuplicateValues(SGs)
+}
+
+// launchTemplateSecurityGroups returns the SGs of launch templates
+func (a *Data) launchTemplateSecurityGroups(launchTemplates []string) []string {
+	var SGs []string
+	for _, ltAddress := range launchTemplates {
+		lt, found := a.LaunchTemplate[ltAddress]
+		if !found {
+			continue
+		}
+		if lt.VpcSecurityGroupIDs != nil {
+			SGs = append(SGs, *lt.VpcSecurityGroupIDs...)
+		}
+		if lt.SecurityGroupNames != nil {
+			SGs = append(SGs, *lt.SecurityGroupNames...)
+		}
+		for _, ni := range lt.NetworkInterfaces {
+			if ni.SecurityGroups != nil {
+				SGs = append(SGs, *ni.SecurityGroups...)
+			}
+		}
+	}
+	return SGs
+}
+
+// asgSubnets returns the subnets of an Auto Scaling Group. If vpc_zone_identifier is not set, the subnets of the
+// network interfaces of its launch templates are used
+func (a *Data) asgSubnets(asg AutoscalingGroup) []string {
+	if asg.VpcZoneIdentifier != nil && len(*asg.VpcZoneIdentifier) > 0 {
+		return *asg.VpcZoneIdentifier
+	}
+	var subnets []string
+	for _, ltAddress := range asgLaunchTemplates(asg) {
+		for _, ni := range a.LaunchTemplate[ltAddress].NetworkInterfaces {
+			if ni.SubnetID != nil {
+				subnets = append(subnets, *ni.SubnetID)
+			}
+		}
+	}
+	return subnets
+}
+
+// linkAutoscalingGroups links the Auto Scaling Groups to the SGs of their launch templates / configurations.
+// It must be called once all resources are parsed
+func (a *Data) linkAutoscalingGroups() {
+	for asgAddress, asg := range a.AutoscalingGroup {
+		SGs := a.asgSecurityGroups(asg)
+		a.linkSecurityGroups(asgAddress, &SGs)
+	}
+}
+
+// autoscalingGroupTargets returns the Auto Scaling Groups registered in a target group or a Classic LB
+func (a *Data) autoscalingGroupTargets(lbAddress string) []string {
+	var asgs []string
+	for asgAddress, asg := range a.AutoscalingGroup {
+		for _, list := range []*[]string{asg.TargetGroupArns, asg.LoadBalancers} {
+			if list == nil {
+				continue
+			}
+			for _, lb := range *list {
+				if referencedResource(lb) == lbAddress {
+					asgs = append(asgs, asgAddress)
+				}
+			}
+		}
+	}
+	for _, attachment := range a.AutoscalingAttachment {
+		for _, lb := range []*string{attachment.LBTargetGroupArn, attachment.ALBTargetGroupArn, attachment.ELB} {
+			if lb != nil && referencedResource(*lb) == lbAddress {
+				asgs = append(asgs, referencedResource(attachment.AutoscalingGroupName))
+			}
+		}
+	}
+	return utils.RemoveDuplicateValues(asgs)
+}
+
+// asgLabel formats the label of an Auto Scaling Group node: its name and its size
+func asgLabel(name string, asg AutoscalingGroup) string {
+	label := strings.Join(utils.ChunkString(name, 8), "\n")
+	if asg.MinSize != nil && asg.MaxSize != nil {
+		label += fmt.Sprintf("\n(ASG %d-%d)", *asg.MinSize, *asg.MaxSize)
+	} else {
+		label += "\n(ASG)"
+	}
+	return label
+}
+
+func (a *Data) createAutoscalingGroup(graph *model.Graph, asgAddress string, asg AutoscalingGroup) (error) {
+	// Create Auto Scaling Group nodes (one per subnet)
+	modulePath, _, asgName := utils.SplitAddress(asgAddress)
+	if asg.Name != nil && *asg.Name != "" {
+		asgName = *asg.Name
+	}
+	subnets := a.asgSubnets(asg)
+	a.log.Verbosef("Create Auto Scaling Group %s in %d subnet(s)", asgAddress, len(subnets))
+
+	return a.createSubnetNodes(graph, iconNode(asgAddress, asgLabel(asgName, asg), "asg.png"), subnets, a.moduleGroup(modulePath))
+}
+
+// createAutoscalingGroupEdges creates the edges of the SGs of the Auto Scaling Groups, like for instances
+func (a *Data) createAutoscalingGroupEdges(graph *model.Graph) (error) {
+	for asgAddress, asg := range a.AutoscalingGroup {
+		SGs := a.asgSecurityGroups(asg)
+		if len(SGs) == 0 {
+			// The instances launched without SG inherit from the default SG
+			for _, nodeName := range a.graphNodes(asgAddress) {
+				err := a.linkDefaultSecurityGroup(graph, nodeName)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		a.parseSGRules(asgAddress, SGs, graph)
+	}
+	return nil
+}
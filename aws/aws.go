@@ -2,35 +2,64 @@ package aws
 
 import (
 	"fmt"
-	"io/ioutil"
 	"net"
-	"os"
-	"path"
+	"sort"
 	"strings"
 
-	tfconfigs "github.com/hashicorp/terraform/configs"
 	hcl2 "github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/gohcl"
-	"github.com/zclconf/go-cty/cty"
-	"github.com/awalterschulze/gographviz"
+	"github.com/hashicorp/hcl/v2/ext/dynblock"
 
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/provider"
 	"github.com/steeve85/tfviz/utils"
 )
 
 
-// IgnoreIngress can be used to not create edges for Ingress rules
-var IgnoreIngress bool
-
-// IgnoreEgress can be used to not create edges for Egress rules
-var IgnoreEgress bool
-
-// Verbose enables verbose mode if set to true
-var Verbose bool
+// referencedAttributes lists the computed attributes (other than id / arn) used to reference other resources.
+// They are set to "<resource address>.<attribute>" in the EvalContext
+var referencedAttributes = map[string][]string{
+	"aws_vpc":	{"main_route_table_id", "default_route_table_id", "default_network_acl_id", "default_security_group_id"},
+	"aws_elb":	{"name"},
+	"aws_autoscaling_group":	{"name"},
+	"aws_launch_template":	{"name", "latest_version", "default_version"},
+	"aws_launch_configuration":	{"name"},
+	"aws_db_subnet_group":	{"name"},
+	"aws_rds_cluster":	{"cluster_identifier"},
+	"aws_elasticache_replication_group":	{"replication_group_id"},
+	"aws_elasticache_subnet_group":	{"name"},
+	"aws_redshift_subnet_group":	{"name"},
+	"aws_lambda_function":	{"function_name", "invoke_arn", "qualified_arn"},
+	"aws_api_gateway_rest_api":	{"execution_arn"},
+	"aws_apigatewayv2_api":	{"execution_arn"},
+	"aws_ecs_cluster":	{"name"},
+	"aws_ecs_task_definition":	{"family"},
+	"aws_eks_cluster":	{"name"},
+	"aws_ec2_transit_gateway":	{"association_default_route_table_id", "propagation_default_route_table_id"},
+	"aws_vpn_connection":	{"transit_gateway_attachment_id"},
+	"aws_vpc_endpoint":	{"prefix_list_id"},
+	"aws_vpc_endpoint_service":	{"service_name"},
+}
 
 // Defining values for ingress / egress rules
 const ingressRule = 1
 const egressRule = 2
 
+// moduleGroup returns the group in which the top level resources of a module are drawn ("" for the top level)
+func (a *Data) moduleGroup(modulePath string) string {
+	if !a.options.ModuleClusters || modulePath == "" {
+		return ""
+	}
+	return utils.NodeID(modulePath)
+}
+
+// realIDLabel formats the real ID of a resource (known from plans and states) to be added to a label
+func realIDLabel(realID string) string {
+	if realID == "" {
+		return ""
+	}
+	return " (" + realID + ")"
+}
+
 // Data is a structure that contain maps of TF parsed resources
 type Data struct {
 	defaultVpc				bool
@@ -41,14 +70,86 @@ type Data struct {
 	Instance				map[string]Instance
 	DBInstance				map[string]DBInstance
 	DBSubnetGroup 			map[string]DBSubnetGroup
+	RDSCluster				map[string]RDSCluster
+	RDSClusterInstance		map[string]RDSClusterInstance
+	ElastiCacheCluster		map[string]ElastiCacheCluster
+	ElastiCacheReplicationGroup	map[string]ElastiCacheReplicationGroup
+	ElastiCacheSubnetGroup	map[string]DBSubnetGroup
+	RedshiftCluster			map[string]RedshiftCluster
+	RedshiftSubnetGroup		map[string]DBSubnetGroup
+	OpenSearchDomain		map[string]OpenSearchDomain
+	LambdaFunction			map[string]LambdaFunction
+	LambdaEventSourceMapping	map[string]LambdaEventSourceMapping
+	LambdaPermission		map[string]LambdaPermission
+	S3BucketNotification	map[string]S3BucketNotification
+	APIGateway				map[string]APIGateway
+	APIGatewayIntegration	map[string]APIGatewayIntegration
+	ECSCluster				map[string]ECSCluster
+	ECSService				map[string]ECSService
+	ECSTaskDefinition		map[string]ECSTaskDefinition
+	EKSCluster				map[string]EKSCluster
+	EKSNodeGroup			map[string]EKSNodeGroup
+	VpcPeeringConnection	map[string]VpcPeeringConnection
+	TransitGateway			map[string]TransitGateway
+	TransitGatewayVpcAttachment	map[string]TransitGatewayVpcAttachment
+	TransitGatewayRouteTable	map[string]TransitGatewayRouteTable
+	TransitGatewayRoute		map[string]TransitGatewayRoute
+	VpnGateway				map[string]VpnGateway
+	VpnGatewayAttachment	map[string]VpnGatewayAttachment
+	CustomerGateway			map[string]CustomerGateway
+	VpnConnection			map[string]VpnConnection
+	VpnConnectionRoute		map[string]VpnConnectionRoute
+	VpcEndpoint				map[string]VpcEndpoint
+	VpcEndpointAssociation	map[string]VpcEndpointAssociation
+	VpcEndpointService		map[string]VpcEndpointService
 	SecurityGroup			map[string]SecurityGroup
 	S3						map[string]S3
+	InternetGateway			map[string]InternetGateway
+	EgressOnlyInternetGateway	map[string]InternetGateway
+	NatGateway				map[string]NatGateway
+	// Route tables are indexed by their ID (the address of aws_route_table resources, or
+	// <vpc address>.main_route_table_id for the main route table of a VPC)
+	RouteTable				map[string]RouteTable
+	RouteTableAssociation	map[string]RouteTableAssociation
+	MainRouteTableAssociation	map[string]MainRouteTableAssociation
+	// Network ACLs are indexed by their ID (the address of aws_network_acl resources, or
+	// <vpc address>.default_network_acl_id for the default network ACL of a VPC)
+	NetworkACL				map[string]NetworkACL
+	NetworkACLAssociation	map[string]NetworkACLAssociation
+	LB						map[string]LB
+	ELB						map[string]ELB
+	LBListener				map[string]LBListener
+	LBTargetGroup			map[string]LBTargetGroup
+	LBTargetGroupAttachment	map[string]LBTargetGroupAttachment
+	AutoscalingGroup		map[string]AutoscalingGroup
+	AutoscalingAttachment	map[string]AutoscalingAttachment
+	LaunchTemplate			map[string]LaunchTemplate
+	LaunchConfiguration		map[string]LaunchConfiguration
 	// list of security groups not defined in the TF module
 	undefinedSecurityGroups		[]string
 	// map of resources linked to a security group
 	SecurityGroupNodeLinks	map[string][]string
-	// list of unsupported resources
-	unsupportedResources	[]string
+	// real IDs of the resources (from plans and states), indexed by resource address
+	resourceIDs				map[string]string
+	// security group rules declared as standalone resources (merged in SecurityGroup once parsed)
+	standaloneSGRules		[]standaloneSGRule
+	// rules of security groups not defined in the TF module, indexed by security group ID
+	undefinedSecurityGroupRules	map[string]SecurityGroup
+	// routes declared as standalone resources (merged in RouteTable once parsed)
+	standaloneRoutes		[]standaloneRoute
+	// network ACL rules declared as standalone resources (merged in NetworkACL once parsed)
+	standaloneNACLRules		[]standaloneNACLRule
+	// subnet of the nodes (indexed by node ID) used to know how they reach the Internet
+	nodeSubnets				map[string]string
+	// nodes of the resources spanning several subnets (one node per subnet), indexed by resource address
+	nodeCopies				map[string][]string
+	// edges created from SG rules (in creation order) and indexed by "src -> dst"
+	sgEdges					[]*model.Edge
+	sgEdgesIndex			map[string]*model.Edge
+	// options of the graph (ingress / egress rules, module clusters)
+	options					provider.Options
+	// log collects the diagnostics of the graph
+	log						*utils.Logger
 }
 
 // Vpc is a structure for AWS VPC resources
@@ -109,12 +210,479 @@ type DBInstance struct {
 
 // DBSubnetGroup is a structure for RDS DB subnet group resources
 type DBSubnetGroup struct {
+	// The name of the DB subnet group
+	Name					*string `hcl:"name"`
 	// A list of VPC subnet IDs
 	SubnetIDs				[]string `hcl:"subnet_ids"`
 	// Other arguments
 	Remain					hcl2.Body `hcl:",remain"`
 }
 
+// RDSCluster is a structure for AWS RDS (Aurora) cluster resources
+type RDSCluster struct {
+	// The cluster identifier
+	ClusterIdentifier		*string `hcl:"cluster_identifier"`
+	// The name of the database engine to be used for this DB cluster
+	Engine					*string `hcl:"engine"`
+	// A DB subnet group to associate with this DB cluster
+	DBSubnetGroupName		*string `hcl:"db_subnet_group_name"`
+	// List of VPC security groups to associate with the Cluster
+	VpcSecurityGroupIDs		*[]string `hcl:"vpc_security_group_ids"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// RDSClusterInstance is a structure for AWS RDS (Aurora) cluster instance resources
+type RDSClusterInstance struct {
+	// The identifier for the RDS instance
+	Identifier				*string `hcl:"identifier"`
+	// The identifier of the aws_rds_cluster in which to launch this instance
+	ClusterIdentifier		string `hcl:"cluster_identifier"`
+	// The instance class to use
+	InstanceClass			*string `hcl:"instance_class"`
+	// A DB subnet group to associate with this DB instance
+	DBSubnetGroupName		*string `hcl:"db_subnet_group_name"`
+	// Bool to control if instance is publicly accessible
+	PubliclyAccessible		*bool `hcl:"publicly_accessible"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ElastiCacheCluster is a structure for AWS ElastiCache cluster resources
+type ElastiCacheCluster struct {
+	// Group identifier
+	ClusterID				*string `hcl:"cluster_id"`
+	// Name of the cache engine to be used for this cache cluster
+	Engine					*string `hcl:"engine"`
+	// ID of the replication group to which this cluster should belong
+	ReplicationGroupID		*string `hcl:"replication_group_id"`
+	// Name of the subnet group to be used for the cache cluster
+	SubnetGroupName			*string `hcl:"subnet_group_name"`
+	// One or more VPC security groups associated with the cache cluster
+	SecurityGroupIDs		*[]string `hcl:"security_group_ids"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ElastiCacheReplicationGroup is a structure for AWS ElastiCache replication group resources
+type ElastiCacheReplicationGroup struct {
+	// The replication group identifier
+	ReplicationGroupID		*string `hcl:"replication_group_id"`
+	// Name of the cache engine to be used for the clusters in this replication group
+	Engine					*string `hcl:"engine"`
+	// The name of the cache subnet group to be used for the replication group
+	SubnetGroupName			*string `hcl:"subnet_group_name"`
+	// One or more Amazon VPC security groups associated with this replication group
+	SecurityGroupIDs		*[]string `hcl:"security_group_ids"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// RedshiftCluster is a structure for AWS Redshift cluster resources
+type RedshiftCluster struct {
+	// The Cluster Identifier
+	ClusterIdentifier		*string `hcl:"cluster_identifier"`
+	// The node type to be provisioned for the cluster
+	NodeType				*string `hcl:"node_type"`
+	// The name of a cluster subnet group to be associated with this cluster
+	ClusterSubnetGroupName	*string `hcl:"cluster_subnet_group_name"`
+	// A list of Virtual Private Cloud (VPC) security groups to be associated with the cluster
+	VpcSecurityGroupIDs		*[]string `hcl:"vpc_security_group_ids"`
+	// If true, the cluster can be accessed from a public network
+	PubliclyAccessible		*bool `hcl:"publicly_accessible"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// OpenSearchDomain is a structure for AWS OpenSearch / Elasticsearch domain resources
+type OpenSearchDomain struct {
+	// Name of the domain
+	DomainName				string `hcl:"domain_name"`
+	// Configuration block for VPC related options
+	VpcOptions				[]struct {
+		// List of VPC Subnet IDs for the domain endpoints to be created in
+		SubnetIDs			*[]string `hcl:"subnet_ids"`
+		// List of VPC Security Group IDs to be applied to the domain endpoints
+		SecurityGroupIDs	*[]string `hcl:"security_group_ids"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"vpc_options,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LambdaFunction is a structure for AWS Lambda function resources
+type LambdaFunction struct {
+	// Unique name for the Lambda Function
+	FunctionName			*string `hcl:"function_name"`
+	// Identifier of the function's runtime
+	Runtime					*string `hcl:"runtime"`
+	// Configuration block to run the function in a VPC
+	VpcConfig				[]struct {
+		// List of subnet IDs associated with the Lambda function
+		SubnetIDs			[]string `hcl:"subnet_ids"`
+		// List of security group IDs associated with the Lambda function
+		SecurityGroupIDs	[]string `hcl:"security_group_ids"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"vpc_config,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LambdaEventSourceMapping is a structure for AWS Lambda event source mapping resources
+type LambdaEventSourceMapping struct {
+	// The event source ARN (Kinesis stream, DynamoDB stream, SQS queue, MQ broker or MSK cluster)
+	EventSourceArn			*string `hcl:"event_source_arn"`
+	// The name or the ARN of the Lambda function that will be subscribing to events
+	FunctionName			string `hcl:"function_name"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LambdaPermission is a structure for AWS Lambda permission resources
+type LambdaPermission struct {
+	// Name of the Lambda function whose resource policy you are updating
+	FunctionName			string `hcl:"function_name"`
+	// The principal who is getting this permission
+	Principal				string `hcl:"principal"`
+	// The ARN of the resource allowed to invoke the function
+	SourceArn				*string `hcl:"source_arn"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// S3BucketNotification is a structure for AWS S3 bucket notification resources
+type S3BucketNotification struct {
+	// The name of the bucket for notification configuration
+	Bucket					string `hcl:"bucket"`
+	// Used to configure notifications to a Lambda Function
+	LambdaFunction			[]struct {
+		// The Lambda function ARN
+		LambdaFunctionArn	*string `hcl:"lambda_function_arn"`
+		// Specifies event for which to send notifications
+		Events				[]string `hcl:"events"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"lambda_function,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// APIGateway is a structure for AWS API Gateway REST (v1) and HTTP / WebSocket (v2) API resources
+type APIGateway struct {
+	// The name of the API
+	Name					*string `hcl:"name"`
+	// The API protocol (v2 only): HTTP or WEBSOCKET
+	ProtocolType			*string `hcl:"protocol_type"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// APIGatewayIntegration is a structure for AWS API Gateway (v1 and v2) integration resources
+type APIGatewayIntegration struct {
+	// The ID of the associated REST API (v1)
+	RestAPIID				*string `hcl:"rest_api_id"`
+	// The HTTP method of the API method (v1)
+	HTTPMethod				*string `hcl:"http_method"`
+	// The input's URI (v1)
+	URI						*string `hcl:"uri"`
+	// The API identifier (v2)
+	APIID					*string `hcl:"api_id"`
+	// The URI of the Lambda function for a Lambda proxy integration (v2)
+	IntegrationURI			*string `hcl:"integration_uri"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ECSCluster is a structure for AWS ECS cluster resources
+type ECSCluster struct {
+	// The name of the cluster
+	Name					string `hcl:"name"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ECSService is a structure for AWS ECS service resources
+type ECSService struct {
+	// The name of the service
+	Name					string `hcl:"name"`
+	// ARN of an ECS cluster
+	Cluster					*string `hcl:"cluster"`
+	// The family and revision (family:revision) or full ARN of the task definition to run in the service
+	TaskDefinition			*string `hcl:"task_definition"`
+	// The number of instances of the task definition to place and keep running
+	DesiredCount			*int `hcl:"desired_count"`
+	// The launch type on which to run the service (EC2, FARGATE or EXTERNAL)
+	LaunchType				*string `hcl:"launch_type"`
+	// The network configuration for the service (awsvpc network mode)
+	NetworkConfiguration	[]struct {
+		// The subnets associated with the task or service
+		Subnets				[]string `hcl:"subnets"`
+		// The security groups associated with the task or service
+		SecurityGroups		*[]string `hcl:"security_groups"`
+		// Assign a public IP address to the ENI (Fargate launch type only)
+		AssignPublicIP		*bool `hcl:"assign_public_ip"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"network_configuration,block"`
+	// Load balancers blocks
+	LoadBalancer			[]struct {
+		// The name of the ELB (Classic) to associate with the service
+		ELBName				*string `hcl:"elb_name"`
+		// The ARN of the Load Balancer target group to associate with the service
+		TargetGroupArn		*string `hcl:"target_group_arn"`
+		// The name of the container to associate with the load balancer
+		ContainerName		string `hcl:"container_name"`
+		// The port on the container to associate with the load balancer
+		ContainerPort		int `hcl:"container_port"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"load_balancer,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ECSTaskDefinition is a structure for AWS ECS task definition resources
+type ECSTaskDefinition struct {
+	// A unique name for your task definition
+	Family					string `hcl:"family"`
+	// A list of valid container definitions provided as a single valid JSON document
+	ContainerDefinitions	string `hcl:"container_definitions"`
+	// The Docker networking mode to use for the containers in the task
+	NetworkMode				*string `hcl:"network_mode"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ECSContainerDefinition is a container of an ECS task definition (container_definitions JSON document)
+type ECSContainerDefinition struct {
+	// The name of the container
+	Name					string `json:"name"`
+	// The port mappings of the container
+	PortMappings			[]struct {
+		// The port number on the container
+		ContainerPort		int `json:"containerPort"`
+		// The protocol used for the port mapping (tcp or udp)
+		Protocol			string `json:"protocol"`
+	} `json:"portMappings"`
+}
+
+// EKSCluster is a structure for AWS EKS cluster resources
+type EKSCluster struct {
+	// Name of the cluster
+	Name					string `hcl:"name"`
+	// Configuration block for the VPC associated with your cluster
+	VpcConfig				[]struct {
+		// List of subnet IDs
+		SubnetIDs			[]string `hcl:"subnet_ids"`
+		// List of security group IDs for the cross-account elastic network interfaces
+		SecurityGroupIDs	*[]string `hcl:"security_group_ids"`
+		// Whether the Amazon EKS public API server endpoint is enabled (true by default)
+		EndpointPublicAccess	*bool `hcl:"endpoint_public_access"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"vpc_config,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// EKSNodeGroup is a structure for AWS EKS node group resources
+type EKSNodeGroup struct {
+	// Name of the EKS Cluster
+	ClusterName				string `hcl:"cluster_name"`
+	// Name of the EKS Node Group
+	NodeGroupName			*string `hcl:"node_group_name"`
+	// Identifiers of EC2 Subnets to associate with the EKS Node Group
+	SubnetIDs				[]string `hcl:"subnet_ids"`
+	// Configuration block with scaling settings
+	ScalingConfig			[]struct {
+		// Minimum number of worker nodes
+		MinSize				int `hcl:"min_size"`
+		// Maximum number of worker nodes
+		MaxSize				int `hcl:"max_size"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"scaling_config,block"`
+	// Configuration block with Launch Template settings
+	LaunchTemplate			[]ASGLaunchTemplate `hcl:"launch_template,block"`
+	// Configuration block with remote access settings
+	RemoteAccess			[]struct {
+		// Set of EC2 Security Group IDs to allow SSH access (port 22) from on the worker nodes
+		SourceSecurityGroupIDs	*[]string `hcl:"source_security_group_ids"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"remote_access,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VpcPeeringConnection is a structure for AWS VPC peering connection resources
+type VpcPeeringConnection struct {
+	// The ID of the requester VPC
+	VpcID					string `hcl:"vpc_id"`
+	// The ID of the VPC with which you are creating the VPC Peering Connection
+	PeerVpcID				string `hcl:"peer_vpc_id"`
+	// The AWS account ID of the owner of the peer VPC
+	PeerOwnerID				*string `hcl:"peer_owner_id"`
+	// The region of the accepter VPC of the VPC Peering Connection
+	PeerRegion				*string `hcl:"peer_region"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// TransitGateway is a structure for AWS Transit Gateway resources
+type TransitGateway struct {
+	// Description of the Transit Gateway
+	Description				*string `hcl:"description"`
+	// Private Autonomous System Number (ASN) for the Amazon side of a BGP session
+	AmazonSideAsn			*int `hcl:"amazon_side_asn"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// TransitGatewayVpcAttachment is a structure for AWS Transit Gateway VPC attachment resources
+type TransitGatewayVpcAttachment struct {
+	// Identifier of EC2 Transit Gateway
+	TransitGatewayID		string `hcl:"transit_gateway_id"`
+	// Identifier of EC2 VPC
+	VpcID					string `hcl:"vpc_id"`
+	// Identifiers of EC2 Subnets
+	SubnetIDs				[]string `hcl:"subnet_ids"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// TransitGatewayRouteTable is a structure for AWS Transit Gateway route table resources
+type TransitGatewayRouteTable struct {
+	// Identifier of EC2 Transit Gateway
+	TransitGatewayID		string `hcl:"transit_gateway_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// TransitGatewayRoute is a structure for AWS Transit Gateway route resources
+type TransitGatewayRoute struct {
+	// IPv4 or IPv6 RFC1924 CIDR used for destination matches
+	DestinationCidrBlock	string `hcl:"destination_cidr_block"`
+	// Identifier of EC2 Transit Gateway Attachment
+	TransitGatewayAttachmentID	*string `hcl:"transit_gateway_attachment_id"`
+	// Indicates whether to drop traffic that matches this route
+	Blackhole				*bool `hcl:"blackhole"`
+	// Identifier of EC2 Transit Gateway Route Table
+	TransitGatewayRouteTableID	string `hcl:"transit_gateway_route_table_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VpnGateway is a structure for AWS VPN gateway (Virtual Private Gateway) resources
+type VpnGateway struct {
+	// The VPC ID to create in
+	VpcID					*string `hcl:"vpc_id"`
+	// The Autonomous System Number (ASN) for the Amazon side of the gateway
+	AmazonSideAsn			*string `hcl:"amazon_side_asn"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VpnGatewayAttachment is a structure for AWS VPN gateway attachment resources
+type VpnGatewayAttachment struct {
+	// The ID of the VPC
+	VpcID					string `hcl:"vpc_id"`
+	// The ID of the Virtual Private Gateway
+	VpnGatewayID			string `hcl:"vpn_gateway_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// CustomerGateway is a structure for AWS customer gateway resources
+type CustomerGateway struct {
+	// The IPv4 address for the customer gateway device's outside interface
+	IPAddress				*string `hcl:"ip_address"`
+	// The gateway's Border Gateway Protocol (BGP) Autonomous System Number (ASN)
+	BgpAsn					*string `hcl:"bgp_asn"`
+	// The type of customer gateway (ipsec.1)
+	Type					string `hcl:"type"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VpnConnection is a structure for AWS Site-to-Site VPN connection resources
+type VpnConnection struct {
+	// The ID of the customer gateway
+	CustomerGatewayID		string `hcl:"customer_gateway_id"`
+	// The ID of the Virtual Private Gateway
+	VpnGatewayID			*string `hcl:"vpn_gateway_id"`
+	// The ID of the EC2 Transit Gateway
+	TransitGatewayID		*string `hcl:"transit_gateway_id"`
+	// The type of VPN connection (ipsec.1)
+	Type					string `hcl:"type"`
+	// Whether the VPN connection uses static routes exclusively
+	StaticRoutesOnly		*bool `hcl:"static_routes_only"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VpnConnectionRoute is a structure for AWS VPN connection route resources
+type VpnConnectionRoute struct {
+	// The CIDR block associated with the local subnet of the customer network
+	DestinationCidrBlock	string `hcl:"destination_cidr_block"`
+	// The ID of the VPN connection
+	VpnConnectionID			string `hcl:"vpn_connection_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VpcEndpoint is a structure for AWS VPC endpoint resources
+type VpcEndpoint struct {
+	// The ID of the VPC in which the endpoint will be used
+	VpcID					string `hcl:"vpc_id"`
+	// The service name (e.g. com.amazonaws.us-east-1.s3)
+	ServiceName				string `hcl:"service_name"`
+	// The VPC endpoint type: Gateway (default), Interface or GatewayLoadBalancer
+	VpcEndpointType			*string `hcl:"vpc_endpoint_type"`
+	// One or more route table IDs (Gateway endpoints)
+	RouteTableIDs			*[]string `hcl:"route_table_ids"`
+	// The ID of one or more subnets in which to create a network interface for the endpoint (Interface and
+	// GatewayLoadBalancer endpoints)
+	SubnetIDs				*[]string `hcl:"subnet_ids"`
+	// The ID of one or more security groups to associate with the network interface (Interface endpoints)
+	SecurityGroupIDs		*[]string `hcl:"security_group_ids"`
+	// Whether or not to associate a private hosted zone with the specified VPC (Interface endpoints)
+	PrivateDNSEnabled		*bool `hcl:"private_dns_enabled"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VpcEndpointAssociation is a structure for AWS VPC endpoint route table, subnet and security group association
+// resources
+type VpcEndpointAssociation struct {
+	// The ID of the VPC endpoint
+	VpcEndpointID			string `hcl:"vpc_endpoint_id"`
+	// The ID of the route table (aws_vpc_endpoint_route_table_association)
+	RouteTableID			*string `hcl:"route_table_id"`
+	// The ID of the subnet (aws_vpc_endpoint_subnet_association)
+	SubnetID				*string `hcl:"subnet_id"`
+	// The ID of the security group (aws_vpc_endpoint_security_group_association)
+	SecurityGroupID			*string `hcl:"security_group_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VpcEndpointService is a structure for AWS VPC endpoint service (PrivateLink) resources
+type VpcEndpointService struct {
+	// Whether or not VPC endpoint connection requests to the service must be accepted by the service owner
+	AcceptanceRequired		bool `hcl:"acceptance_required"`
+	// Amazon Resource Names (ARNs) of one or more Network Load Balancers for the endpoint service
+	NetworkLoadBalancerArns	*[]string `hcl:"network_load_balancer_arns"`
+	// Amazon Resource Names (ARNs) of one or more Gateway Load Balancers for the endpoint service
+	GatewayLoadBalancerArns	*[]string `hcl:"gateway_load_balancer_arns"`
+	// The ARNs of one or more principals allowed to discover the endpoint service
+	AllowedPrincipals		*[]string `hcl:"allowed_principals"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
 // SecurityGroup is a structure for AWS Security Group resources
 type SecurityGroup struct {
 	// The VPC ID
@@ -143,520 +711,1577 @@ type SGRule struct {
 	IPv6CidrBlocks			*[]string `hcl:"ipv6_cidr_blocks"`
 	// List of security group Group Names if using EC2-Classic, or Group IDs if using a VPC
 	SecurityGroups			*[]string `hcl:"security_groups"`
+	// List of prefix list IDs
+	PrefixListIDs			*[]string `hcl:"prefix_list_ids"`
 	// Other arguments
 	Remain					hcl2.Body `hcl:",remain"`
 }
 
-// S3 is a structure for AWS S3 bucket resources
-type S3 struct {
-	// The name of the bucket
-	Bucket					*string `hcl:"bucket"`
+// SecurityGroupRule is a structure for AWS Security Group Rule resources (aws_security_group_rule)
+type SecurityGroupRule struct {
+	// The type of rule being created: ingress or egress
+	Type					string `hcl:"type"`
+	// The security group to apply this rule to
+	SecurityGroupID			string `hcl:"security_group_id"`
+	// The start port (or ICMP type number if protocol is "icmp" or "icmpv6")
+	FromPort				int `hcl:"from_port"`
+	// The end port (or ICMP code if protocol is "icmp")
+	ToPort					int `hcl:"to_port"`
+	// The protocol.  icmp, icmpv6, tcp, udp, "-1" (all)
+	Protocol				string `hcl:"protocol"`
+	// List of CIDR blocks
+	CidrBlocks				*[]string `hcl:"cidr_blocks"`
+	// List of IPv6 CIDR blocks
+	IPv6CidrBlocks			*[]string `hcl:"ipv6_cidr_blocks"`
+	// List of prefix list IDs
+	PrefixListIDs			*[]string `hcl:"prefix_list_ids"`
+	// The security group id to allow access to/from
+	SourceSecurityGroupID	*string `hcl:"source_security_group_id"`
+	// If true, the security group itself will be added as a source to this ingress/egress rule
+	Self					*bool `hcl:"self"`
 	// Other arguments
 	Remain					hcl2.Body `hcl:",remain"`
 }
 
-func createDefaultVpc(graph *gographviz.Escape) (error) {
-	// Create default VPC cluster
-	if Verbose == true {
-		fmt.Println("[VERBOSE] AddSubGraph: cluster_aws_vpc_default // Create Default VPC")
-	}
-	err := graph.AddSubGraph("G", "cluster_aws_vpc_default", map[string]string{
-		"label": "VPC: default",
-	})
-	if err != nil {
-		return err
-	}
-	// Adding invisible node to VPC for links
-	if Verbose == true {
-		fmt.Println("[VERBOSE] AddNode: aws_vpc_default to cluster_aws_vpc_default")
-	}
-	err = graph.AddNode("cluster_aws_vpc_default", "aws_vpc_default", map[string]string{
-		"shape": "point",
-		"style": "invis",
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+// VpcSecurityGroupRule is a structure for AWS VPC Security Group Ingress/Egress Rule resources
+// (aws_vpc_security_group_ingress_rule / aws_vpc_security_group_egress_rule)
+type VpcSecurityGroupRule struct {
+	// The ID of the security group
+	SecurityGroupID			string `hcl:"security_group_id"`
+	// The start port (or ICMP type number if ip_protocol is "icmp" or "icmpv6")
+	FromPort				*int `hcl:"from_port"`
+	// The end port (or ICMP code if ip_protocol is "icmp")
+	ToPort					*int `hcl:"to_port"`
+	// The IP protocol name or number. "-1" (all)
+	IPProtocol				string `hcl:"ip_protocol"`
+	// The source / destination IPv4 CIDR range
+	CidrIPv4				*string `hcl:"cidr_ipv4"`
+	// The source / destination IPv6 CIDR range
+	CidrIPv6				*string `hcl:"cidr_ipv6"`
+	// The ID of the source / destination prefix list
+	PrefixListID			*string `hcl:"prefix_list_id"`
+	// The source / destination security group that is referenced in the rule
+	ReferencedSecurityGroupID	*string `hcl:"referenced_security_group_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
 }
 
-func createDefaultSubnet(graph *gographviz.Escape, clusterName string) (error) {
-	// Create default Subnet cluster
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddNode: cluster_aws_subnet_default to %s // Create Default Subnet\n", clusterName)
-	}
-	err := graph.AddSubGraph(clusterName, "cluster_aws_subnet_default", map[string]string{
-		"label": "Subnet: default",
-	})
-	if err != nil {
-		return err
-	}
+// standaloneSGRule is a rule declared outside of its security group, waiting to be merged in the group
+type standaloneSGRule struct {
+	// Address of the rule resource
+	Address					string
+	// The security group owning the rule
+	SecurityGroupID			string
+	// ingressRule or egressRule
+	RuleType				int
+	Rule					SGRule
+}
 
-	// Adding invisible node to VPC for links
-	if Verbose == true {
-		fmt.Println("[VERBOSE] AddNode: aws_subnet_default to cluster_aws_subnet_default")
-	}
-	err = graph.AddNode("cluster_aws_subnet_default", "aws_subnet_default", map[string]string{
-		"shape": "point",
-		"style": "invis",
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+// InternetGateway is a structure for AWS Internet Gateway and Egress-only Internet Gateway resources
+type InternetGateway struct {
+	// The VPC ID to create in
+	VpcID					*string `hcl:"vpc_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
 }
 
-func createDefaultSecurityGroup(graph *gographviz.Escape) (error) {
-	// Create default security group
-	if Verbose == true {
-		fmt.Println("[VERBOSE] AddNode: sg-default to G // Create default Security Group")
-	}
-	err := graph.AddNode("G", "sg-default", map[string]string{
-		"style": "dotted",
-		"label": "sg-default",
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+// NatGateway is a structure for AWS NAT Gateway resources
+type NatGateway struct {
+	// The Subnet ID of the subnet in which to place the gateway
+	SubnetID				string `hcl:"subnet_id"`
+	// Connectivity type for the gateway: private or public (default)
+	ConnectivityType		*string `hcl:"connectivity_type"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
 }
 
-func createVpc(graph *gographviz.Escape, vpcName string) (error) {
-	// Create VPC cluster
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddSubGraph: cluster_aws_vpc_%s to G // Create VPC\n", vpcName)
-	}
-	err := graph.AddSubGraph("G", "cluster_aws_vpc_"+vpcName, map[string]string{
-		"label": "VPC: "+vpcName,
-		"style": "rounded",
-		"bgcolor": "#EDF1F2",
-		"labeljust": "l",
-	})
-	if err != nil {
-		return err
-	}
+// RouteTable is a structure for AWS Route Table resources
+type RouteTable struct {
+	// The VPC ID
+	VpcID					string `hcl:"vpc_id"`
+	// A list of route objects
+	Routes					[]Route `hcl:"route,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
 
-	// Adding invisible node to VPC for links
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddNode: aws_vpc_%s to cluster_aws_vpc_%s\n", vpcName, vpcName)
-	}
-	err = graph.AddNode("cluster_aws_vpc_"+vpcName, "aws_vpc_"+vpcName, map[string]string{
-		"shape": "point",
-		"style": "invis",
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+// DefaultRouteTable is a structure for AWS Default Route Table resources
+type DefaultRouteTable struct {
+	// The ID of the Default Routing Table
+	DefaultRouteTableID		string `hcl:"default_route_table_id"`
+	// A list of route objects
+	Routes					[]Route `hcl:"route,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
 }
 
-func createSubnet(graph *gographviz.Escape, subnetName string, awsSubnet Subnet) (error) {
-	// Create subnet cluster
-	vpcID := strings.Replace(awsSubnet.VpcID, ".", "_", -1)
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddSubGraph: cluster_aws_subnet_%s to cluster_%s // Create Subnet\n", subnetName, vpcID)
-	}
-	err := graph.AddSubGraph("cluster_"+vpcID, "cluster_aws_subnet_"+subnetName, map[string]string{
-		"label": "Subnet: "+subnetName,
-		"style": "rounded",
-		"bgcolor": "white",
-		"labeljust": "l",
-	})
-	if err != nil {
-		return err
-	}
+// Route is a structure for AWS Route Table route blocks
+type Route struct {
+	// The CIDR block of the route
+	CidrBlock				*string `hcl:"cidr_block"`
+	// The Ipv6 CIDR block of the route
+	IPv6CidrBlock			*string `hcl:"ipv6_cidr_block"`
+	// Identifier of a VPC internet gateway or a virtual private gateway
+	GatewayID				*string `hcl:"gateway_id"`
+	// Identifier of a VPC NAT gateway
+	NatGatewayID			*string `hcl:"nat_gateway_id"`
+	// Identifier of a VPC Egress Only Internet Gateway
+	EgressOnlyGatewayID		*string `hcl:"egress_only_gateway_id"`
+	// Identifier of an EC2 Transit Gateway
+	TransitGatewayID		*string `hcl:"transit_gateway_id"`
+	// Identifier of a VPC peering connection
+	VpcPeeringConnectionID	*string `hcl:"vpc_peering_connection_id"`
+	// Identifier of a VPC Endpoint
+	VpcEndpointID			*string `hcl:"vpc_endpoint_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
 
-	// Adding invisible node to Subnet for links
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddNode: aws_subnet_%s to cluster_aws_subnet_%s\n", subnetName, subnetName)
-	}
-	err = graph.AddNode("cluster_aws_subnet_"+subnetName, "aws_subnet_"+subnetName, map[string]string{
-		"shape": "point",
-		"style": "invis",
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+// RouteResource is a structure for AWS Route resources (aws_route)
+type RouteResource struct {
+	// The ID of the routing table
+	RouteTableID			string `hcl:"route_table_id"`
+	// The destination CIDR block
+	DestinationCidrBlock	*string `hcl:"destination_cidr_block"`
+	// The destination IPv6 CIDR block
+	DestinationIPv6CidrBlock	*string `hcl:"destination_ipv6_cidr_block"`
+	// Identifier of a VPC internet gateway or a virtual private gateway
+	GatewayID				*string `hcl:"gateway_id"`
+	// Identifier of a VPC NAT gateway
+	NatGatewayID			*string `hcl:"nat_gateway_id"`
+	// Identifier of a VPC Egress Only Internet Gateway
+	EgressOnlyGatewayID		*string `hcl:"egress_only_gateway_id"`
+	// Identifier of an EC2 Transit Gateway
+	TransitGatewayID		*string `hcl:"transit_gateway_id"`
+	// Identifier of a VPC peering connection
+	VpcPeeringConnectionID	*string `hcl:"vpc_peering_connection_id"`
+	// Identifier of a VPC Endpoint
+	VpcEndpointID			*string `hcl:"vpc_endpoint_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
 }
 
-func createS3(graph *gographviz.Escape, s3Name string, s3 S3) (error) {
-	// Create S3 bucket node
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddNode: aws_s3_bucket_%s to G // Create S3 bucket\n", s3Name)
-	}
+// RouteTableAssociation is a structure for AWS Route Table Association resources
+type RouteTableAssociation struct {
+	// The subnet ID to create an association
+	SubnetID				*string `hcl:"subnet_id"`
+	// The gateway ID to create an association
+	GatewayID				*string `hcl:"gateway_id"`
+	// The ID of the routing table to associate with
+	RouteTableID			string `hcl:"route_table_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
 
-	// Splitting label if more than 8 chars
-	tmpLabel := s3Name
-	if s3.Bucket != nil && *s3.Bucket != "" {
-		tmpLabel = *s3.Bucket
-	}
-	labelName := strings.Join(utils.ChunkString(tmpLabel, 8), "\n")
+// MainRouteTableAssociation is a structure for AWS Main Route Table Association resources
+type MainRouteTableAssociation struct {
+	// The ID of the VPC whose main route table should be set
+	VpcID					string `hcl:"vpc_id"`
+	// The ID of the Route Table to set as the new main route table for the target VPC
+	RouteTableID			string `hcl:"route_table_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
 
-	err := graph.AddNode("G", "aws_s3_bucket_"+s3Name, map[string]string{
-		"label": labelName,
-		"image": "./aws/icons/s3.png",
-		"width": "1",
-		"height": "1",
-		"fixedsize": "true",
-		"shape": "none",
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+// standaloneRoute is a route declared outside of its route table, waiting to be merged in the table
+type standaloneRoute struct {
+	// Address of the route resource
+	Address					string
+	// The route table owning the route
+	RouteTableID			string
+	// nil for a route table declared without routes (aws_default_route_table)
+	Route					*Route
 }
 
-func createInstance(graph *gographviz.Escape, instanceName string, awsInstance Instance) (error) {
-	// Create instance node
-	var clusterID string
-	if awsInstance.SubnetID == nil {
-		clusterID = "aws_subnet_default"
-	} else {
-		clusterID = strings.Replace(*awsInstance.SubnetID, ".", "_", -1)
+// NetworkACL is a structure for AWS Network ACL resources
+type NetworkACL struct {
+	// The ID of the associated VPC
+	VpcID					string `hcl:"vpc_id"`
+	// A list of Subnet IDs to apply the ACL to
+	SubnetIDs				*[]string `hcl:"subnet_ids"`
+	// A list of ingress rules
+	Ingress					[]NACLRule `hcl:"ingress,block"`
+	// A list of egress rules
+	Egress					[]NACLRule `hcl:"egress,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// DefaultNetworkACL is a structure for AWS Default Network ACL resources
+type DefaultNetworkACL struct {
+	// The Network ACL ID to manage
+	DefaultNetworkACLID		string `hcl:"default_network_acl_id"`
+	// A list of Subnet IDs to apply the ACL to
+	SubnetIDs				*[]string `hcl:"subnet_ids"`
+	// A list of ingress rules
+	Ingress					[]NACLRule `hcl:"ingress,block"`
+	// A list of egress rules
+	Egress					[]NACLRule `hcl:"egress,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// NACLRule is a structure for AWS Network ACL ingress/egress blocks
+type NACLRule struct {
+	// The rule number. Used for ordering
+	RuleNo					int `hcl:"rule_no"`
+	// The action to take: allow or deny
+	Action					string `hcl:"action"`
+	// The protocol to match. "-1" (all)
+	Protocol				string `hcl:"protocol"`
+	// The from port to match
+	FromPort				int `hcl:"from_port"`
+	// The to port to match
+	ToPort					int `hcl:"to_port"`
+	// The CIDR block to match
+	CidrBlock				*string `hcl:"cidr_block"`
+	// The IPv6 CIDR block
+	IPv6CidrBlock			*string `hcl:"ipv6_cidr_block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// NetworkACLRule is a structure for AWS Network ACL Rule resources (aws_network_acl_rule)
+type NetworkACLRule struct {
+	// The ID of the network ACL
+	NetworkACLID			string `hcl:"network_acl_id"`
+	// The rule number for the entry
+	RuleNumber				int `hcl:"rule_number"`
+	// Indicates whether this is an egress rule
+	Egress					*bool `hcl:"egress"`
+	// The protocol. "-1" (all)
+	Protocol				string `hcl:"protocol"`
+	// Indicates whether to allow or deny the traffic that matches the rule
+	RuleAction				string `hcl:"rule_action"`
+	// The network range to allow or deny
+	CidrBlock				*string `hcl:"cidr_block"`
+	// The IPv6 CIDR block to allow or deny
+	IPv6CidrBlock			*string `hcl:"ipv6_cidr_block"`
+	// The from port to match
+	FromPort				*int `hcl:"from_port"`
+	// The to port to match
+	ToPort					*int `hcl:"to_port"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// NetworkACLAssociation is a structure for AWS Network ACL Association resources
+type NetworkACLAssociation struct {
+	// The ID of the network ACL
+	NetworkACLID			string `hcl:"network_acl_id"`
+	// The ID of the associated Subnet
+	SubnetID				string `hcl:"subnet_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// standaloneNACLRule is a rule declared outside of its network ACL, waiting to be merged in the ACL
+type standaloneNACLRule struct {
+	// Address of the rule resource
+	Address					string
+	// The network ACL owning the rule
+	NetworkACLID			string
+	// ingressRule or egressRule
+	RuleType				int
+	// nil for a network ACL declared without rules (aws_default_network_acl)
+	Rule					*NACLRule
+}
+
+// LB is a structure for AWS Load Balancer resources (aws_lb / aws_alb)
+type LB struct {
+	// The name of the LB
+	Name					*string `hcl:"name"`
+	// If true, the LB will be internal
+	Internal				*bool `hcl:"internal"`
+	// The type of load balancer to create: application (default), network or gateway
+	LoadBalancerType		*string `hcl:"load_balancer_type"`
+	// A list of security group IDs to assign to the LB
+	SecurityGroups			*[]string `hcl:"security_groups"`
+	// A list of subnet IDs to attach to the LB
+	Subnets					*[]string `hcl:"subnets"`
+	// A subnet mapping block (alternative to subnets)
+	SubnetMapping			[]LBSubnetMapping `hcl:"subnet_mapping,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBSubnetMapping is a structure for AWS Load Balancer subnet_mapping blocks
+type LBSubnetMapping struct {
+	// The id of the subnet of which to attach to the load balancer
+	SubnetID				string `hcl:"subnet_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ELB is a structure for AWS Classic Load Balancer resources
+type ELB struct {
+	// The name of the ELB
+	Name					*string `hcl:"name"`
+	// If true, ELB will be an internal ELB
+	Internal				*bool `hcl:"internal"`
+	// A list of security group IDs to assign to the ELB
+	SecurityGroups			*[]string `hcl:"security_groups"`
+	// A list of subnet IDs to attach to the ELB
+	Subnets					*[]string `hcl:"subnets"`
+	// A list of instance ids to place in the ELB pool
+	Instances				*[]string `hcl:"instances"`
+	// A list of listener blocks
+	Listeners				[]ELBListener `hcl:"listener,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ELBListener is a structure for AWS Classic Load Balancer listener blocks
+type ELBListener struct {
+	// The port on the instance to route to
+	InstancePort			int `hcl:"instance_port"`
+	// The protocol to use to the instance
+	InstanceProtocol		string `hcl:"instance_protocol"`
+	// The port to listen on for the load balancer
+	LBPort					int `hcl:"lb_port"`
+	// The protocol to listen on
+	LBProtocol				string `hcl:"lb_protocol"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBListener is a structure for AWS Load Balancer Listener resources
+type LBListener struct {
+	// The ARN of the load balancer
+	LoadBalancerArn			string `hcl:"load_balancer_arn"`
+	// The port on which the load balancer is listening
+	Port					*int `hcl:"port"`
+	// The protocol for connections from clients to the load balancer
+	Protocol				*string `hcl:"protocol"`
+	// An Action block
+	DefaultActions			[]LBListenerAction `hcl:"default_action,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBListenerAction is a structure for AWS Load Balancer Listener default_action blocks
+type LBListenerAction struct {
+	// The type of routing action: forward, redirect, fixed-response...
+	Type					string `hcl:"type"`
+	// The ARN of the Target Group to which to route traffic
+	TargetGroupArn			*string `hcl:"target_group_arn"`
+	// Forward block (weighted target groups)
+	Forward					[]LBListenerForward `hcl:"forward,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBListenerForward is a structure for AWS Load Balancer Listener forward blocks
+type LBListenerForward struct {
+	// The target groups
+	TargetGroups			[]LBListenerForwardTargetGroup `hcl:"target_group,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBListenerForwardTargetGroup is a structure for AWS Load Balancer Listener forward target_group blocks
+type LBListenerForwardTargetGroup struct {
+	// The Amazon Resource Name (ARN) of the target group
+	Arn						string `hcl:"arn"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBTargetGroup is a structure for AWS Load Balancer Target Group resources
+type LBTargetGroup struct {
+	// The name of the target group
+	Name					*string `hcl:"name"`
+	// The port on which targets receive traffic
+	Port					*int `hcl:"port"`
+	// The protocol to use for routing traffic to the targets
+	Protocol				*string `hcl:"protocol"`
+	// The type of target: instance (default), ip or lambda
+	TargetType				*string `hcl:"target_type"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBTargetGroupAttachment is a structure for AWS Load Balancer Target Group Attachment resources
+type LBTargetGroupAttachment struct {
+	// The ARN of the target group with which to register targets
+	TargetGroupArn			string `hcl:"target_group_arn"`
+	// The ID of the target: instance ID, IP address or Lambda ARN
+	TargetID				string `hcl:"target_id"`
+	// The port on which targets receive traffic
+	Port					*int `hcl:"port"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// AutoscalingGroup is a structure for AWS Auto Scaling Group resources
+type AutoscalingGroup struct {
+	// The name of the Auto Scaling Group
+	Name					*string `hcl:"name"`
+	// The minimum size of the Auto Scaling Group
+	MinSize					*int `hcl:"min_size"`
+	// The maximum size of the Auto Scaling Group
+	MaxSize					*int `hcl:"max_size"`
+	// A list of subnet IDs to launch resources in
+	VpcZoneIdentifier		*[]string `hcl:"vpc_zone_identifier"`
+	// The name of the launch configuration to use
+	LaunchConfiguration		*string `hcl:"launch_configuration"`
+	// Launch template specification to use to launch instances
+	LaunchTemplate			[]ASGLaunchTemplate `hcl:"launch_template,block"`
+	// Configuration block containing settings to define launch targets
+	MixedInstancesPolicy	[]ASGMixedInstancesPolicy `hcl:"mixed_instances_policy,block"`
+	// A list of aws_alb_target_group ARNs, for use with Application or Network Load Balancing
+	TargetGroupArns			*[]string `hcl:"target_group_arns"`
+	// A list of elastic load balancer names to add to the autoscaling group names (Classic LB only)
+	LoadBalancers			*[]string `hcl:"load_balancers"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ASGLaunchTemplate is a structure for AWS Auto Scaling Group launch_template blocks
+type ASGLaunchTemplate struct {
+	// The ID of the launch template
+	ID						*string `hcl:"id"`
+	// The name of the launch template
+	Name					*string `hcl:"name"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ASGMixedInstancesPolicy is a structure for AWS Auto Scaling Group mixed_instances_policy blocks
+type ASGMixedInstancesPolicy struct {
+	// Nested argument containing launch template settings
+	LaunchTemplate			[]struct {
+		// Nested argument defines the Launch Template
+		LaunchTemplateSpecification	[]struct {
+			// The ID of the launch template
+			LaunchTemplateID	*string `hcl:"launch_template_id"`
+			// The name of the launch template
+			LaunchTemplateName	*string `hcl:"launch_template_name"`
+			// Other arguments
+			Remain				hcl2.Body `hcl:",remain"`
+		} `hcl:"launch_template_specification,block"`
+		// Other arguments
+		Remain				hcl2.Body `hcl:",remain"`
+	} `hcl:"launch_template,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// AutoscalingAttachment is a structure for AWS Auto Scaling Attachment resources
+type AutoscalingAttachment struct {
+	// Name of ASG to associate with the ELB
+	AutoscalingGroupName	string `hcl:"autoscaling_group_name"`
+	// The name of the ELB
+	ELB						*string `hcl:"elb"`
+	// The ARN of an ALB Target Group
+	LBTargetGroupArn		*string `hcl:"lb_target_group_arn"`
+	// The ARN of an ALB Target Group (deprecated)
+	ALBTargetGroupArn		*string `hcl:"alb_target_group_arn"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LaunchTemplate is a structure for AWS Launch Template resources
+type LaunchTemplate struct {
+	// The name of the launch template
+	Name					*string `hcl:"name"`
+	// The AMI from which to launch the instance
+	ImageID					*string `hcl:"image_id"`
+	// The type of the instance
+	InstanceType			*string `hcl:"instance_type"`
+	// A list of security group names to associate with
+	SecurityGroupNames		*[]string `hcl:"security_group_names"`
+	// A list of security group IDs to associate with
+	VpcSecurityGroupIDs		*[]string `hcl:"vpc_security_group_ids"`
+	// Customize network interfaces to be attached at instance boot time
+	NetworkInterfaces		[]LaunchTemplateNetworkInterface `hcl:"network_interfaces,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LaunchTemplateNetworkInterface is a structure for AWS Launch Template network_interfaces blocks
+type LaunchTemplateNetworkInterface struct {
+	// A list of security group IDs to associate
+	SecurityGroups			*[]string `hcl:"security_groups"`
+	// The VPC Subnet ID to associate
+	SubnetID				*string `hcl:"subnet_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LaunchConfiguration is a structure for AWS Launch Configuration resources
+type LaunchConfiguration struct {
+	// The name of the launch configuration
+	Name					*string `hcl:"name"`
+	// The EC2 image ID to launch
+	ImageID					*string `hcl:"image_id"`
+	// The size of instance to launch
+	InstanceType			*string `hcl:"instance_type"`
+	// A list of associated security group IDS
+	SecurityGroups			*[]string `hcl:"security_groups"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// S3 is a structure for AWS S3 bucket resources
+type S3 struct {
+	// The name of the bucket
+	Bucket					*string `hcl:"bucket"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+func (a *Data) createDefaultVpc(graph *model.Graph) (error) {
+	// Create default VPC group
+	a.log.Verbosef("AddGroup: aws_vpc_default // Create Default VPC")
+	return graph.AddGroup(&model.Group{
+		ID:		"aws_vpc_default",
+		Kind:	model.NetworkGroup,
+		Label:	"VPC: default",
+	})
+}
+
+func (a *Data) createDefaultSubnet(graph *model.Graph, parent string) (error) {
+	// Create default Subnet group
+	a.log.Verbosef("AddGroup: aws_subnet_default to %s // Create Default Subnet", parent)
+	return graph.AddGroup(&model.Group{
+		ID:		"aws_subnet_default",
+		Parent:	parent,
+		Kind:	model.SubnetGroup,
+		Label:	"Subnet: default",
+	})
+}
+
+func (a *Data) createDefaultSecurityGroup(graph *model.Graph) (error) {
+	// Create default security group
+	a.log.Verbosef("AddNode: sg-default // Create default Security Group")
+	return graph.AddNode(&model.Node{
+		ID:		"sg-default",
+		Kind:	model.SecurityGroupNode,
+		Label:	"sg-default",
+	})
+}
+
+// iconNode returns the node of a resource drawn with one of the icons of the aws/icons directory
+func iconNode(address string, label string, icon string) *model.Node {
+	_, resourceType, _ := utils.SplitAddress(address)
+	return &model.Node{
+		ID:			utils.NodeID(address),
+		Kind:		resourceType,
+		Label:		label,
+		Address:	address,
+		Icon:		"aws/icons/" + icon,
+	}
+}
+
+func (a *Data) createVpc(graph *model.Graph, vpcAddress string, realID string) (error) {
+	// Create VPC group
+	vpcID := utils.NodeID(vpcAddress)
+	modulePath, _, vpcName := utils.SplitAddress(vpcAddress)
+	parent := a.moduleGroup(modulePath)
+	a.log.Verbosef("AddGroup: %s to %s // Create VPC", vpcID, parent)
+	group := &model.Group{
+		ID:			vpcID,
+		Parent:		parent,
+		Kind:		model.NetworkGroup,
+		Label:		"VPC: " + utils.ModulePrefix(modulePath) + vpcName + realIDLabel(realID),
+		Address:	vpcAddress,
 	}
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddNode: aws_instance_%s to cluster_%s // Create Instance\n", instanceName, clusterID)
+	if realID != "" {
+		group.Attributes = map[string]string{"id": realID}
 	}
+	return graph.AddGroup(group)
+}
 
-	// Splitting label if more than 8 chars
-	labelName := strings.Join(utils.ChunkString(instanceName, 8), "\n")
-
-	err := graph.AddNode("cluster_"+clusterID, "aws_instance_"+instanceName, map[string]string{
-		"label": labelName,
-		"image": "./aws/icons/ec2.png",
-		"width": "1",
-		"height": "1",
-		"fixedsize": "true",
-		"shape": "none",
-	})
-	if err != nil {
-		return err
+func (a *Data) createSubnet(graph *model.Graph, subnetAddress string, awsSubnet Subnet, realID string, routing string, networkACL string) (error) {
+	// Create subnet group in its VPC, or in its module if the VPC is not defined in TF
+	vpcID := utils.NodeID(awsSubnet.VpcID)
+	subnetID := utils.NodeID(subnetAddress)
+	modulePath, _, subnetName := utils.SplitAddress(subnetAddress)
+	if !graph.IsGroup(vpcID) {
+		vpcID = a.moduleGroup(modulePath)
 	}
-	return nil
+	a.log.Verbosef("AddGroup: %s to %s // Create Subnet", subnetID, vpcID)
+
+	// Public / private subnets are known from their route table
+	label := "Subnet: "
+	switch routing {
+	case publicSubnet:
+		label = "Public subnet: "
+	case privateSubnet:
+		label = "Private subnet: "
+	}
+	label += utils.ModulePrefix(modulePath) + subnetName + realIDLabel(realID)
+	attributes := make(map[string]string)
+	if routing != "" {
+		attributes["routing"] = routing
+	}
+	if realID != "" {
+		attributes["id"] = realID
+	}
+	if networkACL != "" {
+		label += "\nNACL: " + networkACLName(networkACL)
+		attributes["network_acl"] = networkACL
+	}
+	return graph.AddGroup(&model.Group{
+		ID:			subnetID,
+		Parent:		vpcID,
+		Kind:		model.SubnetGroup,
+		Label:		label,
+		Address:	subnetAddress,
+		Attributes:	attributes,
+	})
 }
 
+func (a *Data) createS3(graph *model.Graph, s3Address string, s3 S3) (error) {
+	// Create S3 bucket node
+	s3ID := utils.NodeID(s3Address)
+	modulePath, _, s3Name := utils.SplitAddress(s3Address)
+	parent := a.moduleGroup(modulePath)
+	a.log.Verbosef("AddNode: %s to %s // Create S3 bucket", s3ID, parent)
 
-func (a *Data) createDBInstance(graph *gographviz.Escape, instanceName string, awsInstance DBInstance) (error) {
-	// Create DB instance node
-	var clusterID string
-	// if there is no DB Subnet Group, the DB instance is created in the default VPC
-	// same if there is no VPC defined in the TF module
-	if awsInstance.DBSubnetGroupName == nil || len(a.Vpc) == 0{
-		clusterID = "aws_vpc_default"
+	// Splitting label if more than 8 chars
+	tmpLabel := s3Name
+	if s3.Bucket != nil && *s3.Bucket != "" {
+		tmpLabel = *s3.Bucket
+	}
+
+	node := iconNode(s3Address, utils.LabelName(tmpLabel), "s3.png")
+	node.Parent = parent
+	return graph.AddNode(node)
+}
+
+func (a *Data) createInstance(graph *model.Graph, instanceAddress string, awsInstance Instance) (error) {
+	// Create instance node
+	var groupID string
+	if awsInstance.SubnetID == nil {
+		groupID = "aws_subnet_default"
 	} else {
-		// TODO: support multiple subnets from the DB Subnet group
-		// - how to show a DB in multiple subnets?
-		// - can a node be part of 2 subgraph (in graphviz)?
-		// For now, only the first one is used
-		tmpDBname := strings.Split(*awsInstance.DBSubnetGroupName, ".")[1]
-		tmpSubnetName := strings.Split(a.DBSubnetGroup[tmpDBname].SubnetIDs[0], ".")[1]
-		clusterID = "aws_vpc_" + a.Subnet[tmpSubnetName].VpcID
+		groupID = utils.NodeID(*awsInstance.SubnetID)
 	}
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddNode: aws_db_instance_%s to cluster_%s // Create DB Instance\n", instanceName, clusterID)
+	instanceID := utils.NodeID(instanceAddress)
+	modulePath, _, instanceName := utils.SplitAddress(instanceAddress)
+	if !graph.IsGroup(groupID) {
+		// Subnet not defined in TF
+		groupID = a.moduleGroup(modulePath)
 	}
+	a.log.Verbosef("AddNode: %s to %s // Create Instance", instanceID, groupID)
 
 	// Splitting label if more than 8 chars
-	labelName := strings.Join(utils.ChunkString(instanceName, 8), "\n")
+	node := iconNode(instanceAddress, utils.LabelName(instanceName), "ec2.png")
+	node.Parent = groupID
+	return graph.AddNode(node)
+}
+
+
+func (a *Data) createDBInstance(graph *model.Graph, instanceAddress string, awsInstance DBInstance) (error) {
+	// Create DB instance nodes (one per subnet of its DB Subnet Group)
+	_, _, instanceName := utils.SplitAddress(instanceAddress)
+	subnets := a.subnetGroupSubnets(instanceAddress, a.DBSubnetGroup, awsInstance.DBSubnetGroupName)
+	a.log.Verbosef("Create DB Instance %s in %d subnet(s)", instanceAddress, len(subnets))
 
-	fontColor := "black"
 	// DB is publicly available, so setting label color as red
-	if awsInstance.PubliclyAccessible != nil && *awsInstance.PubliclyAccessible == true {
-		fontColor = "red"
-	}
-
-	err := graph.AddNode("cluster_"+clusterID, "aws_db_instance_"+instanceName, map[string]string{
-		"label": labelName,
-		"fontcolor": fontColor,
-		"image": "./aws/icons/db.png",
-		"width": "1",
-		"height": "1",
-		"fixedsize": "true",
-		"shape": "none",
-	})
-	if err != nil {
-		return err
+	public := awsInstance.PubliclyAccessible != nil && *awsInstance.PubliclyAccessible == true
+	return a.createDataNode(graph, instanceAddress, utils.LabelName(instanceName), subnets, "db.png", public)
+}
+
+// CreateDefaultNodes creates default VPC/Subnet/Security Groups if they don't exist in the parsed resources
+func (a *Data) CreateDefaultNodes(graph *model.Graph) (error) {
+	a.defaultVpc = len(a.Vpc) > 0
+	a.defaultSubnet = len(a.Subnet) > 0
+	a.defaultSecurityGroup = len(a.SecurityGroup) > 0
+
+	if !a.defaultVpc {
+		// Create default VPC cluster
+		err := a.createDefaultVpc(graph)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !a.defaultSubnet {
+		// Create default subnet cluster
+		var parent string
+		if !a.defaultVpc {
+			parent = "aws_vpc_default"
+		}
+		err := a.createDefaultSubnet(graph, parent)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !a.defaultSecurityGroup {
+		// Create default security group
+		err := a.createDefaultSecurityGroup(graph)
+		if err != nil {
+			return err
+		}
+		a.undefinedSecurityGroups = append(a.undefinedSecurityGroups, "sg-default")
 	}
 	return nil
 }
 
-// InitiateVariablesAndResources parses TF file to create Variables / Obj references for interpolation
-func InitiateVariablesAndResources(tfModule *tfconfigs.Module) (*hcl2.EvalContext, error) {
-	// Create map for EvalContext to replace variables names by their values inside HCL file using DecodeBody
-	ctxVariables := make(map[string]cty.Value)
-	ctxVpc := make(map[string]cty.Value)
-	ctxAwsSubnet := make(map[string]cty.Value)
-	ctxAwsInstance := make(map[string]cty.Value)
-	ctxAwsSecurityGroup := make(map[string]cty.Value)
-	ctxDBInstance := make(map[string]cty.Value)
-	ctxDBSubnetGroup := make(map[string]cty.Value)
-
-	// Prepare context with TF variables
-	for _, v := range tfModule.Variables {
-		// Handling the case there is no default value for the variable
-		if v.Default.IsNull() {
-			ctxVariables[v.Name] = cty.StringVal("var_" + v.Name)
-		} else {
-			ctxVariables[v.Name] = v.Default
-		}
+// ResolveResources merges the resources declared separately from the resource they belong to (SG rules, routes...)
+// and links the resources referencing each other. It must be called once all resources are parsed
+func (a *Data) ResolveResources() {
+	a.mergeSecurityGroupRules()
+	a.mergeRoutes()
+	a.mergeNACLRules()
+	a.linkAutoscalingGroups()
+	a.linkNodeGroups()
+	a.linkVpcEndpoints()
+}
+
+// referencedResource returns the address of the resource referenced by one of its referencedAttributes
+// (e.g. aws_launch_template.web.name => aws_launch_template.web)
+func referencedResource(reference string) string {
+	return utils.ReferencedResource(reference, referencedAttributes)
+}
+
+// DecodeResource decodes a single instance of a TF resource and adds it to Data.
+// Resources are indexed by their address (e.g. module.vpc.aws_subnet.private[0])
+func (a *Data) DecodeResource(r provider.Resource) bool {
+	if r.ID != "" {
+		// Real ID of the resource, shown in the VPC / subnet labels
+		a.resourceIDs[r.Address] = r.ID
 	}
+	return a.parseTfResource(r.Type, r.Address, r.Body, r.Ctx)
+}
+
+// resourceHandler decodes the resources of a TF type
+type resourceHandler struct {
+	// value is an empty structure of the type the resources are decoded in (e.g. Vpc{})
+	value		interface{}
+	// add adds a decoded resource to Data
+	add			func(a *Data, resourceType string, address string, value interface{})
+}
+
+// resourceHandlers are the handlers of the supported resource types, indexed by resource type
+var resourceHandlers = map[string]resourceHandler{
+	"aws_vpc":	{Vpc{}, (*Data).addVpc},
+	"aws_subnet":	{Subnet{}, (*Data).addSubnet},
+	"aws_instance":	{Instance{}, (*Data).addInstance},
+	"aws_security_group":	{SecurityGroup{}, (*Data).addSecurityGroup},
+	"aws_security_group_rule":	{SecurityGroupRule{}, (*Data).addSecurityGroupRule},
+	"aws_vpc_security_group_ingress_rule":	{VpcSecurityGroupRule{}, (*Data).addVpcSecurityGroupRule},
+	"aws_vpc_security_group_egress_rule":	{VpcSecurityGroupRule{}, (*Data).addVpcSecurityGroupRule},
+	"aws_internet_gateway":	{InternetGateway{}, (*Data).addInternetGateway},
+	"aws_egress_only_internet_gateway":	{InternetGateway{}, (*Data).addEgressOnlyInternetGateway},
+	"aws_nat_gateway":	{NatGateway{}, (*Data).addNatGateway},
+	"aws_route_table":	{RouteTable{}, (*Data).addRouteTable},
+	"aws_default_route_table":	{DefaultRouteTable{}, (*Data).addDefaultRouteTable},
+	"aws_route":	{RouteResource{}, (*Data).addRoute},
+	"aws_route_table_association":	{RouteTableAssociation{}, (*Data).addRouteTableAssociation},
+	"aws_main_route_table_association":	{MainRouteTableAssociation{}, (*Data).addMainRouteTableAssociation},
+	"aws_network_acl":	{NetworkACL{}, (*Data).addNetworkACL},
+	"aws_default_network_acl":	{DefaultNetworkACL{}, (*Data).addDefaultNetworkACL},
+	"aws_network_acl_rule":	{NetworkACLRule{}, (*Data).addNetworkACLRule},
+	"aws_network_acl_association":	{NetworkACLAssociation{}, (*Data).addNetworkACLAssociation},
+	"aws_lb":	{LB{}, (*Data).addLB},
+	"aws_alb":	{LB{}, (*Data).addLB},
+	"aws_elb":	{ELB{}, (*Data).addELB},
+	"aws_lb_listener":	{LBListener{}, (*Data).addLBListener},
+	"aws_alb_listener":	{LBListener{}, (*Data).addLBListener},
+	"aws_lb_target_group":	{LBTargetGroup{}, (*Data).addLBTargetGroup},
+	"aws_alb_target_group":	{LBTargetGroup{}, (*Data).addLBTargetGroup},
+	"aws_lb_target_group_attachment":	{LBTargetGroupAttachment{}, (*Data).addLBTargetGroupAttachment},
+	"aws_alb_target_group_attachment":	{LBTargetGroupAttachment{}, (*Data).addLBTargetGroupAttachment},
+	"aws_autoscaling_group":	{AutoscalingGroup{}, (*Data).addAutoscalingGroup},
+	"aws_autoscaling_attachment":	{AutoscalingAttachment{}, (*Data).addAutoscalingAttachment},
+	"aws_launch_template":	{LaunchTemplate{}, (*Data).addLaunchTemplate},
+	"aws_launch_configuration":	{LaunchConfiguration{}, (*Data).addLaunchConfiguration},
+	"aws_db_instance":	{DBInstance{}, (*Data).addDBInstance},
+	"aws_db_subnet_group":	{DBSubnetGroup{}, (*Data).addDBSubnetGroup},
+	"aws_rds_cluster":	{RDSCluster{}, (*Data).addRDSCluster},
+	"aws_rds_cluster_instance":	{RDSClusterInstance{}, (*Data).addRDSClusterInstance},
+	"aws_elasticache_cluster":	{ElastiCacheCluster{}, (*Data).addElastiCacheCluster},
+	"aws_elasticache_replication_group":	{ElastiCacheReplicationGroup{}, (*Data).addElastiCacheReplicationGroup},
+	"aws_elasticache_subnet_group":	{DBSubnetGroup{}, (*Data).addElastiCacheSubnetGroup},
+	"aws_redshift_cluster":	{RedshiftCluster{}, (*Data).addRedshiftCluster},
+	"aws_redshift_subnet_group":	{DBSubnetGroup{}, (*Data).addRedshiftSubnetGroup},
+	"aws_opensearch_domain":	{OpenSearchDomain{}, (*Data).addOpenSearchDomain},
+	"aws_elasticsearch_domain":	{OpenSearchDomain{}, (*Data).addOpenSearchDomain},
+	"aws_lambda_function":	{LambdaFunction{}, (*Data).addLambdaFunction},
+	"aws_lambda_event_source_mapping":	{LambdaEventSourceMapping{}, (*Data).addLambdaEventSourceMapping},
+	"aws_lambda_permission":	{LambdaPermission{}, (*Data).addLambdaPermission},
+	"aws_s3_bucket_notification":	{S3BucketNotification{}, (*Data).addS3BucketNotification},
+	"aws_api_gateway_rest_api":	{APIGateway{}, (*Data).addAPIGateway},
+	"aws_apigatewayv2_api":	{APIGateway{}, (*Data).addAPIGateway},
+	"aws_api_gateway_integration":	{APIGatewayIntegration{}, (*Data).addAPIGatewayIntegration},
+	"aws_apigatewayv2_integration":	{APIGatewayIntegration{}, (*Data).addAPIGatewayIntegration},
+	"aws_ecs_cluster":	{ECSCluster{}, (*Data).addECSCluster},
+	"aws_ecs_service":	{ECSService{}, (*Data).addECSService},
+	"aws_ecs_task_definition":	{ECSTaskDefinition{}, (*Data).addECSTaskDefinition},
+	"aws_eks_cluster":	{EKSCluster{}, (*Data).addEKSCluster},
+	"aws_eks_node_group":	{EKSNodeGroup{}, (*Data).addEKSNodeGroup},
+	"aws_vpc_peering_connection":	{VpcPeeringConnection{}, (*Data).addVpcPeeringConnection},
+	"aws_ec2_transit_gateway":	{TransitGateway{}, (*Data).addTransitGateway},
+	"aws_ec2_transit_gateway_vpc_attachment":	{TransitGatewayVpcAttachment{}, (*Data).addTransitGatewayVpcAttachment},
+	"aws_ec2_transit_gateway_route_table":	{TransitGatewayRouteTable{}, (*Data).addTransitGatewayRouteTable},
+	"aws_ec2_transit_gateway_route":	{TransitGatewayRoute{}, (*Data).addTransitGatewayRoute},
+	"aws_vpn_gateway":	{VpnGateway{}, (*Data).addVpnGateway},
+	"aws_vpn_gateway_attachment":	{VpnGatewayAttachment{}, (*Data).addVpnGatewayAttachment},
+	"aws_customer_gateway":	{CustomerGateway{}, (*Data).addCustomerGateway},
+	"aws_vpn_connection":	{VpnConnection{}, (*Data).addVpnConnection},
+	"aws_vpn_connection_route":	{VpnConnectionRoute{}, (*Data).addVpnConnectionRoute},
+	"aws_vpc_endpoint":	{VpcEndpoint{}, (*Data).addVpcEndpoint},
+	"aws_vpc_endpoint_route_table_association":	{VpcEndpointAssociation{}, (*Data).addVpcEndpointAssociation},
+	"aws_vpc_endpoint_subnet_association":	{VpcEndpointAssociation{}, (*Data).addVpcEndpointAssociation},
+	"aws_vpc_endpoint_security_group_association":	{VpcEndpointAssociation{}, (*Data).addVpcEndpointAssociation},
+	"aws_vpc_endpoint_service":	{VpcEndpointService{}, (*Data).addVpcEndpointService},
+	"aws_s3_bucket":	{S3{}, (*Data).addS3},
+}
+
+// parseTfResource decodes a resource and returns false if its type is not supported.
+// body can come from HCL files (with ctx used for interpolation) or from JSON plans / states
+func (a *Data) parseTfResource(resourceType string, address string, body hcl2.Body, ctx *hcl2.EvalContext) bool {
+	handler, found := resourceHandlers[resourceType]
+	if !found {
+		a.log.Verbosef("Can't decode %s (not yet supported)", address)
+		return false
+	}
+	if ctx != nil {
+		// Expanding dynamic blocks (e.g. dynamic "ingress") so they are decoded like literal blocks
+		body = dynblock.Expand(body, ctx)
+	}
+
+	a.log.Verbosef("Decoding %s", address)
+	value, diags := utils.DecodeResource(body, ctx, handler.value)
+	a.log.Diags(diags)
+	handler.add(a, resourceType, address, value)
+	return true
+}
+
+// addVpc adds a decoded aws_vpc to Data
+func (a *Data) addVpc(resourceType string, address string, value interface{}) {
+	awsVpc := value.(Vpc)
+
+	// Add Vpc to Data
+	a.Vpc[address] = awsVpc
+}
+
+// addSubnet adds a decoded aws_subnet to Data
+func (a *Data) addSubnet(resourceType string, address string, value interface{}) {
+	awsSubnet := value.(Subnet)
+
+	// Add Subnet to Data
+	a.Subnet[address] = awsSubnet
+}
 
-	// Load variables from Variable Definitions (.tfvars) Files
-	// Start with terraform.tfvars file:
-	inputVariablesFile := path.Join(tfModule.SourceDir, "terraform.tfvars")
-	_, err := os.Stat(inputVariablesFile)
-	if err == nil {
-		vars, diags := tfconfigs.NewParser(nil).LoadValuesFile(inputVariablesFile)
-		utils.PrintDiags(diags)
-		for varName, varValue := range vars {
-			ctxVariables[varName] = varValue
+// addInstance adds a decoded aws_instance to Data
+func (a *Data) addInstance(resourceType string, address string, value interface{}) {
+	awsInstance := value.(Instance)
+
+	// Add Instance to Data
+	a.Instance[address] = awsInstance
+
+	// Creating SG - Instance connections to facilitate the edges creation for the graph
+	if awsInstance.SecurityGroups != nil {
+		for _, sg := range *awsInstance.SecurityGroups {
+			_, found := a.SecurityGroupNodeLinks[sg]
+			if found {
+				a.SecurityGroupNodeLinks[sg] = append(a.SecurityGroupNodeLinks[sg], address)
+			} else {
+				a.SecurityGroupNodeLinks[sg] = []string{address}
+			}
 		}
 	}
-	// Search for .auto.tfvars files
-	files, err := ioutil.ReadDir(tfModule.SourceDir)
-	if err != nil {
-		return nil, err
-	}
-	for _, f := range files {
-		if strings.HasSuffix(f.Name(), ".auto.tfvars") {
-			inputVariablesFile := path.Join(tfModule.SourceDir, f.Name())
-			vars, diags := tfconfigs.NewParser(nil).LoadValuesFile(inputVariablesFile)
-			utils.PrintDiags(diags)
-			for varName, varValue := range vars {
-				ctxVariables[varName] = varValue
+	if awsInstance.VpcSecurityGroupIDs != nil {
+		for _, sg := range *awsInstance.VpcSecurityGroupIDs {
+			_, found := a.SecurityGroupNodeLinks[sg]
+			if found {
+				a.SecurityGroupNodeLinks[sg] = append(a.SecurityGroupNodeLinks[sg], address)
+			} else {
+				a.SecurityGroupNodeLinks[sg] = []string{address}
 			}
 		}
 	}
+}
 
-	// Prepare context with named values to resources
-	for _, v := range tfModule.ManagedResources {
-		if v.Type == "aws_vpc" {
-			ctxVpc[v.Name] = cty.ObjectVal(map[string]cty.Value{
-				"id":    cty.StringVal(v.Type + "." + v.Name),
-				})
-		} else if v.Type == "aws_subnet" {
-			ctxAwsSubnet[v.Name] = cty.ObjectVal(map[string]cty.Value{
-				"id":    cty.StringVal(v.Type + "." + v.Name),
-				})
-		} else if v.Type == "aws_instance" {
-			ctxAwsInstance[v.Name] = cty.ObjectVal(map[string]cty.Value{
-				"id":    cty.StringVal(v.Type + "." + v.Name),
-				})
-		} else if v.Type == "aws_security_group" {
-			ctxAwsSecurityGroup[v.Name] = cty.ObjectVal(map[string]cty.Value{
-				"id":    cty.StringVal(v.Type + "." + v.Name),
-				})
-		} else if v.Type == "aws_db_instance" {
-			ctxDBInstance[v.Name] = cty.ObjectVal(map[string]cty.Value{
-				"id":    cty.StringVal(v.Type + "." + v.Name),
-				})
-		} else if v.Type == "aws_db_subnet_group" {
-			ctxDBSubnetGroup[v.Name] = cty.ObjectVal(map[string]cty.Value{
-				"id":    cty.StringVal(v.Type + "." + v.Name),
-				})
-		}
+// addSecurityGroup adds a decoded aws_security_group to Data
+func (a *Data) addSecurityGroup(resourceType string, address string, value interface{}) {
+	awsSecurityGroup := value.(SecurityGroup)
+
+	// Add SecurityGroup to Data
+	a.SecurityGroup[address] = awsSecurityGroup
+}
+
+// addSecurityGroupRule adds a decoded aws_security_group_rule to Data
+func (a *Data) addSecurityGroupRule(resourceType string, address string, value interface{}) {
+	awsSGRule := value.(SecurityGroupRule)
+
+	rule := SGRule{
+		FromPort:		awsSGRule.FromPort,
+		ToPort:			awsSGRule.ToPort,
+		Self:			awsSGRule.Self,
+		Protocol:		awsSGRule.Protocol,
+		CidrBlocks:		awsSGRule.CidrBlocks,
+		IPv6CidrBlocks:	awsSGRule.IPv6CidrBlocks,
+		PrefixListIDs:	awsSGRule.PrefixListIDs,
 	}
-	
-	ctx := &hcl2.EvalContext{
-		Variables: map[string]cty.Value{
-			"var": cty.ObjectVal(ctxVariables),
-			"aws_vpc" : cty.ObjectVal(ctxVpc),
-			"aws_subnet" : cty.ObjectVal(ctxAwsSubnet),
-			"aws_instance" : cty.ObjectVal(ctxAwsInstance),
-			"aws_security_group" : cty.ObjectVal(ctxAwsSecurityGroup),
-			"aws_db_instance" : cty.ObjectVal(ctxDBInstance),
-			"aws_db_subnet_group" : cty.ObjectVal(ctxDBSubnetGroup),
-		},
+	if awsSGRule.SourceSecurityGroupID != nil {
+		rule.SecurityGroups = &[]string{*awsSGRule.SourceSecurityGroupID}
 	}
-	return ctx, nil
+	ruleType := ingressRule
+	if awsSGRule.Type == "egress" {
+		ruleType = egressRule
+	}
+
+	// The rule is merged in its Security Group once all resources are parsed
+	a.standaloneSGRules = append(a.standaloneSGRules, standaloneSGRule{address, awsSGRule.SecurityGroupID, ruleType, rule})
 }
 
-// CreateDefaultNodes creates default VPC/Subnet/Security Groups if they don't exist in the TF module
-func (a *Data) CreateDefaultNodes(tfModule *tfconfigs.Module, graph *gographviz.Escape) (error) {
-	for _, v := range tfModule.ManagedResources {
-		if v.Type == "aws_vpc" {
-			a.defaultVpc = true
-		} else if v.Type == "aws_subnet" {
-			a.defaultSubnet = true
-		} else if v.Type == "aws_security_group" {
-			a.defaultSecurityGroup = true
+// addVpcSecurityGroupRule adds a decoded aws_vpc_security_group_ingress_rule or aws_vpc_security_group_egress_rule to Data
+func (a *Data) addVpcSecurityGroupRule(resourceType string, address string, value interface{}) {
+	awsSGRule := value.(VpcSecurityGroupRule)
+
+	rule := SGRule{
+		Protocol:		awsSGRule.IPProtocol,
+	}
+	if awsSGRule.FromPort != nil {
+		rule.FromPort = *awsSGRule.FromPort
+	}
+	if awsSGRule.ToPort != nil {
+		rule.ToPort = *awsSGRule.ToPort
+	}
+	if awsSGRule.CidrIPv4 != nil {
+		rule.CidrBlocks = &[]string{*awsSGRule.CidrIPv4}
+	}
+	if awsSGRule.CidrIPv6 != nil {
+		rule.IPv6CidrBlocks = &[]string{*awsSGRule.CidrIPv6}
+	}
+	if awsSGRule.PrefixListID != nil {
+		rule.PrefixListIDs = &[]string{*awsSGRule.PrefixListID}
+	}
+	if awsSGRule.ReferencedSecurityGroupID != nil {
+		if *awsSGRule.ReferencedSecurityGroupID == awsSGRule.SecurityGroupID {
+			// A rule referencing its own security group is a self rule
+			self := true
+			rule.Self = &self
+		} else {
+			rule.SecurityGroups = &[]string{*awsSGRule.ReferencedSecurityGroupID}
 		}
 	}
+	ruleType := ingressRule
+	if resourceType == "aws_vpc_security_group_egress_rule" {
+		ruleType = egressRule
+	}
 
-	if !a.defaultVpc {
-		// Create default VPC cluster
-		err := createDefaultVpc(graph)
-		if err != nil {
-			return err
-		}
+	// The rule is merged in its Security Group once all resources are parsed
+	a.standaloneSGRules = append(a.standaloneSGRules, standaloneSGRule{address, awsSGRule.SecurityGroupID, ruleType, rule})
+}
+
+// addInternetGateway adds a decoded aws_internet_gateway to Data
+func (a *Data) addInternetGateway(resourceType string, address string, value interface{}) {
+	awsInternetGateway := value.(InternetGateway)
+
+	// Add InternetGateway to Data
+	a.InternetGateway[address] = awsInternetGateway
+}
+
+// addEgressOnlyInternetGateway adds a decoded aws_egress_only_internet_gateway to Data
+func (a *Data) addEgressOnlyInternetGateway(resourceType string, address string, value interface{}) {
+	awsEgressOnlyInternetGateway := value.(InternetGateway)
+
+	// Add EgressOnlyInternetGateway to Data
+	a.EgressOnlyInternetGateway[address] = awsEgressOnlyInternetGateway
+}
+
+// addNatGateway adds a decoded aws_nat_gateway to Data
+func (a *Data) addNatGateway(resourceType string, address string, value interface{}) {
+	awsNatGateway := value.(NatGateway)
+
+	// Add NatGateway to Data
+	a.NatGateway[address] = awsNatGateway
+}
+
+// addRouteTable adds a decoded aws_route_table to Data
+func (a *Data) addRouteTable(resourceType string, address string, value interface{}) {
+	awsRouteTable := value.(RouteTable)
+
+	// Add RouteTable to Data (routes already added by aws_route resources are kept)
+	if routeTable, found := a.RouteTable[address]; found {
+		awsRouteTable.Routes = append(awsRouteTable.Routes, routeTable.Routes...)
 	}
+	a.RouteTable[address] = awsRouteTable
+}
 
-	if !a.defaultSubnet {
-		// Create default subnet cluster
-		var clusterName string
-		if !a.defaultVpc {
-			clusterName = "cluster_aws_vpc_default"
-		} else {
-			clusterName = "G"
+// addDefaultRouteTable adds a decoded aws_default_route_table to Data
+func (a *Data) addDefaultRouteTable(resourceType string, address string, value interface{}) {
+	awsDefaultRouteTable := value.(DefaultRouteTable)
+
+	// The default route table is the main route table of its VPC, its routes are merged like aws_route resources
+	a.standaloneRoutes = append(a.standaloneRoutes, standaloneRoute{address, awsDefaultRouteTable.DefaultRouteTableID, nil})
+	for i := range awsDefaultRouteTable.Routes {
+		a.standaloneRoutes = append(a.standaloneRoutes, standaloneRoute{address, awsDefaultRouteTable.DefaultRouteTableID, &awsDefaultRouteTable.Routes[i]})
+	}
+}
+
+// addRoute adds a decoded aws_route to Data
+func (a *Data) addRoute(resourceType string, address string, value interface{}) {
+	awsRoute := value.(RouteResource)
+
+	route := Route{
+		CidrBlock:				awsRoute.DestinationCidrBlock,
+		IPv6CidrBlock:			awsRoute.DestinationIPv6CidrBlock,
+		GatewayID:				awsRoute.GatewayID,
+		NatGatewayID:			awsRoute.NatGatewayID,
+		EgressOnlyGatewayID:	awsRoute.EgressOnlyGatewayID,
+		TransitGatewayID:		awsRoute.TransitGatewayID,
+		VpcPeeringConnectionID:	awsRoute.VpcPeeringConnectionID,
+		VpcEndpointID:			awsRoute.VpcEndpointID,
+	}
+
+	// The route is merged in its Route Table once all resources are parsed
+	a.standaloneRoutes = append(a.standaloneRoutes, standaloneRoute{address, awsRoute.RouteTableID, &route})
+}
+
+// addRouteTableAssociation adds a decoded aws_route_table_association to Data
+func (a *Data) addRouteTableAssociation(resourceType string, address string, value interface{}) {
+	awsRouteTableAssociation := value.(RouteTableAssociation)
+
+	// Add RouteTableAssociation to Data
+	a.RouteTableAssociation[address] = awsRouteTableAssociation
+}
+
+// addMainRouteTableAssociation adds a decoded aws_main_route_table_association to Data
+func (a *Data) addMainRouteTableAssociation(resourceType string, address string, value interface{}) {
+	awsMainRouteTableAssociation := value.(MainRouteTableAssociation)
+
+	// Add MainRouteTableAssociation to Data
+	a.MainRouteTableAssociation[address] = awsMainRouteTableAssociation
+}
+
+// addNetworkACL adds a decoded aws_network_acl to Data
+func (a *Data) addNetworkACL(resourceType string, address string, value interface{}) {
+	awsNetworkACL := value.(NetworkACL)
+
+	// Add NetworkACL to Data (rules already added by aws_network_acl_rule resources are kept)
+	if networkACL, found := a.NetworkACL[address]; found {
+		awsNetworkACL.Ingress = append(awsNetworkACL.Ingress, networkACL.Ingress...)
+		awsNetworkACL.Egress = append(awsNetworkACL.Egress, networkACL.Egress...)
+	}
+	a.NetworkACL[address] = awsNetworkACL
+}
+
+// addDefaultNetworkACL adds a decoded aws_default_network_acl to Data
+func (a *Data) addDefaultNetworkACL(resourceType string, address string, value interface{}) {
+	awsDefaultNetworkACL := value.(DefaultNetworkACL)
+
+	// The default network ACL of a VPC is merged like aws_network_acl_rule resources
+	a.standaloneNACLRules = append(a.standaloneNACLRules, standaloneNACLRule{address, awsDefaultNetworkACL.DefaultNetworkACLID, 0, nil})
+	for i := range awsDefaultNetworkACL.Ingress {
+		a.standaloneNACLRules = append(a.standaloneNACLRules, standaloneNACLRule{address, awsDefaultNetworkACL.DefaultNetworkACLID, ingressRule, &awsDefaultNetworkACL.Ingress[i]})
+	}
+	for i := range awsDefaultNetworkACL.Egress {
+		a.standaloneNACLRules = append(a.standaloneNACLRules, standaloneNACLRule{address, awsDefaultNetworkACL.DefaultNetworkACLID, egressRule, &awsDefaultNetworkACL.Egress[i]})
+	}
+	if awsDefaultNetworkACL.SubnetIDs != nil {
+		for _, subnetID := range *awsDefaultNetworkACL.SubnetIDs {
+			a.NetworkACLAssociation[address+"."+subnetID] = NetworkACLAssociation{
+				NetworkACLID:	awsDefaultNetworkACL.DefaultNetworkACLID,
+				SubnetID:		subnetID,
+			}
 		}
-		err := createDefaultSubnet(graph, clusterName)
-		if err != nil {
-			return err
+	}
+}
+
+// addNetworkACLRule adds a decoded aws_network_acl_rule to Data
+func (a *Data) addNetworkACLRule(resourceType string, address string, value interface{}) {
+	awsNetworkACLRule := value.(NetworkACLRule)
+
+	rule := NACLRule{
+		RuleNo:			awsNetworkACLRule.RuleNumber,
+		Action:			awsNetworkACLRule.RuleAction,
+		Protocol:		awsNetworkACLRule.Protocol,
+		CidrBlock:		awsNetworkACLRule.CidrBlock,
+		IPv6CidrBlock:	awsNetworkACLRule.IPv6CidrBlock,
+	}
+	if awsNetworkACLRule.FromPort != nil {
+		rule.FromPort = *awsNetworkACLRule.FromPort
+	}
+	if awsNetworkACLRule.ToPort != nil {
+		rule.ToPort = *awsNetworkACLRule.ToPort
+	}
+	ruleType := ingressRule
+	if awsNetworkACLRule.Egress != nil && *awsNetworkACLRule.Egress {
+		ruleType = egressRule
+	}
+
+	// The rule is merged in its Network ACL once all resources are parsed
+	a.standaloneNACLRules = append(a.standaloneNACLRules, standaloneNACLRule{address, awsNetworkACLRule.NetworkACLID, ruleType, &rule})
+}
+
+// addNetworkACLAssociation adds a decoded aws_network_acl_association to Data
+func (a *Data) addNetworkACLAssociation(resourceType string, address string, value interface{}) {
+	awsNetworkACLAssociation := value.(NetworkACLAssociation)
+
+	// Add NetworkACLAssociation to Data
+	a.NetworkACLAssociation[address] = awsNetworkACLAssociation
+}
+
+// addLB adds a decoded aws_lb or aws_alb to Data
+func (a *Data) addLB(resourceType string, address string, value interface{}) {
+	awsLB := value.(LB)
+
+	// Add LB to Data
+	a.LB[address] = awsLB
+
+	// Creating SG - LB connections to facilitate the edges creation for the graph
+	a.linkSecurityGroups(address, awsLB.SecurityGroups)
+}
+
+// addELB adds a decoded aws_elb to Data
+func (a *Data) addELB(resourceType string, address string, value interface{}) {
+	awsELB := value.(ELB)
+
+	// Add ELB to Data
+	a.ELB[address] = awsELB
+
+	// Creating SG - ELB connections to facilitate the edges creation for the graph
+	a.linkSecurityGroups(address, awsELB.SecurityGroups)
+}
+
+// addLBListener adds a decoded aws_lb_listener or aws_alb_listener to Data
+func (a *Data) addLBListener(resourceType string, address string, value interface{}) {
+	awsLBListener := value.(LBListener)
+
+	// Add LBListener to Data
+	a.LBListener[address] = awsLBListener
+}
+
+// addLBTargetGroup adds a decoded aws_lb_target_group or aws_alb_target_group to Data
+func (a *Data) addLBTargetGroup(resourceType string, address string, value interface{}) {
+	awsLBTargetGroup := value.(LBTargetGroup)
+
+	// Add LBTargetGroup to Data
+	a.LBTargetGroup[address] = awsLBTargetGroup
+}
+
+// addLBTargetGroupAttachment adds a decoded aws_lb_target_group_attachment or aws_alb_target_group_attachment to Data
+func (a *Data) addLBTargetGroupAttachment(resourceType string, address string, value interface{}) {
+	awsLBTargetGroupAttachment := value.(LBTargetGroupAttachment)
+
+	// Add LBTargetGroupAttachment to Data
+	a.LBTargetGroupAttachment[address] = awsLBTargetGroupAttachment
+}
+
+// addAutoscalingGroup adds a decoded aws_autoscaling_group to Data
+func (a *Data) addAutoscalingGroup(resourceType string, address string, value interface{}) {
+	awsAutoscalingGroup := value.(AutoscalingGroup)
+
+	// Add AutoscalingGroup to Data
+	a.AutoscalingGroup[address] = awsAutoscalingGroup
+}
+
+// addAutoscalingAttachment adds a decoded aws_autoscaling_attachment to Data
+func (a *Data) addAutoscalingAttachment(resourceType string, address string, value interface{}) {
+	awsAutoscalingAttachment := value.(AutoscalingAttachment)
+
+	// Add AutoscalingAttachment to Data
+	a.AutoscalingAttachment[address] = awsAutoscalingAttachment
+}
+
+// addLaunchTemplate adds a decoded aws_launch_template to Data
+func (a *Data) addLaunchTemplate(resourceType string, address string, value interface{}) {
+	awsLaunchTemplate := value.(LaunchTemplate)
+
+	// Add LaunchTemplate to Data
+	a.LaunchTemplate[address] = awsLaunchTemplate
+}
+
+// addLaunchConfiguration adds a decoded aws_launch_configuration to Data
+func (a *Data) addLaunchConfiguration(resourceType string, address string, value interface{}) {
+	awsLaunchConfiguration := value.(LaunchConfiguration)
+
+	// Add LaunchConfiguration to Data
+	a.LaunchConfiguration[address] = awsLaunchConfiguration
+}
+
+// addDBInstance adds a decoded aws_db_instance to Data
+func (a *Data) addDBInstance(resourceType string, address string, value interface{}) {
+	awsDBInstance := value.(DBInstance)
+
+	// Add DBInstance to Data
+	a.DBInstance[address] = awsDBInstance
+
+	if awsDBInstance.VpcSecurityGroupIDs != nil {
+		a.log.Verbosef("Security groups of %s: %v", address, *awsDBInstance.VpcSecurityGroupIDs)
+		for _, sg := range *awsDBInstance.VpcSecurityGroupIDs {
+			_, found := a.SecurityGroupNodeLinks[sg]
+			if found {
+				a.SecurityGroupNodeLinks[sg] = append(a.SecurityGroupNodeLinks[sg], address)
+			} else {
+				a.SecurityGroupNodeLinks[sg] = []string{address}
+			}
 		}
 	}
+}
+
+// addDBSubnetGroup adds a decoded aws_db_subnet_group to Data
+func (a *Data) addDBSubnetGroup(resourceType string, address string, value interface{}) {
+	awsDBSubnetGroup := value.(DBSubnetGroup)
+
+	// Add DBSubnetGroup to Data
+	a.DBSubnetGroup[address] = awsDBSubnetGroup
+}
+
+// addRDSCluster adds a decoded aws_rds_cluster to Data
+func (a *Data) addRDSCluster(resourceType string, address string, value interface{}) {
+	awsRDSCluster := value.(RDSCluster)
+
+	// Add RDSCluster to Data
+	a.RDSCluster[address] = awsRDSCluster
+	a.linkSecurityGroups(address, awsRDSCluster.VpcSecurityGroupIDs)
+}
+
+// addRDSClusterInstance adds a decoded aws_rds_cluster_instance to Data
+func (a *Data) addRDSClusterInstance(resourceType string, address string, value interface{}) {
+	awsRDSClusterInstance := value.(RDSClusterInstance)
+
+	// Add RDSClusterInstance to Data
+	a.RDSClusterInstance[address] = awsRDSClusterInstance
+}
+
+// addElastiCacheCluster adds a decoded aws_elasticache_cluster to Data
+func (a *Data) addElastiCacheCluster(resourceType string, address string, value interface{}) {
+	awsElastiCacheCluster := value.(ElastiCacheCluster)
+
+	// Add ElastiCacheCluster to Data
+	a.ElastiCacheCluster[address] = awsElastiCacheCluster
+	if stringValue(awsElastiCacheCluster.ReplicationGroupID) == "" {
+		// The clusters of a replication group are linked through their group
+		a.linkSecurityGroups(address, awsElastiCacheCluster.SecurityGroupIDs)
+	}
+}
+
+// addElastiCacheReplicationGroup adds a decoded aws_elasticache_replication_group to Data
+func (a *Data) addElastiCacheReplicationGroup(resourceType string, address string, value interface{}) {
+	awsElastiCacheReplicationGroup := value.(ElastiCacheReplicationGroup)
+
+	// Add ElastiCacheReplicationGroup to Data
+	a.ElastiCacheReplicationGroup[address] = awsElastiCacheReplicationGroup
+	a.linkSecurityGroups(address, awsElastiCacheReplicationGroup.SecurityGroupIDs)
+}
+
+// addElastiCacheSubnetGroup adds a decoded aws_elasticache_subnet_group to Data
+func (a *Data) addElastiCacheSubnetGroup(resourceType string, address string, value interface{}) {
+	awsElastiCacheSubnetGroup := value.(DBSubnetGroup)
+
+	// Add ElastiCacheSubnetGroup to Data
+	a.ElastiCacheSubnetGroup[address] = awsElastiCacheSubnetGroup
+}
+
+// addRedshiftCluster adds a decoded aws_redshift_cluster to Data
+func (a *Data) addRedshiftCluster(resourceType string, address string, value interface{}) {
+	awsRedshiftCluster := value.(RedshiftCluster)
+
+	// Add RedshiftCluster to Data
+	a.RedshiftCluster[address] = awsRedshiftCluster
+	a.linkSecurityGroups(address, awsRedshiftCluster.VpcSecurityGroupIDs)
+}
+
+// addRedshiftSubnetGroup adds a decoded aws_redshift_subnet_group to Data
+func (a *Data) addRedshiftSubnetGroup(resourceType string, address string, value interface{}) {
+	awsRedshiftSubnetGroup := value.(DBSubnetGroup)
+
+	// Add RedshiftSubnetGroup to Data
+	a.RedshiftSubnetGroup[address] = awsRedshiftSubnetGroup
+}
+
+// addOpenSearchDomain adds a decoded aws_opensearch_domain or aws_elasticsearch_domain to Data
+func (a *Data) addOpenSearchDomain(resourceType string, address string, value interface{}) {
+	awsOpenSearchDomain := value.(OpenSearchDomain)
+
+	// Add OpenSearchDomain to Data
+	a.OpenSearchDomain[address] = awsOpenSearchDomain
+	for _, vpcOptions := range awsOpenSearchDomain.VpcOptions {
+		a.linkSecurityGroups(address, vpcOptions.SecurityGroupIDs)
+	}
+}
+
+// addLambdaFunction adds a decoded aws_lambda_function to Data
+func (a *Data) addLambdaFunction(resourceType string, address string, value interface{}) {
+	awsLambdaFunction := value.(LambdaFunction)
+
+	// Add LambdaFunction to Data
+	a.LambdaFunction[address] = awsLambdaFunction
+	for _, vpcConfig := range awsLambdaFunction.VpcConfig {
+		a.linkSecurityGroups(address, &vpcConfig.SecurityGroupIDs)
+	}
+}
+
+// addLambdaEventSourceMapping adds a decoded aws_lambda_event_source_mapping to Data
+func (a *Data) addLambdaEventSourceMapping(resourceType string, address string, value interface{}) {
+	awsLambdaEventSourceMapping := value.(LambdaEventSourceMapping)
+
+	// Add LambdaEventSourceMapping to Data
+	a.LambdaEventSourceMapping[address] = awsLambdaEventSourceMapping
+}
+
+// addLambdaPermission adds a decoded aws_lambda_permission to Data
+func (a *Data) addLambdaPermission(resourceType string, address string, value interface{}) {
+	awsLambdaPermission := value.(LambdaPermission)
+
+	// Add LambdaPermission to Data
+	a.LambdaPermission[address] = awsLambdaPermission
+}
+
+// addS3BucketNotification adds a decoded aws_s3_bucket_notification to Data
+func (a *Data) addS3BucketNotification(resourceType string, address string, value interface{}) {
+	awsS3BucketNotification := value.(S3BucketNotification)
+
+	// Add S3BucketNotification to Data
+	a.S3BucketNotification[address] = awsS3BucketNotification
+}
+
+// addAPIGateway adds a decoded aws_api_gateway_rest_api or aws_apigatewayv2_api to Data
+func (a *Data) addAPIGateway(resourceType string, address string, value interface{}) {
+	awsAPIGateway := value.(APIGateway)
+
+	// Add APIGateway to Data
+	a.APIGateway[address] = awsAPIGateway
+}
+
+// addAPIGatewayIntegration adds a decoded aws_api_gateway_integration or aws_apigatewayv2_integration to Data
+func (a *Data) addAPIGatewayIntegration(resourceType string, address string, value interface{}) {
+	awsAPIGatewayIntegration := value.(APIGatewayIntegration)
+
+	// Add APIGatewayIntegration to Data
+	a.APIGatewayIntegration[address] = awsAPIGatewayIntegration
+}
+
+// addECSCluster adds a decoded aws_ecs_cluster to Data
+func (a *Data) addECSCluster(resourceType string, address string, value interface{}) {
+	awsECSCluster := value.(ECSCluster)
+
+	// Add ECSCluster to Data
+	a.ECSCluster[address] = awsECSCluster
+}
+
+// addECSService adds a decoded aws_ecs_service to Data
+func (a *Data) addECSService(resourceType string, address string, value interface{}) {
+	awsECSService := value.(ECSService)
+
+	// Add ECSService to Data
+	a.ECSService[address] = awsECSService
+	for _, networkConfiguration := range awsECSService.NetworkConfiguration {
+		a.linkSecurityGroups(address, networkConfiguration.SecurityGroups)
+	}
+}
+
+// addECSTaskDefinition adds a decoded aws_ecs_task_definition to Data
+func (a *Data) addECSTaskDefinition(resourceType string, address string, value interface{}) {
+	awsECSTaskDefinition := value.(ECSTaskDefinition)
+
+	// Add ECSTaskDefinition to Data
+	a.ECSTaskDefinition[address] = awsECSTaskDefinition
+}
+
+// addEKSCluster adds a decoded aws_eks_cluster to Data
+func (a *Data) addEKSCluster(resourceType string, address string, value interface{}) {
+	awsEKSCluster := value.(EKSCluster)
+
+	// Add EKSCluster to Data
+	a.EKSCluster[address] = awsEKSCluster
+	for _, vpcConfig := range awsEKSCluster.VpcConfig {
+		a.linkSecurityGroups(address, vpcConfig.SecurityGroupIDs)
+	}
+}
+
+// addEKSNodeGroup adds a decoded aws_eks_node_group to Data
+func (a *Data) addEKSNodeGroup(resourceType string, address string, value interface{}) {
+	awsEKSNodeGroup := value.(EKSNodeGroup)
+
+	// Add EKSNodeGroup to Data
+	a.EKSNodeGroup[address] = awsEKSNodeGroup
+}
+
+// addVpcPeeringConnection adds a decoded aws_vpc_peering_connection to Data
+func (a *Data) addVpcPeeringConnection(resourceType string, address string, value interface{}) {
+	awsVpcPeeringConnection := value.(VpcPeeringConnection)
+
+	// Add VpcPeeringConnection to Data
+	a.VpcPeeringConnection[address] = awsVpcPeeringConnection
+}
+
+// addTransitGateway adds a decoded aws_ec2_transit_gateway to Data
+func (a *Data) addTransitGateway(resourceType string, address string, value interface{}) {
+	awsTransitGateway := value.(TransitGateway)
+
+	// Add TransitGateway to Data
+	a.TransitGateway[address] = awsTransitGateway
+}
+
+// addTransitGatewayVpcAttachment adds a decoded aws_ec2_transit_gateway_vpc_attachment to Data
+func (a *Data) addTransitGatewayVpcAttachment(resourceType string, address string, value interface{}) {
+	awsTransitGatewayVpcAttachment := value.(TransitGatewayVpcAttachment)
+
+	// Add TransitGatewayVpcAttachment to Data
+	a.TransitGatewayVpcAttachment[address] = awsTransitGatewayVpcAttachment
+}
+
+// addTransitGatewayRouteTable adds a decoded aws_ec2_transit_gateway_route_table to Data
+func (a *Data) addTransitGatewayRouteTable(resourceType string, address string, value interface{}) {
+	awsTransitGatewayRouteTable := value.(TransitGatewayRouteTable)
+
+	// Add TransitGatewayRouteTable to Data
+	a.TransitGatewayRouteTable[address] = awsTransitGatewayRouteTable
+}
+
+// addTransitGatewayRoute adds a decoded aws_ec2_transit_gateway_route to Data
+func (a *Data) addTransitGatewayRoute(resourceType string, address string, value interface{}) {
+	awsTransitGatewayRoute := value.(TransitGatewayRoute)
+
+	// Add TransitGatewayRoute to Data
+	a.TransitGatewayRoute[address] = awsTransitGatewayRoute
+}
+
+// addVpnGateway adds a decoded aws_vpn_gateway to Data
+func (a *Data) addVpnGateway(resourceType string, address string, value interface{}) {
+	awsVpnGateway := value.(VpnGateway)
+
+	// Add VpnGateway to Data
+	a.VpnGateway[address] = awsVpnGateway
+}
+
+// addVpnGatewayAttachment adds a decoded aws_vpn_gateway_attachment to Data
+func (a *Data) addVpnGatewayAttachment(resourceType string, address string, value interface{}) {
+	awsVpnGatewayAttachment := value.(VpnGatewayAttachment)
+
+	// Add VpnGatewayAttachment to Data
+	a.VpnGatewayAttachment[address] = awsVpnGatewayAttachment
+}
 
-	if !a.defaultSecurityGroup {
-		// Create default security group
-		err := createDefaultSecurityGroup(graph)
-		if err != nil {
-			return err
-		}
-		a.undefinedSecurityGroups = append(a.undefinedSecurityGroups, "sg-default")
-	}
-	return nil
+// addCustomerGateway adds a decoded aws_customer_gateway to Data
+func (a *Data) addCustomerGateway(resourceType string, address string, value interface{}) {
+	awsCustomerGateway := value.(CustomerGateway)
+
+	// Add CustomerGateway to Data
+	a.CustomerGateway[address] = awsCustomerGateway
 }
 
-// ParseTfResources parse the TF file / module to identify resources that will be used later on to create the graph
-func (a *Data) ParseTfResources(tfModule *tfconfigs.Module, ctx *hcl2.EvalContext, graph *gographviz.Escape) (error) {
-	for _, v := range tfModule.ManagedResources {
-		switch v.Type {
-		case "aws_vpc":
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] Decoding %s.%s\n", v.Type, v.Name)
-			}
-			var Vpc Vpc
-			diags := gohcl.DecodeBody(v.Config, ctx, &Vpc)
-			utils.PrintDiags(diags)
+// addVpnConnection adds a decoded aws_vpn_connection to Data
+func (a *Data) addVpnConnection(resourceType string, address string, value interface{}) {
+	awsVpnConnection := value.(VpnConnection)
 
-			// Add Vpc to Data
-			a.Vpc[v.Name] = Vpc
+	// Add VpnConnection to Data
+	a.VpnConnection[address] = awsVpnConnection
+}
 
-		case "aws_subnet":
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] Decoding %s.%s\n", v.Type, v.Name)
-			}
-			var awsSubnet Subnet
-			diags := gohcl.DecodeBody(v.Config, ctx, &awsSubnet)
-			utils.PrintDiags(diags)
+// addVpnConnectionRoute adds a decoded aws_vpn_connection_route to Data
+func (a *Data) addVpnConnectionRoute(resourceType string, address string, value interface{}) {
+	awsVpnConnectionRoute := value.(VpnConnectionRoute)
 
-			// Add Subnet to Data
-			a.Subnet[v.Name] = awsSubnet
+	// Add VpnConnectionRoute to Data
+	a.VpnConnectionRoute[address] = awsVpnConnectionRoute
+}
 
-		case "aws_instance":
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] Decoding %s.%s\n", v.Type, v.Name)
-			}
-			var awsInstance Instance
-			diags := gohcl.DecodeBody(v.Config, ctx, &awsInstance)
-			utils.PrintDiags(diags)
-			
-			// Add Instance to Data
-			a.Instance[v.Name] = awsInstance
-
-			// Creating SG - Instance connections to facilitate the edges creation for the graph
-			if awsInstance.SecurityGroups != nil {
-				for _, sg := range *awsInstance.SecurityGroups {
-					_, found := a.SecurityGroupNodeLinks[sg]
-					if found {
-						a.SecurityGroupNodeLinks[sg] = append(a.SecurityGroupNodeLinks[sg], v.Type+"."+v.Name)
-					} else {
-						a.SecurityGroupNodeLinks[sg] = []string{v.Type+"."+v.Name}
-					}
-				}
-			}
-			if awsInstance.VpcSecurityGroupIDs != nil {
-				for _, sg := range *awsInstance.VpcSecurityGroupIDs {
-					_, found := a.SecurityGroupNodeLinks[sg]
-					if found {
-						a.SecurityGroupNodeLinks[sg] = append(a.SecurityGroupNodeLinks[sg], v.Type+"."+v.Name)
-					} else {
-						a.SecurityGroupNodeLinks[sg] = []string{v.Type+"."+v.Name}
-					}
-				}
-			}
+// addVpcEndpoint adds a decoded aws_vpc_endpoint to Data
+func (a *Data) addVpcEndpoint(resourceType string, address string, value interface{}) {
+	awsVpcEndpoint := value.(VpcEndpoint)
 
-		case "aws_security_group":
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] Decoding %s.%s\n", v.Type, v.Name)
-			}
-			var awsSecurityGroup SecurityGroup
-			diags := gohcl.DecodeBody(v.Config, ctx, &awsSecurityGroup)
-			utils.PrintDiags(diags)
+	// Add VpcEndpoint to Data (its SGs are linked once the SG associations are merged)
+	a.VpcEndpoint[address] = awsVpcEndpoint
+}
 
-			// Add SecurityGroup to Data
-			a.SecurityGroup["aws_security_group."+v.Name] = awsSecurityGroup
+// addVpcEndpointAssociation adds a decoded aws_vpc_endpoint_route_table_association, aws_vpc_endpoint_subnet_association or aws_vpc_endpoint_security_group_association to Data
+func (a *Data) addVpcEndpointAssociation(resourceType string, address string, value interface{}) {
+	awsVpcEndpointAssociation := value.(VpcEndpointAssociation)
 
-		case "aws_db_instance":
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] Decoding %s.%s\n", v.Type, v.Name)
-			}
-			var awsDBInstance DBInstance
-			diags := gohcl.DecodeBody(v.Config, ctx, &awsDBInstance)
-			utils.PrintDiags(diags)
-			
-			// Add DBInstance to Data
-			a.DBInstance[v.Name] = awsDBInstance
-
-			if awsDBInstance.VpcSecurityGroupIDs != nil {
-				fmt.Println("DEBUG (awsDBInstance.VpcSecurityGroupIDs):", *awsDBInstance.VpcSecurityGroupIDs)
-				for _, sg := range *awsDBInstance.VpcSecurityGroupIDs {
-					_, found := a.SecurityGroupNodeLinks[sg]
-					if found {
-						a.SecurityGroupNodeLinks[sg] = append(a.SecurityGroupNodeLinks[sg], v.Type+"."+v.Name)
-					} else {
-						a.SecurityGroupNodeLinks[sg] = []string{v.Type+"."+v.Name}
-					}
-					fmt.Println("DEBUG (a.SecurityGroupNodeLinks[sg]):", a.SecurityGroupNodeLinks[sg])
-				}
-			}
+	// Add VpcEndpointAssociation to Data
+	a.VpcEndpointAssociation[address] = awsVpcEndpointAssociation
+}
 
-		case "aws_db_subnet_group":
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] Decoding %s.%s\n", v.Type, v.Name)
-			}
-			var awsDBSubnetGroup DBSubnetGroup
-			diags := gohcl.DecodeBody(v.Config, ctx, &awsDBSubnetGroup)
-			utils.PrintDiags(diags)
-			
-			// Add DBSubnetGroup to Data
-			a.DBSubnetGroup[v.Name] = awsDBSubnetGroup
-		
-		case "aws_s3_bucket":
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] Decoding %s.%s\n", v.Type, v.Name)
-			}
-			var awsS3 S3
-			diags := gohcl.DecodeBody(v.Config, ctx, &awsS3)
-			utils.PrintDiags(diags)
-			
-			// Add S3 to Data
-			a.S3[v.Name] = awsS3
+// addVpcEndpointService adds a decoded aws_vpc_endpoint_service to Data
+func (a *Data) addVpcEndpointService(resourceType string, address string, value interface{}) {
+	awsVpcEndpointService := value.(VpcEndpointService)
+
+	// Add VpcEndpointService to Data
+	a.VpcEndpointService[address] = awsVpcEndpointService
+}
+
+// addS3 adds a decoded aws_s3_bucket to Data
+func (a *Data) addS3(resourceType string, address string, value interface{}) {
+	awsS3 := value.(S3)
+
+	// Add S3 to Data
+	a.S3[address] = awsS3
+}
 
-		default:
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] Can't decode %s.%s (not yet supported)\n", v.Type, v.Name)
+// mergeSecurityGroupRules adds the rules declared as standalone resources to the rules of their Security Group.
+// It must be called once all resources are parsed, as rules can be parsed before their Security Group
+func (a *Data) mergeSecurityGroupRules() {
+	for _, r := range a.standaloneSGRules {
+		if r.SecurityGroupID == "" {
+			a.log.Error(fmt.Errorf("%s: unknown security_group_id, the rule is ignored", r.Address))
+			continue
+		}
+		a.log.Verbosef("Merging %s in %s", r.Address, r.SecurityGroupID)
+		sg, found := a.SecurityGroup[r.SecurityGroupID]
+		if !found {
+			// The Security Group is not defined in TF: its rules are kept aside
+			if a.undefinedSecurityGroupRules == nil {
+				a.undefinedSecurityGroupRules = make(map[string]SecurityGroup)
 			}
-			a.unsupportedResources = append(a.unsupportedResources, v.Type + "." + v.Name)
+			sg = a.undefinedSecurityGroupRules[r.SecurityGroupID]
+		}
+		if r.RuleType == ingressRule {
+			sg.Ingress = append(sg.Ingress, r.Rule)
+		} else {
+			sg.Egress = append(sg.Egress, r.Rule)
+		}
+		if found {
+			a.SecurityGroup[r.SecurityGroupID] = sg
+		} else {
+			a.undefinedSecurityGroupRules[r.SecurityGroupID] = sg
 		}
 	}
-
-	return nil
+	a.standaloneSGRules = nil
 }
 
 // CreateGraphNodes creates the nodes for the graph
-func (a *Data) CreateGraphNodes(graph *gographviz.Escape) (error) {
-	// Add VPC clusters to graph
+func (a *Data) CreateGraphNodes(graph *model.Graph) (error) {
+	// Add default VPC / subnet / Security Group if needed
+	err := a.CreateDefaultNodes(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add VPC groups to graph
 	for vpcName := range a.Vpc {
-		err := createVpc(graph, vpcName)
+		err := a.createVpc(graph, vpcName, a.resourceIDs[vpcName])
 		if err != nil {
 			return err
 		}
 	}
 
-	// Add Subnet clusters to graph
+	// Add Subnet groups to graph
 	for subnetName, subnetObj := range a.Subnet {
-		err := createSubnet(graph, subnetName, subnetObj)
+		err := a.createSubnet(graph, subnetName, subnetObj, a.resourceIDs[subnetName], a.subnetRouting(subnetName), a.subnetNetworkACL(subnetName))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add Internet Gateway nodes to graph
+	for igwName, igwObj := range a.InternetGateway {
+		err := a.createInternetGateway(graph, igwName, igwObj, false, a.Vpc)
+		if err != nil {
+			return err
+		}
+	}
+	for igwName, igwObj := range a.EgressOnlyInternetGateway {
+		err := a.createInternetGateway(graph, igwName, igwObj, true, a.Vpc)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add NAT Gateway nodes to graph
+	for natName, natObj := range a.NatGateway {
+		err := a.createNatGateway(graph, natName, natObj, a.Subnet)
 		if err != nil {
 			return err
 		}
@@ -664,10 +2289,13 @@ func (a *Data) CreateGraphNodes(graph *gographviz.Escape) (error) {
 
 	// Add Instance nodes to graph
 	for instanceName, instanceObj := range a.Instance {
-		err := createInstance(graph, instanceName, instanceObj)
+		err := a.createInstance(graph, instanceName, instanceObj)
 		if err != nil {
 			return err
 		}
+		if instanceObj.SubnetID != nil {
+			a.setNodeSubnet(utils.NodeID(instanceName), *instanceObj.SubnetID)
+		}
 	}
 
 	// Add DB Instance nodes to graph
@@ -678,9 +2306,61 @@ func (a *Data) CreateGraphNodes(graph *gographviz.Escape) (error) {
 		}
 	}
 
+	// Add the other data store nodes (Aurora, ElastiCache, Redshift, OpenSearch) to graph
+	err = a.createDataStores(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add LB nodes to graph
+	for lbName, lbObj := range a.LB {
+		err := a.createLB(graph, lbName, lbObj)
+		if err != nil {
+			return err
+		}
+	}
+	for elbName, elbObj := range a.ELB {
+		err := a.createELB(graph, elbName, elbObj)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add VPC endpoint and VPC endpoint service nodes to graph
+	err = a.createVpcEndpoints(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add Transit Gateway, VPN Gateway and Customer Gateway nodes to graph
+	err = a.createNetworkGateways(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add Lambda function and API Gateway nodes to graph
+	err = a.createServerless(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add ECS service and EKS nodes to graph
+	err = a.createContainers(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add Auto Scaling Group nodes to graph
+	for asgName, asgObj := range a.AutoscalingGroup {
+		err := a.createAutoscalingGroup(graph, asgName, asgObj)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Add S3 bucket nodes to graph
 	for s3Name, s3Obj := range a.S3 {
-		err := createS3(graph, s3Name, s3Obj)
+		err := a.createS3(graph, s3Name, s3Obj)
 		if err != nil {
 			return err
 		}
@@ -689,51 +2369,148 @@ func (a *Data) CreateGraphNodes(graph *gographviz.Escape) (error) {
 	return nil
 }
 
-func createInternetSGRuleEdge(ruleType int, nodeName string, graph *gographviz.Escape) (error) {
+func (a *Data) createInternetSGRuleEdge(ruleType int, nodeName string, sgName string, rule SGRule) {
 	// Highlight Ingress from 0.0.0.0/0 and Egress to 0.0.0.0/0 in red
 
+	// AWS services reachable through the Gateway endpoints of the node subnet are not reached through the Internet
+	if ruleType == egressRule {
+		for _, endpoint := range a.subnetGatewayEndpoints(a.nodeSubnets[nodeName]) {
+			a.addSGEdge(nodeName, utils.NodeID(endpoint), ruleFlow(ruleType, sgName, rule), a.blockedByNACL(ruleType, nodeName, rule, "0.0.0.0/0", ""))
+		}
+	}
+
+	// If the route table of the node subnet is known, the Internet is only reachable through its gateways
+	internet := "Internet"
+	routes, known := a.defaultRoutes(a.nodeSubnets[nodeName])
+	if known {
+		target := routes["0.0.0.0/0"]
+		_, igw := a.InternetGateway[target]
+		_, nat := a.NatGateway[target]
+		switch {
+		case nat && ruleType == egressRule:
+			// Private subnet: egress traffic goes through the NAT Gateway
+			internet = utils.NodeID(target)
+		case !igw:
+			a.log.Verbosef("%s is not reachable from / can't reach the Internet (private subnet)", nodeName)
+			return
+		}
+	}
+
 	// Based on the rule type Ingress or Egress define the source and destination items
 	var src, dst string
 	if ruleType == ingressRule {
-		src, dst = "Internet", nodeName
+		src, dst = internet, nodeName
 	} else {
-		src, dst = nodeName, "Internet"
+		src, dst = nodeName, internet
 	}
 
-	if Verbose == true {
-		fmt.Printf("[VERBOSE] AddEdge: %s -> %s\n", src, dst)
+	a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), a.blockedByNACL(ruleType, nodeName, rule, "0.0.0.0/0", "")).Public = true
+}
+
+// ruleFlow returns the flow allowed by a SG rule: its protocol and port range (e.g. tcp/22, tcp/80-443 or all)
+func ruleFlow(ruleType int, sgName string, rule SGRule) *model.Flow {
+	flow := &model.Flow{
+		Direction:	model.Ingress,
+		Rule:		sgName,
 	}
-	err := graph.AddEdge(src, dst, true, map[string]string{
-		"color": "red",
-	})
-	if err != nil {
-		return err
+	if ruleType == egressRule {
+		flow.Direction = model.Egress
+	}
+	protocol := strings.ToLower(rule.Protocol)
+	switch protocol {
+	case "-1", "all":
+		flow.Protocol = "all"
+		return flow
+	case "1":
+		protocol = "icmp"
+	case "6":
+		protocol = "tcp"
+	case "17":
+		protocol = "udp"
+	case "58":
+		protocol = "icmpv6"
+	}
+	flow.Protocol = protocol
+	switch {
+	case protocol == "icmp" || protocol == "icmpv6":
+		// For ICMP, from_port is the ICMP type (-1 for all types)
+		if rule.FromPort >= 0 {
+			flow.Ports = fmt.Sprintf("%d", rule.FromPort)
+		}
+	case rule.FromPort == rule.ToPort:
+		flow.Ports = fmt.Sprintf("%d", rule.FromPort)
+	case rule.FromPort == 0 && rule.ToPort == 65535:
+		flow.Ports = "all"
+	default:
+		flow.Ports = fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort)
+	}
+	return flow
+}
+
+// addSGEdge records an edge created from a SG rule. Parallel rules between the same nodes are merged
+// in a single edge, their flows are appended. flow is nil for edges not created from a rule.
+// blocked is true if the traffic allowed by the rule is denied by a network ACL
+func (a *Data) addSGEdge(src string, dst string, flow *model.Flow, blocked bool) *model.Edge {
+	if a.sgEdgesIndex == nil {
+		a.sgEdgesIndex = make(map[string]*model.Edge)
+	}
+	key := src + " -> " + dst
+	edge, found := a.sgEdgesIndex[key]
+	if !found {
+		edge = &model.Edge{Src: src, Dst: dst, Kind: model.RuleEdge}
+		a.sgEdgesIndex[key] = edge
+		a.sgEdges = append(a.sgEdges, edge)
+	}
+	if flow == nil {
+		return edge
+	}
+	if blocked {
+		flow.Blocked = "blocked by NACL"
+	}
+	for _, f := range edge.Flows {
+		if f == *flow {
+			return edge
+		}
+	}
+	edge.Flows = append(edge.Flows, *flow)
+	return edge
+}
+
+// createSGEdges adds the edges recorded by addSGEdge to the graph
+func (a *Data) createSGEdges(graph *model.Graph) (error) {
+	for _, edge := range a.sgEdges {
+		a.log.Verbosef("AddEdge: %s -> %s", edge.Src, edge.Dst)
+		graph.AddEdge(edge)
 	}
 	return nil
 }
 
-func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *gographviz.Escape) (error) {
+func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *model.Graph) (error) {
 	// Based on the rule type Ingress or Egress define the source and destination items
 	var src, dst string
 	var sgRule []SGRule
+	sg, found := a.SecurityGroup[sgName]
+	if !found {
+		// Rules declared as standalone resources for a SG not defined in TF
+		sg = a.undefinedSecurityGroupRules[sgName]
+	}
 	if ruleType == ingressRule {
 		src, dst = sgName, nodeName
-		sgRule = a.SecurityGroup[sgName].Ingress
+		sgRule = sg.Ingress
 	} else {
 		src, dst = nodeName, sgName
-		sgRule = a.SecurityGroup[sgName].Egress
+		sgRule = sg.Egress
 	}
 
 	if _, found1 := a.SecurityGroup[sgName]; !found1 {
 		_, found2 := utils.Find(a.undefinedSecurityGroups, sgName)
 		if !found2 {
 			// If the SG is not defined in TF, we need to create the Node before the Edges
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] AddNode: %s to G\n", sgName)
-			}
-			err := graph.AddNode("G", sgName, map[string]string{
-				"style": "dotted",
-				"label": sgName,
+			a.log.Verbosef("AddNode: %s", sgName)
+			err := graph.AddNode(&model.Node{
+				ID:		sgName,
+				Kind:	model.SecurityGroupNode,
+				Label:	sgName,
 			})
 			if err != nil {
 				return err
@@ -742,28 +2519,19 @@ func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *
 		}
 
 		// The SG exists, we just need to link it with the appropriate nodes
-		if Verbose == true {
-			fmt.Printf("[VERBOSE] AddEdge: %s -> %s\n", src, dst)
-		}
-		err := graph.AddEdge(src, dst, true, nil)
-		if err != nil {
-			return err
-		}
+		a.addSGEdge(src, dst, nil, false)
 	}
 	for _, rule := range sgRule {
 		if rule.CidrBlocks != nil {
 			for _, cidr := range *rule.CidrBlocks {
 				// Special ingress/egress rule for 0.0.0.0/0
 				if cidr == "0.0.0.0/0" {
-					err := createInternetSGRuleEdge(ruleType, nodeName, graph)
-					if err != nil {
-						return err
-					}
+					a.createInternetSGRuleEdge(ruleType, nodeName, sgName, rule)
 				} else {
 					ipAddrSG, _, err := net.ParseCIDR(cidr)
 					if err != nil {
 						// Unrecognized SG name
-						utils.PrintError(err)
+						a.log.Error(err)
 					} else {
 						// The source/destination is a valid CIDR
 						edgeCreated := false
@@ -771,22 +2539,18 @@ func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *
 							// Checking for Security Group source/destination IP / Subnet matching
 							_, ipNetSubnet, err := net.ParseCIDR(v.CidrBlock)
 							if err != nil {
-								return err
+								// Invalid or unknown CIDR of the subnet, the other subnets are checked
+								a.log.Error(fmt.Errorf("%s: invalid cidr_block %q", k, v.CidrBlock))
+								continue
 							}
 							if ipNetSubnet.Contains(ipAddrSG) {
 								// the source/destination IP is part of this subnet CIDR
 								if ruleType == ingressRule {
-									src, dst = "aws_subnet_"+k, nodeName
+									src, dst = utils.NodeID(k), nodeName
 								} else {
-									src, dst = nodeName, "aws_subnet_"+k
-								}
-								if Verbose == true {
-									fmt.Printf("[VERBOSE] AddEdge: %s -> %s\n", src, dst)
-								}
-								err = graph.AddEdge(src, dst, true, nil)
-								if err != nil {
-									return err
+									src, dst = nodeName, utils.NodeID(k)
 								}
+								a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), a.blockedByNACL(ruleType, nodeName, rule, cidr, k))
 								edgeCreated = true
 							}
 						}
@@ -797,22 +2561,18 @@ func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *
 							for k, v := range a.Vpc {
 								_, ipNetVpc, err := net.ParseCIDR(v.CidrBlock)
 								if err != nil {
-									return err
+									// Invalid or unknown CIDR of the VPC, the other VPCs are checked
+									a.log.Error(fmt.Errorf("%s: invalid cidr_block %q", k, v.CidrBlock))
+									continue
 								}
 								if ipNetVpc.Contains(ipAddrSG) {
 									// the source/destination IP is part of this VPC CIDR
 									if ruleType == ingressRule {
-										src, dst = "aws_vpc_"+k, nodeName
+										src, dst = utils.NodeID(k), nodeName
 									} else {
-										src, dst = nodeName, "aws_vpc_"+k
-									}
-									if Verbose == true {
-										fmt.Printf("[VERBOSE] AddEdge: %s -> %s\n", src, dst)
-									}
-									err = graph.AddEdge(src, dst, true, nil)
-									if err != nil {
-										return err
+										src, dst = nodeName, utils.NodeID(k)
 									}
+									a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), a.blockedByNACL(ruleType, nodeName, rule, cidr, ""))
 									edgeCreated = true
 								}
 							}
@@ -820,11 +2580,27 @@ func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *
 
 						if !edgeCreated {
 							// Security Group source/destination IP did not matched with Subnet and VPC CIDRs
-							// Creating a node for the source/destination as it is likely to be an undefined IP/CIDR
-							if Verbose == true {
-								fmt.Printf("[VERBOSE] AddNode: %s to G\n", cidr)
+							// Checking the routes of the node subnet (VPC peering, Transit Gateway, VPN)
+							if peer := a.routedPeer(nodeName, cidr); peer != "" {
+								if ruleType == ingressRule {
+									src, dst = peer, nodeName
+								} else {
+									src, dst = nodeName, peer
+								}
+								a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), a.blockedByNACL(ruleType, nodeName, rule, cidr, ""))
+								edgeCreated = true
 							}
-							err := graph.AddNode("G", cidr, nil)
+						}
+
+						if !edgeCreated {
+							// Security Group source/destination IP did not matched with Subnet and VPC CIDRs
+							// Creating a node for the source/destination as it is likely to be an undefined IP/CIDR
+							a.log.Verbosef("AddNode: %s", cidr)
+							err := graph.AddNode(&model.Node{
+								ID:		cidr,
+								Kind:	model.CidrNode,
+								Label:	cidr,
+							})
 							if err != nil {
 								return err
 							}
@@ -833,35 +2609,58 @@ func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *
 							} else {
 								src, dst = nodeName, cidr
 							}
-							if Verbose == true {
-								fmt.Printf("[VERBOSE] AddEdge: %s -> %s\n", src, dst)
-							}
-							err = graph.AddEdge(src, dst, true, nil)
-							if err != nil {
-								return err
-							}
+							a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), a.blockedByNACL(ruleType, nodeName, rule, cidr, ""))
+						}
+					}
+				}
+			}
+		}
+
+		// Create a node for each prefix list (e.g. AWS services prefix lists)
+		if rule.PrefixListIDs != nil {
+			for _, pl := range *rule.PrefixListIDs {
+				// Prefix list of a Gateway endpoint (e.g. aws_vpc_endpoint.s3.prefix_list_id)
+				if _, found := a.VpcEndpoint[referencedResource(pl)]; found {
+					for _, endpointNode := range a.graphNodes(referencedResource(pl)) {
+						if ruleType == ingressRule {
+							src, dst = endpointNode, nodeName
+						} else {
+							src, dst = nodeName, endpointNode
 						}
+						a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), false)
 					}
+					continue
+				}
+				plID := utils.NodeID(pl)
+				a.log.Verbosef("AddNode: %s", plID)
+				err := graph.AddNode(&model.Node{
+					ID:		plID,
+					Kind:	model.PrefixListNode,
+					Label:	"Prefix list: " + pl,
+				})
+				if err != nil {
+					return err
+				}
+				if ruleType == ingressRule {
+					src, dst = plID, nodeName
+				} else {
+					src, dst = nodeName, plID
 				}
+				a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), false)
 			}
 		}
 
 		// Create edges for all instances linked to SGRule.Self
 		if rule.Self != nil && *rule.Self != false {
 			for _, v1 := range a.SecurityGroupNodeLinks[sgName] {
-				v2 := strings.Replace(v1, ".", "_", -1)
-				if v2 != nodeName {
-					if ruleType == ingressRule {
-						src, dst = v2, nodeName
-					} else {
-						src, dst = nodeName, v2
-					}
-					if Verbose == true {
-						fmt.Printf("[VERBOSE] AddEdge: %s -> %s\n", src, dst)
-					}
-					err := graph.AddEdge(src, dst, true, nil)
-					if err != nil {
-						return err
+				for _, v2 := range a.graphNodes(v1) {
+					if v2 != nodeName {
+						if ruleType == ingressRule {
+							src, dst = v2, nodeName
+						} else {
+							src, dst = nodeName, v2
+						}
+						a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), a.blockedByNACL(ruleType, nodeName, rule, "", a.nodeSubnets[v2]))
 					}
 				}
 			}
@@ -871,19 +2670,14 @@ func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *
 		if rule.SecurityGroups != nil {
 			for _, v1 := range *rule.SecurityGroups {
 				for _, v2 := range a.SecurityGroupNodeLinks[v1] {
-					v3 := strings.Replace(v2, ".", "_", -1)
-					if v3 != nodeName {
-						if ruleType == ingressRule {
-							src, dst = v3, nodeName
-						} else {
-							src, dst = nodeName, v3
-						}
-						if Verbose == true {
-							fmt.Printf("[VERBOSE] AddEdge: %s -> %s\n", src, dst)
-						}
-						err := graph.AddEdge(src, dst, true, nil)
-						if err != nil {
-							return err
+					for _, v3 := range a.graphNodes(v2) {
+						if v3 != nodeName {
+							if ruleType == ingressRule {
+								src, dst = v3, nodeName
+							} else {
+								src, dst = nodeName, v3
+							}
+							a.addSGEdge(src, dst, ruleFlow(ruleType, sgName, rule), a.blockedByNACL(ruleType, nodeName, rule, "", a.nodeSubnets[v3]))
 						}
 					}
 				}
@@ -896,7 +2690,7 @@ func (a *Data) parseSGRule(ruleType int, nodeName string, sgName string, graph *
 }
 
 // CreateGraphEdges creates edges for the graph
-func (a *Data) CreateGraphEdges(graph *gographviz.Escape) (error) {
+func (a *Data) CreateGraphEdges(graph *model.Graph) (error) {
 	// Link Instances with their Security Groups
 	for instanceName, instanceObj := range a.Instance {
 
@@ -911,19 +2705,7 @@ func (a *Data) CreateGraphEdges(graph *gographviz.Escape) (error) {
 
 		// This instance has no SG attached and so will inherit from the default SG
 		if len(SGs) == 0 {
-			_, found := utils.Find(a.undefinedSecurityGroups, "sg-default")
-			if !found {
-				// Create default security group
-				err := createDefaultSecurityGroup(graph)
-				if err != nil {
-					return err
-				}
-				a.undefinedSecurityGroups = append(a.undefinedSecurityGroups, "sg-default")
-			}
-			if Verbose == true {
-				fmt.Printf("[VERBOSE] AddEdge: sg-default -> aws_instance_%s\n", instanceName)
-			}
-			err := graph.AddEdge("sg-default", "aws_instance_"+instanceName, true, nil)
+			err := a.linkDefaultSecurityGroup(graph, utils.NodeID(instanceName))
 			if err != nil {
 				return err
 			}
@@ -931,13 +2713,19 @@ func (a *Data) CreateGraphEdges(graph *gographviz.Escape) (error) {
 		// The instance has at least one SG attached to it
 		for _, sg := range SGs {
 			// Parse Ingress SG rules
-			if !IgnoreIngress {
-				a.parseSGRule(ingressRule, "aws_instance_"+instanceName, sg, graph)
+			if !a.options.IgnoreIngress {
+				err := a.parseSGRule(ingressRule, utils.NodeID(instanceName), sg, graph)
+				if err != nil {
+					return err
+				}
 			}
 
 			// Parse Egress SG rules
-			if !IgnoreEgress {
-				a.parseSGRule(egressRule, "aws_instance_"+instanceName, sg, graph)
+			if !a.options.IgnoreEgress {
+				err := a.parseSGRule(egressRule, utils.NodeID(instanceName), sg, graph)
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -951,29 +2739,137 @@ func (a *Data) CreateGraphEdges(graph *gographviz.Escape) (error) {
 			SGs = append(SGs, *instanceObj.VpcSecurityGroupIDs...)
 		}
 
-		// The instance has at least one SG attached to it
-		for _, sg := range SGs {
-			// Parse Ingress SG rules
-			if !IgnoreIngress {
-				a.parseSGRule(ingressRule, "aws_db_instance_"+instanceName, sg, graph)
-			}
+		// The instance has at least one SG attached to it (parsed for each subnet of the DB instance)
+		a.parseSGRules(instanceName, SGs, graph)
+	}
 
-			// Parse Egress SG rules
-			if !IgnoreEgress {
-				a.parseSGRule(egressRule, "aws_db_instance_"+instanceName, sg, graph)
-			}
-		}
+	// Link the other data stores (Aurora, ElastiCache, Redshift, OpenSearch) with their Security Groups
+	a.createDataStoreEdges(graph)
+
+	// Link Lambda functions with their Security Groups and their triggers
+	err := a.createLambdaEdges(graph)
+	if err != nil {
+		return err
+	}
+
+	// Link Auto Scaling Groups with their Security Groups
+	err = a.createAutoscalingGroupEdges(graph)
+	if err != nil {
+		return err
+	}
+
+	// Link ECS services and EKS clusters / node groups with their Security Groups
+	err = a.createContainerEdges(graph)
+	if err != nil {
+		return err
+	}
+
+	// Link Load Balancers with their Security Groups and targets
+	err = a.createLBEdges(graph)
+	if err != nil {
+		return err
+	}
+
+	// Link VPC endpoints with their Security Groups, subnets and services
+	err = a.createVpcEndpointEdges(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add the VPC peering connections, Transit Gateway attachments and VPN connections
+	err = a.createConnectionEdges(graph)
+	if err != nil {
+		return err
 	}
 
+	// Add the routes from subnets to the Internet
+	err = a.createRouteEdges(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add the edges (merged by source / destination) to the graph
+	return a.createSGEdges(graph)
+}
+
+// linkDefaultSecurityGroup links a node without SG to the default SG
+func (a *Data) linkDefaultSecurityGroup(graph *model.Graph, nodeName string) (error) {
+	_, found := utils.Find(a.undefinedSecurityGroups, "sg-default")
+	if !found {
+		// Create default security group
+		err := a.createDefaultSecurityGroup(graph)
+		if err != nil {
+			return err
+		}
+		a.undefinedSecurityGroups = append(a.undefinedSecurityGroups, "sg-default")
+	}
+	a.addSGEdge("sg-default", nodeName, nil, false)
 	return nil
 }
 
-// PrintUnsupportedResources displays all resources currently unsupported by tfviz
-func (a *Data) PrintUnsupportedResources() {
-	if len(a.unsupportedResources) > 0 {
-		fmt.Println("[WARNING] Unsupported resources:")
-		for _, r := range a.unsupportedResources {
-			fmt.Println(" -", r)
+// setNodeSubnet records the subnet of a node
+func (a *Data) setNodeSubnet(nodeName string, subnetAddress string) {
+	if a.nodeSubnets == nil {
+		a.nodeSubnets = make(map[string]string)
+	}
+	a.nodeSubnets[nodeName] = subnetAddress
+}
+
+// linkSecurityGroups creates the SG - resource connections to facilitate the edges creation for the graph
+func (a *Data) linkSecurityGroups(address string, securityGroups *[]string) {
+	if securityGroups == nil {
+		return
+	}
+	for _, sg := range *securityGroups {
+		a.SecurityGroupNodeLinks[sg] = append(a.SecurityGroupNodeLinks[sg], address)
+	}
+}
+
+// createSubnetNodes creates the nodes of a resource spanning several subnets (e.g. load balancers): one node
+// per subnet (copy of node), suffixed with the subnet if there are several. If none of the subnets is known, node is
+// created in fallbackGroup
+func (a *Data) createSubnetNodes(graph *model.Graph, node *model.Node, subnets []string, fallbackGroup string) (error) {
+	address := node.Address
+	var knownSubnets []string
+	for _, subnet := range utils.RemoveDuplicateValues(subnets) {
+		if _, found := a.Subnet[subnet]; found {
+			knownSubnets = append(knownSubnets, subnet)
+		}
+	}
+	sort.Strings(knownSubnets)
+
+	if len(knownSubnets) == 0 {
+		a.log.Verbosef("AddNode: %s to %s", node.ID, fallbackGroup)
+		node.Parent = fallbackGroup
+		return graph.AddNode(node)
+	}
+
+	if a.nodeCopies == nil {
+		a.nodeCopies = make(map[string][]string)
+	}
+	for _, subnet := range knownSubnets {
+		id := utils.NodeID(address)
+		if len(knownSubnets) > 1 {
+			id += "__" + utils.NodeID(subnet)
+		}
+		a.log.Verbosef("AddNode: %s to %s", id, utils.NodeID(subnet))
+		subnetNode := *node
+		subnetNode.ID = id
+		subnetNode.Parent = utils.NodeID(subnet)
+		err := graph.AddNode(&subnetNode)
+		if err != nil {
+			return err
 		}
+		a.setNodeSubnet(id, subnet)
+		a.nodeCopies[address] = append(a.nodeCopies[address], id)
+	}
+	return nil
+}
+
+// graphNodes returns the IDs of the nodes drawn for a resource (several for resources spanning several subnets)
+func (a *Data) graphNodes(address string) []string {
+	if ids, found := a.nodeCopies[address]; found {
+		return ids
 	}
+	return []string{utils.NodeID(address)}
 }
\ No newline at end of file
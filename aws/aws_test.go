@@ -0,0 +1,651 @@
+package aws
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awalterschulze/gographviz"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/provider"
+	"github.com/steeve85/tfviz/render"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// testGraph draws the Terraform configuration of a testdata directory, like main does
+func testGraph(t *testing.T, dir string) *gographviz.Graph {
+	return testOptionsGraph(t, dir, provider.Options{}, render.Options{})
+}
+
+// testOptionsGraph draws the Terraform configuration of a testdata directory with provider and rendering options.
+// The diagnostics are collected in options.Log if it is set
+func testOptionsGraph(t *testing.T, dir string, options provider.Options, renderOptions render.Options) *gographviz.Graph {
+	if options.Log == nil {
+		options.Log = &utils.Logger{}
+	}
+	tfConfig, err := utils.ParseTFfile(filepath.Join("testdata", dir), options.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	providers := provider.New(options)
+	ctxs, err := providers.InitiateVariablesAndResources(tfConfig, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := providers.ParseTfResources(tfConfig, ctxs); err != nil {
+		t.Fatal(err)
+	}
+	return drawTestGraph(t, providers, renderOptions)
+}
+
+// testPlanGraph draws a JSON plan of the testdata directory
+func testPlanGraph(t *testing.T, file string) *gographviz.Graph {
+	providers := provider.New(provider.Options{Log: &utils.Logger{}})
+	if err := providers.ParseTfPlan(filepath.Join("testdata", file)); err != nil {
+		t.Fatal(err)
+	}
+	return drawTestGraph(t, providers, render.Options{})
+}
+
+// testStateGraph draws a state of the testdata directory
+func testStateGraph(t *testing.T, file string) *gographviz.Graph {
+	providers := provider.New(provider.Options{Log: &utils.Logger{}})
+	if err := providers.ParseTfState(filepath.Join("testdata", file)); err != nil {
+		t.Fatal(err)
+	}
+	return drawTestGraph(t, providers, render.Options{})
+}
+
+// drawTestGraph adds the nodes and edges of the parsed resources to a new graph and renders it, like main does
+func drawTestGraph(t *testing.T, providers *provider.Set, renderOptions render.Options) *gographviz.Graph {
+	graph := model.New()
+	if err := providers.CreateGraphNodes(graph); err != nil {
+		t.Fatal(err)
+	}
+	if err := providers.CreateGraphEdges(graph); err != nil {
+		t.Fatal(err)
+	}
+	dot, err := render.DOT(graph, renderOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := gographviz.Read([]byte(dot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// hasNode returns true if the graph has a node for a TF resource in a cluster
+func hasNode(graph *gographviz.Graph, address string, cluster string) bool {
+	return graph.Relations.ParentToChildren[cluster][utils.NodeID(address)]
+}
+
+// hasEdge returns true if the graph has an edge between two nodes
+func hasEdge(graph *gographviz.Graph, src string, dst string) bool {
+	return len(graph.Edges.SrcToDsts[src][dst]) > 0
+}
+
+// edgeLabel returns the label of the edge between two nodes
+func edgeLabel(graph *gographviz.Graph, src string, dst string) string {
+	for _, edge := range graph.Edges.SrcToDsts[src][dst] {
+		return edge.Attrs[gographviz.Label]
+	}
+	return ""
+}
+
+func TestModules(t *testing.T) {
+	graph := testGraph(t, "modules")
+	vpc := "cluster_" + utils.NodeID("module.network.aws_vpc.main")
+	subnet := "cluster_" + utils.NodeID("module.network.aws_subnet.public")
+	if !graph.Relations.ParentToChildren[vpc][subnet] {
+		t.Errorf("subnet of the child module not in the VPC of the child module")
+	}
+	// The subnet ID is an output of the child module
+	if !hasNode(graph, "aws_instance.web", subnet) {
+		t.Errorf("instance of the root module not in the subnet of the child module")
+	}
+}
+
+func TestModuleClusters(t *testing.T) {
+	graph := testOptionsGraph(t, "modules", provider.Options{ModuleClusters: true}, render.Options{})
+	module := "cluster_" + utils.NodeID("module.network")
+	if !graph.Relations.ParentToChildren[module]["cluster_"+utils.NodeID("module.network.aws_vpc.main")] {
+		t.Errorf("VPC of the child module not in the module cluster")
+	}
+}
+
+func TestLocals(t *testing.T) {
+	graph := testGraph(t, "locals")
+	vpc := "cluster_" + utils.NodeID("aws_vpc.main")
+	for _, subnet := range []string{"aws_subnet.private[0]", "aws_subnet.private[1]"} {
+		if !graph.Relations.ParentToChildren[vpc]["cluster_"+utils.NodeID(subnet)] {
+			t.Errorf("%s not in the VPC", subnet)
+		}
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	graph := testGraph(t, "functions")
+	if !hasNode(graph, "aws_instance.web[0]", "cluster_"+utils.NodeID(`aws_subnet.zone["a"]`)) {
+		t.Errorf("aws_instance.web[0] not in the subnet a")
+	}
+	if !hasNode(graph, "aws_instance.web[1]", "cluster_"+utils.NodeID(`aws_subnet.zone["b"]`)) {
+		t.Errorf("aws_instance.web[1] not in the subnet b")
+	}
+}
+
+func TestParseTfPlan(t *testing.T) {
+	// The IDs known after apply are replaced by the addresses of the resources they reference
+	graph := testPlanGraph(t, "plan.json")
+	subnet := "cluster_" + utils.NodeID("aws_subnet.public")
+	if !graph.Relations.ParentToChildren["cluster_"+utils.NodeID("aws_vpc.main")][subnet] {
+		t.Errorf("subnet not in the VPC")
+	}
+	for _, instance := range []string{"aws_instance.web[0]", "aws_instance.web[1]"} {
+		if !hasNode(graph, instance, subnet) {
+			t.Errorf("%s not in the subnet", instance)
+		}
+	}
+}
+
+func TestParseTfState(t *testing.T) {
+	// The real IDs are replaced by the addresses of the resources and added to the labels
+	graph := testStateGraph(t, "terraform.tfstate")
+	vpc := "cluster_" + utils.NodeID("aws_vpc.main")
+	subnet := "cluster_" + utils.NodeID("module.network.aws_subnet.public[0]")
+	if !graph.Relations.ParentToChildren[vpc][subnet] {
+		t.Errorf("subnet not in the VPC")
+	}
+	if !hasNode(graph, "aws_instance.web", subnet) {
+		t.Errorf("instance not in the subnet")
+	}
+	if label := graph.SubGraphs.SubGraphs[vpc].Attrs[gographviz.Label]; label != `"VPC: main (vpc-0a1b2c3d)"` {
+		t.Errorf("got VPC label %s", label)
+	}
+}
+
+func TestParseTfStateVersion(t *testing.T) {
+	if err := provider.New(provider.Options{Log: &utils.Logger{}}).ParseTfState(filepath.Join("testdata", "v3.tfstate")); err == nil {
+		t.Errorf("no error for a version 3 state")
+	}
+}
+
+func TestStandaloneSecurityGroupRules(t *testing.T) {
+	graph := testGraph(t, "sgrules")
+	web, legacy := utils.NodeID("aws_instance.web"), utils.NodeID("aws_instance.legacy")
+	if !hasEdge(graph, "Internet", web) {
+		t.Errorf("aws_security_group_rule not merged in its security group")
+	}
+	if !hasEdge(graph, web, "Internet") {
+		t.Errorf("aws_vpc_security_group_egress_rule not merged in its security group")
+	}
+	if !hasEdge(graph, "Internet", legacy) {
+		t.Errorf("rule of a security group not defined in TF not applied")
+	}
+}
+
+func TestParseTfResourceExpandsDynamicBlocks(t *testing.T) {
+	src := `
+vpc_id = "aws_vpc.main"
+
+ingress {
+  from_port   = 22
+  to_port     = 22
+  protocol    = "tcp"
+  cidr_blocks = ["10.0.0.0/16"]
+}
+
+dynamic "ingress" {
+  for_each = var.ports
+  content {
+    from_port   = ingress.value
+    to_port     = ingress.value
+    protocol    = "tcp"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+dynamic "egress" {
+  for_each = var.egress
+  iterator = rule
+  content {
+    from_port   = rule.value.port
+    to_port     = rule.value.port
+    protocol    = rule.value.protocol
+    cidr_blocks = [rule.key]
+  }
+}
+`
+	file, diags := hclparse.NewParser().ParseHCL([]byte(src), "main.tf")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	ctx := &hcl2.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{
+				"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)}),
+				"egress": cty.MapVal(map[string]cty.Value{
+					"10.1.0.0/16": cty.ObjectVal(map[string]cty.Value{"port": cty.NumberIntVal(5432), "protocol": cty.StringVal("tcp")}),
+				}),
+			}),
+		},
+	}
+
+	log := &utils.Logger{}
+	a := NewData(provider.Options{Log: log})
+	if !a.parseTfResource("aws_security_group", "aws_security_group.web", file.Body, ctx) {
+		t.Fatal("aws_security_group not decoded")
+	}
+	if diags := log.Diagnostics(); len(diags) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	sg := a.SecurityGroup["aws_security_group.web"]
+	wantIngress := []struct {
+		port	int
+		cidr	string
+	}{{22, "10.0.0.0/16"}, {80, "0.0.0.0/0"}, {443, "0.0.0.0/0"}}
+	if len(sg.Ingress) != len(wantIngress) {
+		t.Fatalf("got %d ingress rules, want %d", len(sg.Ingress), len(wantIngress))
+	}
+	for i, want := range wantIngress {
+		rule := sg.Ingress[i]
+		if rule.FromPort != want.port || rule.ToPort != want.port || rule.CidrBlocks == nil || (*rule.CidrBlocks)[0] != want.cidr {
+			t.Errorf("ingress rule %d = %d-%d from %v, want %d from %s", i, rule.FromPort, rule.ToPort, rule.CidrBlocks, want.port, want.cidr)
+		}
+	}
+	if len(sg.Egress) != 1 || sg.Egress[0].FromPort != 5432 || sg.Egress[0].Protocol != "tcp" || (*sg.Egress[0].CidrBlocks)[0] != "10.1.0.0/16" {
+		t.Errorf("got egress rules %+v, want tcp/5432 to 10.1.0.0/16", sg.Egress)
+	}
+}
+
+func TestInvalidSubnetCidr(t *testing.T) {
+	// The subnet with an invalid CIDR is reported and the rules are matched with the other subnets
+	log := &utils.Logger{}
+	graph := testOptionsGraph(t, "badcidr", provider.Options{Log: log}, render.Options{})
+	app := utils.NodeID("aws_subnet.app")
+	for _, node := range []string{"aws_instance.web", "aws_lb.front"} {
+		if !hasEdge(graph, app, utils.NodeID(node)) {
+			t.Errorf("no edge from the subnet app to %s", node)
+		}
+	}
+	errors := 0
+	for _, d := range log.Diagnostics() {
+		if d.Severity == utils.ErrorSeverity {
+			if !strings.Contains(d.Message, `aws_subnet.bad: invalid cidr_block "10.0.1.0/33"`) {
+				t.Errorf("unexpected error: %s", d)
+			}
+			errors++
+		}
+	}
+	if errors == 0 {
+		t.Error("invalid CIDR of the subnet bad not reported")
+	}
+}
+
+func TestRuleFlow(t *testing.T) {
+	tests := []struct {
+		rule		SGRule
+		label		string
+	}{
+		{SGRule{Protocol: "-1"}, "all"},
+		{SGRule{Protocol: "tcp", FromPort: 22, ToPort: 22}, "tcp/22"},
+		{SGRule{Protocol: "6", FromPort: 80, ToPort: 443}, "tcp/80-443"},
+		{SGRule{Protocol: "udp", FromPort: 0, ToPort: 65535}, "udp/all"},
+		{SGRule{Protocol: "icmp", FromPort: -1, ToPort: -1}, "icmp"},
+		{SGRule{Protocol: "icmp", FromPort: 8, ToPort: 0}, "icmp/8"},
+	}
+	for _, test := range tests {
+		flow := ruleFlow(egressRule, "aws_security_group.web", test.rule)
+		if label := render.FlowLabel(*flow); label != test.label {
+			t.Errorf("ruleFlow(%+v) = %q, want %q", test.rule, label, test.label)
+		}
+		if flow.Direction != model.Egress || flow.Rule != "aws_security_group.web" {
+			t.Errorf("ruleFlow(%+v) = %+v, direction and rule not set", test.rule, flow)
+		}
+	}
+}
+
+func TestEdgeLabels(t *testing.T) {
+	// Parallel rules are merged in a single edge
+	graph := testGraph(t, "labels")
+	web := utils.NodeID("aws_instance.web")
+	if n := len(graph.Edges.SrcToDsts["Internet"][web]); n != 1 {
+		t.Errorf("got %d edges from the Internet, want 1", n)
+	}
+	if label := edgeLabel(graph, "Internet", web); label != "\"tcp/80-443\nudp/53\"" {
+		t.Errorf("got ingress label %s", label)
+	}
+	if label := edgeLabel(graph, web, "Internet"); label != `"all"` {
+		t.Errorf("got egress label %s", label)
+	}
+
+	graph = testOptionsGraph(t, "labels", provider.Options{}, render.Options{DisableEdgeLabels: true})
+	if label := edgeLabel(graph, "Internet", web); label != "" {
+		t.Errorf("got label %s with DisableEdgeLabels", label)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	graph := testGraph(t, "routes")
+	for subnet, label := range map[string]string{
+		"aws_subnet.public":	`"Public subnet: public"`,
+		"aws_subnet.private":	`"Private subnet: private"`,
+	} {
+		if got := graph.SubGraphs.SubGraphs["cluster_"+utils.NodeID(subnet)].Attrs[gographviz.Label]; got != label {
+			t.Errorf("got %s label %s, want %s", subnet, got, label)
+		}
+	}
+
+	// Path to the Internet: subnet -> NAT Gateway -> Internet Gateway -> Internet
+	public, private := utils.NodeID("aws_subnet.public"), utils.NodeID("aws_subnet.private")
+	nat, igw := utils.NodeID("aws_nat_gateway.nat"), utils.NodeID("aws_internet_gateway.igw")
+	for _, edge := range [][2]string{{public, igw}, {private, nat}, {nat, igw}, {igw, "Internet"}} {
+		if !hasEdge(graph, edge[0], edge[1]) {
+			t.Errorf("no route %s -> %s", edge[0], edge[1])
+		}
+	}
+
+	// The egress traffic to 0.0.0.0/0 of the private subnet goes through the NAT Gateway
+	web, app := utils.NodeID("aws_instance.web"), utils.NodeID("aws_instance.app")
+	if !hasEdge(graph, web, "Internet") || !hasEdge(graph, app, nat) || hasEdge(graph, app, "Internet") {
+		t.Errorf("egress to 0.0.0.0/0 not drawn to the gateways")
+	}
+}
+
+func TestLoadBalancers(t *testing.T) {
+	graph := testGraph(t, "lb")
+	web := utils.NodeID("aws_instance.web")
+	for _, subnet := range []string{"aws_subnet.a", "aws_subnet.b"} {
+		// The LB has a node in each of its subnets
+		lb := utils.NodeID("aws_lb.front") + "__" + utils.NodeID(subnet)
+		if !graph.Relations.ParentToChildren["cluster_"+utils.NodeID(subnet)][lb] {
+			t.Errorf("no LB node in %s", subnet)
+		}
+		if label := edgeLabel(graph, "Internet", lb); label != `"tcp/443"` {
+			t.Errorf("got label %s from the Internet to the LB (SG rule)", label)
+		}
+		// Listener to the targets of the target group
+		if label := edgeLabel(graph, lb, web); label != `"https/443 → http/8080"` {
+			t.Errorf("got label %s from the LB to the target", label)
+		}
+	}
+
+	// The listeners of Internet-facing LBs without SG are reachable from the Internet
+	if label := edgeLabel(graph, "Internet", utils.NodeID("aws_lb.nlb")); label != `"tcp/22"` {
+		t.Errorf("got label %s from the Internet to the NLB", label)
+	}
+}
+
+func TestAutoscalingGroups(t *testing.T) {
+	graph := testGraph(t, "asg")
+	lb := utils.NodeID("aws_lb.front")
+	for _, subnet := range []string{"aws_subnet.a", "aws_subnet.b"} {
+		// The ASG has a node in each of its subnets, with the SGs of its launch template
+		asg := utils.NodeID("aws_autoscaling_group.web") + "__" + utils.NodeID(subnet)
+		if !graph.Relations.ParentToChildren["cluster_"+utils.NodeID(subnet)][asg] {
+			t.Errorf("no ASG node in %s", subnet)
+		}
+		if label := edgeLabel(graph, "Internet", asg); label != `"tcp/80"` {
+			t.Errorf("got label %s from the Internet to the ASG", label)
+		}
+		// Target group of the ASG
+		if !hasEdge(graph, lb, asg) {
+			t.Errorf("no edge from the LB to the ASG in %s", subnet)
+		}
+	}
+
+	// Launch configuration without SG
+	batch := utils.NodeID("aws_autoscaling_group.batch")
+	if !hasNode(graph, "aws_autoscaling_group.batch", "cluster_"+utils.NodeID("aws_subnet.b")) {
+		t.Error("no ASG node in aws_subnet.b")
+	}
+	if !hasEdge(graph, `"sg-default"`, batch) {
+		t.Error("the ASG without SG is not linked to the default SG")
+	}
+	if got := graph.Nodes.Lookup[batch].Attrs[gographviz.Label]; got != "\"batch\n(ASG 0-1)\"" {
+		t.Errorf("got label %s for the ASG", got)
+	}
+}
+
+func TestDBSubnetGroups(t *testing.T) {
+	graph := testGraph(t, "db")
+	for _, instance := range []string{"aws_db_instance.main", "aws_db_instance.replica"} {
+		for _, subnet := range []string{"aws_subnet.a", "aws_subnet.b"} {
+			// The DB instance has a node in each subnet of its DB subnet group
+			id := utils.NodeID(instance) + "__" + utils.NodeID(subnet)
+			if !graph.Relations.ParentToChildren["cluster_"+utils.NodeID(subnet)][id] {
+				t.Errorf("no node for %s in %s", instance, subnet)
+			}
+		}
+	}
+
+	// The SG rules are drawn for each node of the DB instance
+	for _, subnet := range []string{"aws_subnet.a", "aws_subnet.b"} {
+		if label := edgeLabel(graph, utils.NodeID("aws_vpc.main"), utils.NodeID("aws_db_instance.main")+"__"+utils.NodeID(subnet)); label != `"tcp/5432"` {
+			t.Errorf("got label %s from the VPC to the DB instance in %s", label, subnet)
+		}
+	}
+
+	// The DB instances with an unknown DB subnet group are drawn outside of any VPC
+	if !hasNode(graph, "aws_db_instance.other", "G") {
+		t.Error("the DB instance with an unknown DB subnet group is not drawn")
+	}
+}
+
+func TestDataStores(t *testing.T) {
+	graph := testGraph(t, "datastores")
+	subnet := "cluster_" + utils.NodeID("aws_subnet.a")
+
+	// Aurora clusters are drawn with their instances, and are public if one of their instances is
+	aurora := graph.Nodes.Lookup[utils.NodeID("aws_rds_cluster.aurora")]
+	if aurora == nil || !hasNode(graph, "aws_rds_cluster.aurora", subnet) {
+		t.Fatal("no Aurora cluster node in its DB subnet group")
+	}
+	if got := aurora.Attrs[gographviz.Label]; got != "\"aurora\n(2 instances)\"" {
+		t.Errorf("got label %s for the Aurora cluster", got)
+	}
+	if got := aurora.Attrs[gographviz.FontColor]; got != "red" {
+		t.Errorf("got font color %s for the public Aurora cluster", got)
+	}
+	if graph.Nodes.Lookup[utils.NodeID("aws_rds_cluster_instance.aurora[0]")] != nil {
+		t.Error("the Aurora cluster instances are drawn")
+	}
+
+	// ElastiCache clusters of a replication group are drawn with their group
+	if !hasNode(graph, "aws_elasticache_replication_group.redis", subnet) {
+		t.Error("no ElastiCache replication group node in its subnet group")
+	}
+	if graph.Nodes.Lookup[utils.NodeID("aws_elasticache_cluster.replica")] != nil {
+		t.Error("the ElastiCache cluster of a replication group is drawn")
+	}
+	for _, address := range []string{"aws_rds_cluster.aurora", "aws_elasticache_replication_group.redis"} {
+		if label := edgeLabel(graph, utils.NodeID("aws_vpc.main"), utils.NodeID(address)); label != `"tcp/6379"` {
+			t.Errorf("got label %s from the VPC to %s", label, address)
+		}
+	}
+
+	// OpenSearch domains without VPC options have a public endpoint
+	search := graph.Nodes.Lookup[utils.NodeID("aws_opensearch_domain.search")]
+	if search == nil || search.Attrs[gographviz.FontColor] != "red" {
+		t.Error("the OpenSearch domain without VPC options is not drawn as public")
+	}
+}
+
+func TestLambdaTriggers(t *testing.T) {
+	graph := testGraph(t, "lambda")
+	api := utils.NodeID("aws_lambda_function.api")
+	worker := utils.NodeID("aws_lambda_function.worker")
+
+	// Lambda functions attached to a VPC are drawn in their subnets
+	if !hasNode(graph, "aws_lambda_function.api", "cluster_"+utils.NodeID("aws_subnet.a")) {
+		t.Error("the Lambda function attached to a VPC is not in its subnet")
+	}
+	if !hasNode(graph, "aws_lambda_function.worker", "G") {
+		t.Error("the Lambda function not attached to a VPC is not drawn")
+	}
+
+	// API Gateways are public endpoints
+	gateway := utils.NodeID("aws_apigatewayv2_api.http")
+	if label := edgeLabel(graph, "Internet", gateway); label != `"tcp/443"` {
+		t.Errorf("got label %s from the Internet to the API Gateway", label)
+	}
+
+	triggers := []struct {
+		src	string
+		dst	string
+		label	string
+	}{
+		{gateway, api, ""},
+		{utils.NodeID("aws_s3_bucket.uploads"), worker, `"s3:ObjectCreated:*"`},
+		// Event source not drawn by tfviz (generic node)
+		{utils.NodeID("arn:aws:sqs:eu-west-1:123456789012:jobs"), worker, ""},
+		// Service principal of a Lambda permission
+		{utils.NodeID("events.amazonaws.com"), worker, ""},
+	}
+	for _, trigger := range triggers {
+		edges := graph.Edges.SrcToDsts[trigger.src][trigger.dst]
+		if len(edges) == 0 {
+			t.Errorf("no trigger edge from %s to %s", trigger.src, trigger.dst)
+			continue
+		}
+		if got := edges[0].Attrs[gographviz.Label]; got != trigger.label {
+			t.Errorf("got label %s from %s to %s, want %s", got, trigger.src, trigger.dst, trigger.label)
+		}
+		if got := edges[0].Attrs[gographviz.Style]; got != "bold" {
+			t.Errorf("got style %s from %s to %s", got, trigger.src, trigger.dst)
+		}
+	}
+}
+
+func TestContainers(t *testing.T) {
+	graph := testGraph(t, "containers")
+
+	// ECS services are drawn in the subnets of their network configuration with their containers and ports
+	service := utils.NodeID("aws_ecs_service.app")
+	if !hasNode(graph, "aws_ecs_service.app", "cluster_"+utils.NodeID("aws_subnet.a")) {
+		t.Fatal("no ECS service node in its subnet")
+	}
+	if got := graph.Nodes.Lookup[service].Attrs[gographviz.Label]; got != "\"app\n(ECS main)\nweb: tcp/8080\nsidecar\"" {
+		t.Errorf("got label %s for the ECS service", got)
+	}
+	if label := edgeLabel(graph, utils.NodeID("aws_vpc.main"), service); label != `"tcp/8080"` {
+		t.Errorf("got label %s from the VPC to the ECS service", label)
+	}
+
+	// EKS clusters with a public API server endpoint are highlighted in red
+	cluster := utils.NodeID("aws_eks_cluster.k8s")
+	if !hasNode(graph, "aws_eks_cluster.k8s", "cluster_"+utils.NodeID("aws_subnet.b")) {
+		t.Fatal("no EKS cluster node in its subnet")
+	}
+	if got := graph.Nodes.Lookup[cluster].Attrs[gographviz.FontColor]; got != "red" {
+		t.Errorf("got font color %s for the EKS cluster with a public endpoint", got)
+	}
+
+	// The control plane and the node groups can communicate, and remote_access allows SSH from its SGs
+	workers := utils.NodeID("aws_eks_node_group.workers")
+	if label := edgeLabel(graph, cluster, workers); label != `"all"` {
+		t.Errorf("got label %s from the EKS cluster to the node group", label)
+	}
+	if label := edgeLabel(graph, workers, cluster); label != `"all"` {
+		t.Errorf("got label %s from the node group to the EKS cluster", label)
+	}
+	if label := edgeLabel(graph, utils.NodeID("aws_instance.bastion"), workers); label != `"tcp/22"` {
+		t.Errorf("got label %s from the remote access SG to the node group", label)
+	}
+}
+
+func TestNetworkConnections(t *testing.T) {
+	graph := testGraph(t, "peering")
+	main := utils.NodeID("aws_vpc.main")
+
+	connections := []struct {
+		src	string
+		dst	string
+		label	string
+	}{
+		{main, utils.NodeID("aws_vpc.shared"), `"peering: shared"`},
+		// Peer VPC not defined in TF
+		{main, utils.NodeID("aws_vpc_peering_connection.partner.vpc-0a1b2c3d"), `"peering: partner"`},
+		{utils.NodeID("aws_customer_gateway.office"), utils.NodeID("aws_ec2_transit_gateway.tgw"), `"VPN: office"`},
+	}
+	for _, connection := range connections {
+		edges := graph.Edges.SrcToDsts[connection.src][connection.dst]
+		if len(edges) == 0 {
+			t.Errorf("no connection from %s to %s", connection.src, connection.dst)
+			continue
+		}
+		if got := edges[0].Attrs[gographviz.Label]; got != connection.label {
+			t.Errorf("got label %s from %s to %s, want %s", got, connection.src, connection.dst, connection.label)
+		}
+		if got := edges[0].Attrs[gographviz.Style]; got != "dashed" {
+			t.Errorf("got style %s from %s to %s", got, connection.src, connection.dst)
+		}
+	}
+
+	// SG rules allowing CIDRs outside of the VPCs follow the routes of the subnet: through the peering connection
+	// to the peer VPC, and through the Transit Gateway to the Customer Gateway of the VPN connection
+	app := utils.NodeID("aws_instance.app")
+	if label := edgeLabel(graph, app, utils.NodeID("aws_vpc.shared")); label != `"tcp/5432"` {
+		t.Errorf("got label %s from the instance to the peer VPC", label)
+	}
+	if label := edgeLabel(graph, utils.NodeID("aws_customer_gateway.office"), app); label != `"tcp/22"` {
+		t.Errorf("got label %s from the Customer Gateway to the instance", label)
+	}
+	if hasEdge(graph, "Internet", app) || hasEdge(graph, app, "Internet") {
+		t.Error("the routed CIDRs are drawn from / to the Internet")
+	}
+}
+
+func TestVpcEndpoints(t *testing.T) {
+	graph := testGraph(t, "vpce")
+	s3 := utils.NodeID("aws_vpc_endpoint.s3")
+	sqs := utils.NodeID("aws_vpc_endpoint.sqs")
+
+	// Gateway endpoints are drawn in their VPC, Interface endpoints in their subnets
+	if !hasNode(graph, "aws_vpc_endpoint.s3", "cluster_"+utils.NodeID("aws_vpc.main")) {
+		t.Error("the Gateway endpoint is not in its VPC")
+	}
+	if !hasNode(graph, "aws_vpc_endpoint.sqs", "cluster_"+utils.NodeID("aws_subnet.app")) {
+		t.Error("the Interface endpoint is not in its subnet")
+	}
+
+	endpointEdges := []struct {
+		src	string
+		dst	string
+		label	string
+	}{
+		// Subnets to the Gateway endpoints of their route table
+		{utils.NodeID("aws_subnet.app"), s3, `"s3"`},
+		// Endpoints to the services they reach
+		{s3, utils.NodeID("aws_s3_bucket.data"), ""},
+		{sqs, utils.NodeID("sqs.amazonaws.com"), ""},
+	}
+	for _, edge := range endpointEdges {
+		edges := graph.Edges.SrcToDsts[edge.src][edge.dst]
+		if len(edges) == 0 {
+			t.Errorf("no edge from %s to %s", edge.src, edge.dst)
+			continue
+		}
+		if got := edges[0].Attrs[gographviz.Label]; got != edge.label {
+			t.Errorf("got label %s from %s to %s, want %s", got, edge.src, edge.dst, edge.label)
+		}
+		if got := edges[0].Attrs[gographviz.Color]; got != "blue" {
+			t.Errorf("got color %s from %s to %s", got, edge.src, edge.dst)
+		}
+	}
+
+	// Egress rules allowing 0.0.0.0/0 are drawn to the Gateway endpoints, and the SGs associated to the Interface
+	// endpoints are applied
+	if label := edgeLabel(graph, utils.NodeID("aws_instance.app"), s3); label != `"tcp/443"` {
+		t.Errorf("got label %s from the instance to the Gateway endpoint", label)
+	}
+	if label := edgeLabel(graph, utils.NodeID("aws_vpc.main"), sqs); label != `"tcp/443"` {
+		t.Errorf("got label %s from the VPC to the Interface endpoint", label)
+	}
+}
@@ -0,0 +1,245 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// ecsServiceSubnetsAndSGs returns the subnets and SGs of an ECS service (awsvpc network mode only)
+func ecsServiceSubnetsAndSGs(service ECSService) (subnets []string, SGs []string) {
+	for _, networkConfiguration := range service.NetworkConfiguration {
+		subnets = append(subnets, networkConfiguration.Subnets...)
+		if networkConfiguration.SecurityGroups != nil {
+			SGs = append(SGs, *networkConfiguration.SecurityGroups...)
+		}
+	}
+	return subnets, SGs
+}
+
+// ecsClusterName returns the name of the ECS cluster of a service, referenced by its address, its name or its ARN
+func (a *Data) ecsClusterName(service ECSService) string {
+	if service.Cluster == nil || *service.Cluster == "" {
+		return "default"
+	}
+	if cluster, found := a.ECSCluster[referencedResource(*service.Cluster)]; found {
+		return cluster.Name
+	}
+	// arn:aws:ecs:<region>:<account>:cluster/<name>
+	parts := strings.Split(*service.Cluster, "/")
+	return parts[len(parts)-1]
+}
+
+// ecsContainers returns the containers of the task definition of a service, referenced by its address, its ARN
+// or its family[:revision]
+func (a *Data) ecsContainers(service ECSService) []ECSContainerDefinition {
+	if service.TaskDefinition == nil {
+		return nil
+	}
+	taskDefinition, found := a.ECSTaskDefinition[referencedResource(*service.TaskDefinition)]
+	if !found {
+		family := strings.SplitN(*service.TaskDefinition, ":", 2)[0]
+		for _, t := range a.ECSTaskDefinition {
+			if t.Family == family {
+				taskDefinition, found = t, true
+			}
+		}
+	}
+	if !found || taskDefinition.ContainerDefinitions == "" {
+		return nil
+	}
+	var containers []ECSContainerDefinition
+	err := json.Unmarshal([]byte(taskDefinition.ContainerDefinitions), &containers)
+	if err != nil {
+		a.log.Error(fmt.Errorf("%s: invalid container_definitions: %s", taskDefinition.Family, err))
+		return nil
+	}
+	return containers
+}
+
+// ecsServiceLabel formats the label of an ECS service node: its name, its cluster and its containers / ports
+func (a *Data) ecsServiceLabel(service ECSService) string {
+	label := strings.Join(utils.ChunkString(service.Name, 8), "\n")
+	label += "\n(ECS " + a.ecsClusterName(service) + ")"
+	for _, container := range a.ecsContainers(service) {
+		var ports []string
+		for _, portMapping := range container.PortMappings {
+			protocol := portMapping.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			ports = append(ports, fmt.Sprintf("%s/%d", protocol, portMapping.ContainerPort))
+		}
+		label += "\n" + container.Name
+		if len(ports) > 0 {
+			label += ": " + strings.Join(ports, ", ")
+		}
+	}
+	return label
+}
+
+// ecsServiceTargets returns the ECS services registered in a target group or a Classic LB, with their container
+// port
+func (a *Data) ecsServiceTargets(lbAddress string) []lbTarget {
+	var targets []lbTarget
+	for serviceAddress, service := range a.ECSService {
+		for _, lb := range service.LoadBalancer {
+			port := lb.ContainerPort
+			if lb.TargetGroupArn != nil && referencedResource(*lb.TargetGroupArn) == lbAddress {
+				targets = append(targets, lbTarget{serviceAddress, &port})
+			}
+			if lb.ELBName != nil && (referencedResource(*lb.ELBName) == lbAddress || stringValue(a.ELB[lbAddress].Name) == *lb.ELBName) {
+				targets = append(targets, lbTarget{serviceAddress, &port})
+			}
+		}
+	}
+	return targets
+}
+
+// eksEndpointPublic returns true if the API server endpoint of an EKS cluster is public (default)
+func eksEndpointPublic(cluster EKSCluster) bool {
+	for _, vpcConfig := range cluster.VpcConfig {
+		if vpcConfig.EndpointPublicAccess != nil && !*vpcConfig.EndpointPublicAccess {
+			return false
+		}
+	}
+	return true
+}
+
+// eksClusterSubnetsAndSGs returns the subnets and SGs of an EKS cluster
+func eksClusterSubnetsAndSGs(cluster EKSCluster) (subnets []string, SGs []string) {
+	for _, vpcConfig := range cluster.VpcConfig {
+		subnets = append(subnets, vpcConfig.SubnetIDs...)
+		if vpcConfig.SecurityGroupIDs != nil {
+			SGs = append(SGs, *vpcConfig.SecurityGroupIDs...)
+		}
+	}
+	return subnets, SGs
+}
+
+// eksClusterAddress returns the address of the EKS cluster of a node group ("" if unknown)
+func (a *Data) eksClusterAddress(nodeGroup EKSNodeGroup) string {
+	if _, found := a.EKSCluster[referencedResource(nodeGroup.ClusterName)]; found {
+		return referencedResource(nodeGroup.ClusterName)
+	}
+	for clusterAddress, cluster := range a.EKSCluster {
+		if cluster.Name == nodeGroup.ClusterName {
+			return clusterAddress
+		}
+	}
+	return ""
+}
+
+// linkNodeGroups links the EKS node groups to the SGs of their launch templates.
+// It must be called once all resources are parsed
+func (a *Data) linkNodeGroups() {
+	for nodeGroupAddress, nodeGroup := range a.EKSNodeGroup {
+		SGs := a.launchTemplateSecurityGroups(launchTemplateAddresses(nodeGroup.LaunchTemplate))
+		a.linkSecurityGroups(nodeGroupAddress, &SGs)
+	}
+}
+
+// createContainers creates the nodes of the ECS services and of the EKS clusters / node groups (one per subnet)
+func (a *Data) createContainers(graph *model.Graph) (error) {
+	for serviceAddress, service := range a.ECSService {
+		modulePath, _, _ := utils.SplitAddress(serviceAddress)
+		subnets, _ := ecsServiceSubnetsAndSGs(service)
+		a.log.Verbosef("Create ECS service %s in %d subnet(s)", serviceAddress, len(subnets))
+		err := a.createSubnetNodes(graph, iconNode(serviceAddress, a.ecsServiceLabel(service), "ecs.png"), subnets, a.moduleGroup(modulePath))
+		if err != nil {
+			return err
+		}
+	}
+
+	// The API server endpoint of EKS clusters is public by default, highlighting it in red
+	for clusterAddress, cluster := range a.EKSCluster {
+		subnets, _ := eksClusterSubnetsAndSGs(cluster)
+		a.log.Verbosef("Create EKS cluster %s in %d subnet(s)", clusterAddress, len(subnets))
+		detail := "EKS"
+		if eksEndpointPublic(cluster) {
+			detail = "EKS, public endpoint"
+		}
+		err := a.createDataNode(graph, clusterAddress, dataLabel(clusterAddress, &cluster.Name, detail), subnets, "eks.png", eksEndpointPublic(cluster))
+		if err != nil {
+			return err
+		}
+	}
+
+	for nodeGroupAddress, nodeGroup := range a.EKSNodeGroup {
+		modulePath, _, _ := utils.SplitAddress(nodeGroupAddress)
+		a.log.Verbosef("Create EKS node group %s in %d subnet(s)", nodeGroupAddress, len(nodeGroup.SubnetIDs))
+		detail := "node group"
+		for _, scalingConfig := range nodeGroup.ScalingConfig {
+			detail = fmt.Sprintf("node group %d-%d", scalingConfig.MinSize, scalingConfig.MaxSize)
+		}
+		err := a.createSubnetNodes(graph, iconNode(nodeGroupAddress, dataLabel(nodeGroupAddress, nodeGroup.NodeGroupName, detail), "ec2.png"), nodeGroup.SubnetIDs, a.moduleGroup(modulePath))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createContainerEdges creates the edges of the ECS services and of the EKS clusters / node groups
+func (a *Data) createContainerEdges(graph *model.Graph) (error) {
+	for serviceAddress, service := range a.ECSService {
+		if len(service.NetworkConfiguration) == 0 {
+			// bridge / host network mode: the tasks use the SGs of their container instances
+			continue
+		}
+		_, SGs := ecsServiceSubnetsAndSGs(service)
+		if len(SGs) == 0 {
+			// The tasks launched without SG inherit from the default SG
+			for _, nodeName := range a.graphNodes(serviceAddress) {
+				err := a.linkDefaultSecurityGroup(graph, nodeName)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		a.parseSGRules(serviceAddress, SGs, graph)
+	}
+
+	for clusterAddress, cluster := range a.EKSCluster {
+		_, SGs := eksClusterSubnetsAndSGs(cluster)
+		a.parseSGRules(clusterAddress, SGs, graph)
+	}
+
+	for nodeGroupAddress, nodeGroup := range a.EKSNodeGroup {
+		SGs := a.launchTemplateSecurityGroups(launchTemplateAddresses(nodeGroup.LaunchTemplate))
+		a.parseSGRules(nodeGroupAddress, SGs, graph)
+
+		clusterAddress := a.eksClusterAddress(nodeGroup)
+		for _, nodeName := range a.graphNodes(nodeGroupAddress) {
+			// The cluster SG created by EKS (not defined in TF) allows all the traffic between the control plane
+			// and the managed nodes
+			if clusterAddress != "" {
+				allTraffic := SGRule{Protocol: "-1"}
+				for _, clusterNode := range a.graphNodes(clusterAddress) {
+					a.addSGEdge(clusterNode, nodeName, ruleFlow(ingressRule, clusterAddress, allTraffic), a.blockedByNACL(ingressRule, nodeName, allTraffic, "", a.nodeSubnets[clusterNode]))
+					a.addSGEdge(nodeName, clusterNode, ruleFlow(egressRule, clusterAddress, allTraffic), a.blockedByNACL(egressRule, nodeName, allTraffic, "", a.nodeSubnets[clusterNode]))
+				}
+			}
+
+			// SSH access from the SGs of remote_access
+			ssh := SGRule{Protocol: "tcp", FromPort: 22, ToPort: 22}
+			for _, remoteAccess := range nodeGroup.RemoteAccess {
+				if remoteAccess.SourceSecurityGroupIDs == nil {
+					continue
+				}
+				for _, sg := range *remoteAccess.SourceSecurityGroupIDs {
+					for _, peer := range a.SecurityGroupNodeLinks[sg] {
+						for _, peerNode := range a.graphNodes(peer) {
+							a.addSGEdge(peerNode, nodeName, ruleFlow(ingressRule, nodeGroupAddress, ssh), a.blockedByNACL(ingressRule, nodeName, ssh, "", a.nodeSubnets[peerNode]))
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
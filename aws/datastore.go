@@ -0,0 +1,230 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// findSubnetGroup returns the subnet group (DB, ElastiCache or Redshift) referenced by its address or its name
+func findSubnetGroup(subnetGroups map[string]DBSubnetGroup, name string) (DBSubnetGroup, bool) {
+	if subnetGroup, found := subnetGroups[referencedResource(name)]; found {
+		return subnetGroup, true
+	}
+	for _, subnetGroup := range subnetGroups {
+		if subnetGroup.Name != nil && *subnetGroup.Name == name {
+			return subnetGroup, true
+		}
+	}
+	return DBSubnetGroup{}, false
+}
+
+// subnetGroupSubnets returns the subnets of the subnet group of a resource (nil if it has no subnet group or if
+// the subnet group is unknown)
+func (a *Data) subnetGroupSubnets(address string, subnetGroups map[string]DBSubnetGroup, name *string) []string {
+	if name == nil || *name == "" {
+		return nil
+	}
+	subnetGroup, found := findSubnetGroup(subnetGroups, *name)
+	if !found {
+		a.log.Error(fmt.Errorf("%s: unknown subnet group %s", address, *name))
+		return nil
+	}
+	return subnetGroup.SubnetIDs
+}
+
+// stringValue returns the value of an optional string ("" if not set)
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// createDataNode creates the nodes of a data store (one per subnet). Data stores without known subnet are created
+// in the default VPC if there is no VPC defined in the TF module, or in their module otherwise.
+// Publicly accessible data stores are public nodes
+func (a *Data) createDataNode(graph *model.Graph, address string, label string, subnets []string, icon string, public bool) (error) {
+	modulePath, _, _ := utils.SplitAddress(address)
+	fallbackGroup := a.moduleGroup(modulePath)
+	if len(a.Vpc) == 0 {
+		fallbackGroup = "aws_vpc_default"
+	}
+
+	node := iconNode(address, label, icon)
+	node.Public = public
+	return a.createSubnetNodes(graph, node, subnets, fallbackGroup)
+}
+
+// dataLabel formats the label of a data store node: its name (or identifier) and a detail (e.g. its engine)
+func dataLabel(address string, identifier *string, detail string) string {
+	_, _, name := utils.SplitAddress(address)
+	if identifier != nil && *identifier != "" {
+		name = *identifier
+	}
+	label := strings.Join(utils.ChunkString(name, 8), "\n")
+	if detail != "" {
+		label += "\n(" + detail + ")"
+	}
+	return label
+}
+
+// rdsClusterAddress returns the address of the RDS cluster of a cluster instance ("" if unknown)
+func (a *Data) rdsClusterAddress(instance RDSClusterInstance) string {
+	if _, found := a.RDSCluster[referencedResource(instance.ClusterIdentifier)]; found {
+		return referencedResource(instance.ClusterIdentifier)
+	}
+	for clusterAddress, cluster := range a.RDSCluster {
+		if cluster.ClusterIdentifier != nil && *cluster.ClusterIdentifier == instance.ClusterIdentifier {
+			return clusterAddress
+		}
+	}
+	return ""
+}
+
+// replicationGroupAddress returns the address of the replication group of an ElastiCache cluster ("" if none)
+func (a *Data) replicationGroupAddress(cluster ElastiCacheCluster) string {
+	if cluster.ReplicationGroupID == nil || *cluster.ReplicationGroupID == "" {
+		return ""
+	}
+	if _, found := a.ElastiCacheReplicationGroup[referencedResource(*cluster.ReplicationGroupID)]; found {
+		return referencedResource(*cluster.ReplicationGroupID)
+	}
+	for groupAddress, group := range a.ElastiCacheReplicationGroup {
+		if group.ReplicationGroupID != nil && *group.ReplicationGroupID == *cluster.ReplicationGroupID {
+			return groupAddress
+		}
+	}
+	return ""
+}
+
+// openSearchVpcOptions returns the subnets and SGs of an OpenSearch domain (none if its endpoint is public)
+func openSearchVpcOptions(domain OpenSearchDomain) (subnets []string, SGs []string) {
+	for _, vpcOptions := range domain.VpcOptions {
+		if vpcOptions.SubnetIDs != nil {
+			subnets = append(subnets, *vpcOptions.SubnetIDs...)
+		}
+		if vpcOptions.SecurityGroupIDs != nil {
+			SGs = append(SGs, *vpcOptions.SecurityGroupIDs...)
+		}
+	}
+	return subnets, SGs
+}
+
+// createDataStores creates the nodes of the Aurora clusters, ElastiCache clusters, Redshift clusters and
+// OpenSearch domains
+func (a *Data) createDataStores(graph *model.Graph) (error) {
+	// Aurora clusters are drawn with their instances: the cluster is public if one of its instances is
+	clusterInstances := make(map[string]int)
+	publicClusters := make(map[string]bool)
+	for instanceAddress, instance := range a.RDSClusterInstance {
+		public := instance.PubliclyAccessible != nil && *instance.PubliclyAccessible
+		clusterAddress := a.rdsClusterAddress(instance)
+		if clusterAddress != "" {
+			clusterInstances[clusterAddress]++
+			publicClusters[clusterAddress] = publicClusters[clusterAddress] || public
+			continue
+		}
+
+		// Instance of an unknown cluster
+		subnets := a.subnetGroupSubnets(instanceAddress, a.DBSubnetGroup, instance.DBSubnetGroupName)
+		err := a.createDataNode(graph, instanceAddress, dataLabel(instanceAddress, instance.Identifier, stringValue(instance.InstanceClass)), subnets, "aurora.png", public)
+		if err != nil {
+			return err
+		}
+	}
+	for clusterAddress, cluster := range a.RDSCluster {
+		subnets := a.subnetGroupSubnets(clusterAddress, a.DBSubnetGroup, cluster.DBSubnetGroupName)
+		if cluster.DBSubnetGroupName == nil {
+			// The cluster instances can define the DB subnet group instead of the cluster
+			for instanceAddress, instance := range a.RDSClusterInstance {
+				if a.rdsClusterAddress(instance) == clusterAddress {
+					subnets = append(subnets, a.subnetGroupSubnets(instanceAddress, a.DBSubnetGroup, instance.DBSubnetGroupName)...)
+				}
+			}
+		}
+		detail := fmt.Sprintf("%d instances", clusterInstances[clusterAddress])
+		if clusterInstances[clusterAddress] == 1 {
+			detail = "1 instance"
+		}
+		a.log.Verbosef("Create RDS cluster %s in %d subnet(s)", clusterAddress, len(subnets))
+		err := a.createDataNode(graph, clusterAddress, dataLabel(clusterAddress, cluster.ClusterIdentifier, detail), subnets, "aurora.png", publicClusters[clusterAddress])
+		if err != nil {
+			return err
+		}
+	}
+
+	// ElastiCache clusters belonging to a replication group are drawn with their group
+	for clusterAddress, cluster := range a.ElastiCacheCluster {
+		if a.replicationGroupAddress(cluster) != "" {
+			continue
+		}
+		subnets := a.subnetGroupSubnets(clusterAddress, a.ElastiCacheSubnetGroup, cluster.SubnetGroupName)
+		a.log.Verbosef("Create ElastiCache cluster %s in %d subnet(s)", clusterAddress, len(subnets))
+		err := a.createDataNode(graph, clusterAddress, dataLabel(clusterAddress, cluster.ClusterID, stringValue(cluster.Engine)), subnets, "elasticache.png", false)
+		if err != nil {
+			return err
+		}
+	}
+	for groupAddress, group := range a.ElastiCacheReplicationGroup {
+		subnets := a.subnetGroupSubnets(groupAddress, a.ElastiCacheSubnetGroup, group.SubnetGroupName)
+		a.log.Verbosef("Create ElastiCache replication group %s in %d subnet(s)", groupAddress, len(subnets))
+		err := a.createDataNode(graph, groupAddress, dataLabel(groupAddress, group.ReplicationGroupID, stringValue(group.Engine)), subnets, "elasticache.png", false)
+		if err != nil {
+			return err
+		}
+	}
+
+	for clusterAddress, cluster := range a.RedshiftCluster {
+		subnets := a.subnetGroupSubnets(clusterAddress, a.RedshiftSubnetGroup, cluster.ClusterSubnetGroupName)
+		a.log.Verbosef("Create Redshift cluster %s in %d subnet(s)", clusterAddress, len(subnets))
+		public := cluster.PubliclyAccessible != nil && *cluster.PubliclyAccessible
+		err := a.createDataNode(graph, clusterAddress, dataLabel(clusterAddress, cluster.ClusterIdentifier, stringValue(cluster.NodeType)), subnets, "redshift.png", public)
+		if err != nil {
+			return err
+		}
+	}
+
+	// OpenSearch domains without VPC options have a public endpoint
+	for domainAddress, domain := range a.OpenSearchDomain {
+		subnets, _ := openSearchVpcOptions(domain)
+		a.log.Verbosef("Create OpenSearch domain %s in %d subnet(s)", domainAddress, len(subnets))
+		err := a.createDataNode(graph, domainAddress, dataLabel(domainAddress, &domain.DomainName, ""), subnets, "opensearch.png", len(domain.VpcOptions) == 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createDataStoreEdges parses the SG rules of the Aurora clusters, ElastiCache clusters, Redshift clusters and
+// OpenSearch domains, like for DB instances
+func (a *Data) createDataStoreEdges(graph *model.Graph) {
+	for clusterAddress, cluster := range a.RDSCluster {
+		if cluster.VpcSecurityGroupIDs != nil {
+			a.parseSGRules(clusterAddress, *cluster.VpcSecurityGroupIDs, graph)
+		}
+	}
+	for clusterAddress, cluster := range a.ElastiCacheCluster {
+		if cluster.SecurityGroupIDs != nil && a.replicationGroupAddress(cluster) == "" {
+			a.parseSGRules(clusterAddress, *cluster.SecurityGroupIDs, graph)
+		}
+	}
+	for groupAddress, group := range a.ElastiCacheReplicationGroup {
+		if group.SecurityGroupIDs != nil {
+			a.parseSGRules(groupAddress, *group.SecurityGroupIDs, graph)
+		}
+	}
+	for clusterAddress, cluster := range a.RedshiftCluster {
+		if cluster.VpcSecurityGroupIDs != nil {
+			a.parseSGRules(clusterAddress, *cluster.VpcSecurityGroupIDs, graph)
+		}
+	}
+	for domainAddress, domain := range a.OpenSearchDomain {
+		_, SGs := openSearchVpcOptions(domain)
+		a.parseSGRules(domainAddress, SGs, graph)
+	}
+}
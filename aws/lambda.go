@@ -0,0 +1,232 @@
+package aws
+
+import (
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// lambdaSubnetsAndSGs returns the subnets and SGs of a Lambda function (none if it is not attached to a VPC)
+func lambdaSubnetsAndSGs(function LambdaFunction) (subnets []string, SGs []string) {
+	for _, vpcConfig := range function.VpcConfig {
+		subnets = append(subnets, vpcConfig.SubnetIDs...)
+		SGs = append(SGs, vpcConfig.SecurityGroupIDs...)
+	}
+	return subnets, SGs
+}
+
+// lambdaFunctionAddress returns the address of a Lambda function referenced by its address, its name or its ARN
+// ("" if unknown)
+func (a *Data) lambdaFunctionAddress(reference string) string {
+	address := referencedResource(reference)
+	if _, found := a.LambdaFunction[address]; found {
+		return address
+	}
+
+	// arn:aws:lambda:<region>:<account>:function:<name>[:<qualifier>]
+	name := reference
+	if i := strings.Index(name, ":function:"); i >= 0 {
+		name = strings.SplitN(name[i+len(":function:"):], ":", 2)[0]
+	}
+	for functionAddress, function := range a.LambdaFunction {
+		if function.FunctionName != nil && *function.FunctionName == name {
+			return functionAddress
+		}
+	}
+	return ""
+}
+
+// s3BucketAddress returns the address of a S3 bucket referenced by its address or its name ("" if unknown)
+func (a *Data) s3BucketAddress(reference string) string {
+	if _, found := a.S3[reference]; found {
+		return reference
+	}
+	for bucketAddress, bucket := range a.S3 {
+		if bucket.Bucket != nil && *bucket.Bucket == reference {
+			return bucketAddress
+		}
+	}
+	return ""
+}
+
+// eventSourceNodes returns the nodes of an event source referenced by its address or its ARN. Event sources
+// not drawn on the graph (e.g. SQS queues, DynamoDB streams or literal ARNs) are drawn as generic nodes
+func (a *Data) eventSourceNodes(graph *model.Graph, source string) ([]string, error) {
+	address := source
+	if !strings.HasPrefix(address, "arn:") {
+		// Sub-resources of a TF resource (e.g. <API execution ARN>/*/POST/path) are drawn as their parent resource
+		address = referencedResource(strings.SplitN(source, "/", 2)[0])
+	}
+	if bucketAddress := a.s3BucketAddress(address); bucketAddress != "" {
+		address = bucketAddress
+	}
+	nodes := a.graphNodes(address)
+	if graph.IsNode(nodes[0]) {
+		return nodes, nil
+	}
+
+	modulePath, resourceType, name := utils.SplitAddress(address)
+	switch {
+	case strings.HasPrefix(address, "arn:"):
+		// arn:<partition>:<service>:<region>:<account>:<resource>
+		parts := strings.SplitN(address, ":", 6)
+		modulePath, resourceType, name = "", "", parts[len(parts)-1]
+		if len(parts) > 2 {
+			resourceType = parts[2]
+		}
+	case strings.HasSuffix(address, ".amazonaws.com"):
+		// Service principal (e.g. events.amazonaws.com)
+		modulePath, resourceType, name = "", "", address
+	}
+	label := strings.Join(utils.ChunkString(name, 12), "\n")
+	if resourceType != "" {
+		label += "\n(" + resourceType + ")"
+	}
+	a.log.Verbosef("AddNode: %s to %s // Create event source", utils.NodeID(address), a.moduleGroup(modulePath))
+	err := graph.AddNode(&model.Node{
+		ID:		utils.NodeID(address),
+		Parent:	a.moduleGroup(modulePath),
+		Kind:	model.ServiceNode,
+		Label:	label,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{utils.NodeID(address)}, nil
+}
+
+// addTriggerEdges links an event source to the nodes of the Lambda function it invokes
+func (a *Data) addTriggerEdges(graph *model.Graph, source string, functionReference string, label string) (error) {
+	functionAddress := a.lambdaFunctionAddress(functionReference)
+	if functionAddress == "" {
+		a.log.Verbosef("Unknown Lambda function %s, the trigger from %s is ignored", functionReference, source)
+		return nil
+	}
+	sourceNodes, err := a.eventSourceNodes(graph, source)
+	if err != nil {
+		return err
+	}
+	// The invocations (e.g. S3 events or HTTP methods) are described by the label of the edge
+	var flow *model.Flow
+	if label != "" {
+		flow = &model.Flow{Description: label}
+	}
+	for _, src := range sourceNodes {
+		for _, dst := range a.graphNodes(functionAddress) {
+			a.addSGEdge(src, dst, flow, false).Kind = model.TriggerEdge
+		}
+	}
+	return nil
+}
+
+// apiGatewayLabel formats the label of an API Gateway node: its name and its type
+func apiGatewayLabel(apiAddress string, api APIGateway) string {
+	_, resourceType, _ := utils.SplitAddress(apiAddress)
+	detail := "REST API"
+	if resourceType == "aws_apigatewayv2_api" {
+		detail = "HTTP API"
+		if api.ProtocolType != nil && *api.ProtocolType != "" {
+			detail = strings.ToUpper(*api.ProtocolType) + " API"
+		}
+	}
+	return dataLabel(apiAddress, api.Name, detail)
+}
+
+// createServerless creates the nodes of the Lambda functions (in their subnets if they are attached to a VPC)
+// and of the API Gateways
+func (a *Data) createServerless(graph *model.Graph) (error) {
+	for functionAddress, function := range a.LambdaFunction {
+		modulePath, _, _ := utils.SplitAddress(functionAddress)
+		subnets, _ := lambdaSubnetsAndSGs(function)
+		a.log.Verbosef("Create Lambda function %s in %d subnet(s)", functionAddress, len(subnets))
+		err := a.createSubnetNodes(graph, iconNode(functionAddress, dataLabel(functionAddress, function.FunctionName, stringValue(function.Runtime)), "lambda.png"), subnets, a.moduleGroup(modulePath))
+		if err != nil {
+			return err
+		}
+	}
+
+	for apiAddress, api := range a.APIGateway {
+		modulePath, _, _ := utils.SplitAddress(apiAddress)
+		a.log.Verbosef("AddNode: %s to %s // Create API Gateway", utils.NodeID(apiAddress), a.moduleGroup(modulePath))
+		node := iconNode(apiAddress, apiGatewayLabel(apiAddress, api), "apigateway.png")
+		node.Parent = a.moduleGroup(modulePath)
+		err := graph.AddNode(node)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createLambdaEdges creates the edges of the Lambda functions: SG rules of the functions attached to a VPC, and
+// the triggers invoking them (event source mappings, permissions, S3 notifications and API Gateway integrations)
+func (a *Data) createLambdaEdges(graph *model.Graph) (error) {
+	for functionAddress, function := range a.LambdaFunction {
+		_, SGs := lambdaSubnetsAndSGs(function)
+		a.parseSGRules(functionAddress, SGs, graph)
+	}
+
+	// API Gateways are public endpoints
+	for apiAddress := range a.APIGateway {
+		a.createInternetSGRuleEdge(ingressRule, utils.NodeID(apiAddress), apiAddress, SGRule{
+			Protocol:	"tcp",
+			FromPort:	443,
+			ToPort:		443,
+		})
+	}
+
+	for _, mapping := range a.LambdaEventSourceMapping {
+		if mapping.EventSourceArn == nil || *mapping.EventSourceArn == "" {
+			continue
+		}
+		err := a.addTriggerEdges(graph, *mapping.EventSourceArn, mapping.FunctionName, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, permission := range a.LambdaPermission {
+		source := stringValue(permission.SourceArn)
+		if source == "" {
+			if !strings.HasSuffix(permission.Principal, ".amazonaws.com") {
+				// Accounts and wildcard principals are not drawn
+				continue
+			}
+			// Any resource of the AWS service can invoke the function
+			source = permission.Principal
+		}
+		err := a.addTriggerEdges(graph, source, permission.FunctionName, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, notification := range a.S3BucketNotification {
+		for _, lambdaFunction := range notification.LambdaFunction {
+			if lambdaFunction.LambdaFunctionArn == nil {
+				continue
+			}
+			err := a.addTriggerEdges(graph, notification.Bucket, *lambdaFunction.LambdaFunctionArn, strings.Join(lambdaFunction.Events, "\n"))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, integration := range a.APIGatewayIntegration {
+		api, uri := stringValue(integration.RestAPIID), stringValue(integration.URI)
+		if integration.APIID != nil {
+			api, uri = *integration.APIID, stringValue(integration.IntegrationURI)
+		}
+		if api == "" || uri == "" {
+			continue
+		}
+		err := a.addTriggerEdges(graph, api, uri, stringValue(integration.HTTPMethod))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
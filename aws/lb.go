@@ -0,0 +1,226 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// lbTarget is a target registered in a LB target group
+type lbTarget struct {
+	// Address of the target resource
+	Address					string
+	// The port on which the target receives traffic (if different from the target group port)
+	Port					*int
+}
+
+// endpointLabel formats the protocol and port of a listener / target (e.g. https/443)
+func endpointLabel(protocol *string, port *int) string {
+	var label string
+	if protocol != nil {
+		label = strings.ToLower(*protocol)
+	}
+	if port != nil {
+		if label != "" {
+			label += "/"
+		}
+		label += fmt.Sprintf("%d", *port)
+	}
+	return label
+}
+
+// lbNode returns the node of a LB: its name, and whether it is Internet-facing or internal
+func lbNode(address string, name string, internal *bool) *model.Node {
+	label := strings.Join(utils.ChunkString(name, 8), "\n")
+	public := false
+	if internal != nil && *internal {
+		label += "\n(internal)"
+	} else {
+		// LBs are Internet-facing by default
+		label += "\n(Internet-facing)"
+		public = true
+	}
+	node := iconNode(address, label, "elb.png")
+	node.Public = public
+	return node
+}
+
+func (a *Data) createLB(graph *model.Graph, lbAddress string, lb LB) (error) {
+	// Create LB nodes (one per subnet)
+	modulePath, _, lbName := utils.SplitAddress(lbAddress)
+	if lb.Name != nil && *lb.Name != "" {
+		lbName = *lb.Name
+	}
+	var subnets []string
+	if lb.Subnets != nil {
+		subnets = append(subnets, *lb.Subnets...)
+	}
+	for _, mapping := range lb.SubnetMapping {
+		subnets = append(subnets, mapping.SubnetID)
+	}
+	a.log.Verbosef("Create LB %s in %d subnet(s)", lbAddress, len(subnets))
+
+	// Splitting label if more than 8 chars
+	return a.createSubnetNodes(graph, lbNode(lbAddress, lbName, lb.Internal), subnets, a.moduleGroup(modulePath))
+}
+
+func (a *Data) createELB(graph *model.Graph, elbAddress string, elb ELB) (error) {
+	// Create Classic LB nodes (one per subnet)
+	modulePath, _, elbName := utils.SplitAddress(elbAddress)
+	if elb.Name != nil && *elb.Name != "" {
+		elbName = *elb.Name
+	}
+	var subnets []string
+	if elb.Subnets != nil {
+		subnets = *elb.Subnets
+	}
+	a.log.Verbosef("Create ELB %s in %d subnet(s)", elbAddress, len(subnets))
+
+	// Splitting label if more than 8 chars
+	return a.createSubnetNodes(graph, lbNode(elbAddress, elbName, elb.Internal), subnets, a.moduleGroup(modulePath))
+}
+
+// targetGroupTargets returns the targets registered in a target group
+func (a *Data) targetGroupTargets(targetGroupAddress string) []lbTarget {
+	var targets []lbTarget
+	for _, attachment := range a.LBTargetGroupAttachment {
+		if attachment.TargetGroupArn == targetGroupAddress {
+			targets = append(targets, lbTarget{attachment.TargetID, attachment.Port})
+		}
+	}
+	for _, asgAddress := range a.autoscalingGroupTargets(targetGroupAddress) {
+		targets = append(targets, lbTarget{asgAddress, nil})
+	}
+	targets = append(targets, a.ecsServiceTargets(targetGroupAddress)...)
+	return targets
+}
+
+// addLBTargetEdges links the nodes of a LB to the nodes of a target with the listener / target ports as label
+func (a *Data) addLBTargetEdges(graph *model.Graph, lbAddress string, targetAddress string, label string) {
+	var flow *model.Flow
+	if label != "" {
+		flow = &model.Flow{Description: label}
+	}
+	for _, src := range a.graphNodes(lbAddress) {
+		for _, dst := range a.graphNodes(targetAddress) {
+			if !graph.IsNode(dst) {
+				// The target is not drawn (e.g. IP address)
+				continue
+			}
+			a.addSGEdge(src, dst, flow, false)
+		}
+	}
+}
+
+// createLBEdges creates the edges of the load balancers: security groups, Internet access and listeners to targets
+func (a *Data) createLBEdges(graph *model.Graph) (error) {
+	for lbAddress, lb := range a.LB {
+		var SGs []string
+		if lb.SecurityGroups != nil {
+			SGs = *lb.SecurityGroups
+		}
+		if len(SGs) == 0 && (lb.Internal == nil || !*lb.Internal) {
+			// Internet-facing LB without SG (e.g. NLB): the Internet can reach all its listeners
+			for _, listener := range a.LBListener {
+				if listener.LoadBalancerArn != lbAddress {
+					continue
+				}
+				for _, nodeName := range a.graphNodes(lbAddress) {
+					a.createInternetSGRuleEdge(ingressRule, nodeName, lbAddress, SGRule{
+						Protocol:	"tcp",
+						FromPort:	intValue(listener.Port),
+						ToPort:		intValue(listener.Port),
+					})
+				}
+			}
+		}
+		a.parseSGRules(lbAddress, SGs, graph)
+	}
+
+	for elbAddress, elb := range a.ELB {
+		var SGs []string
+		if elb.SecurityGroups != nil {
+			SGs = *elb.SecurityGroups
+		}
+		a.parseSGRules(elbAddress, SGs, graph)
+
+		// Listeners to instances, Auto Scaling Groups and ECS services
+		var targets []string
+		if elb.Instances != nil {
+			targets = append(targets, *elb.Instances...)
+		}
+		targets = append(targets, a.autoscalingGroupTargets(elbAddress)...)
+		for _, service := range a.ecsServiceTargets(elbAddress) {
+			targets = append(targets, service.Address)
+		}
+		for _, instance := range targets {
+			for _, listener := range elb.Listeners {
+				label := endpointLabel(&listener.LBProtocol, &listener.LBPort) + " → " + endpointLabel(&listener.InstanceProtocol, &listener.InstancePort)
+				a.addLBTargetEdges(graph, elbAddress, instance, label)
+			}
+		}
+	}
+
+	// Listeners to the targets of their target groups
+	for _, listener := range a.LBListener {
+		if _, found := a.LB[listener.LoadBalancerArn]; !found {
+			continue
+		}
+		for _, action := range listener.DefaultActions {
+			var targetGroups []string
+			if action.TargetGroupArn != nil {
+				targetGroups = append(targetGroups, *action.TargetGroupArn)
+			}
+			for _, forward := range action.Forward {
+				for _, tg := range forward.TargetGroups {
+					targetGroups = append(targetGroups, tg.Arn)
+				}
+			}
+			for _, tgAddress := range targetGroups {
+				tg := a.LBTargetGroup[tgAddress]
+				for _, target := range a.targetGroupTargets(tgAddress) {
+					port := tg.Port
+					if target.Port != nil {
+						port = target.Port
+					}
+					label := endpointLabel(listener.Protocol, listener.Port) + " → " + endpointLabel(tg.Protocol, port)
+					a.addLBTargetEdges(graph, listener.LoadBalancerArn, target.Address, label)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseSGRules parses the ingress / egress rules of the SGs of a resource for all its nodes. Errors are logged
+// and the other SGs are parsed
+func (a *Data) parseSGRules(address string, SGs []string, graph *model.Graph) {
+	for _, nodeName := range a.graphNodes(address) {
+		for _, sg := range SGs {
+			// Parse Ingress SG rules
+			if !a.options.IgnoreIngress {
+				if err := a.parseSGRule(ingressRule, nodeName, sg, graph); err != nil {
+					a.log.Error(err)
+				}
+			}
+
+			// Parse Egress SG rules
+			if !a.options.IgnoreEgress {
+				if err := a.parseSGRule(egressRule, nodeName, sg, graph); err != nil {
+					a.log.Error(err)
+				}
+			}
+		}
+	}
+}
+
+// intValue returns the value of an optional int (0 if not set)
+func intValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
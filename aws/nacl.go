@@ -0,0 +1,178 @@
+package aws
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/steeve85/tfviz/utils"
+)
+
+// networkACLKey returns the key of a network ACL in Data.NetworkACL from a network ACL ID.
+// The default network ACL of a VPC can be referenced by default_network_acl_id or (in plans) by the VPC itself
+func (a *Data) networkACLKey(networkACLID string) string {
+	if _, found := a.Vpc[networkACLID]; found {
+		return networkACLID + ".default_network_acl_id"
+	}
+	return networkACLID
+}
+
+// mergeNACLRules adds the rules declared as standalone resources (aws_network_acl_rule, aws_default_network_acl)
+// to their Network ACL. It must be called once all resources are parsed
+func (a *Data) mergeNACLRules() {
+	for _, r := range a.standaloneNACLRules {
+		if r.NetworkACLID == "" {
+			a.log.Error(fmt.Errorf("%s: unknown network_acl_id, the rule is ignored", r.Address))
+			continue
+		}
+		key := a.networkACLKey(r.NetworkACLID)
+		a.log.Verbosef("Merging %s in %s", r.Address, key)
+		networkACL, found := a.NetworkACL[key]
+		if !found && strings.HasSuffix(key, ".default_network_acl_id") {
+			// Default network ACL of a VPC (not declared as a resource)
+			networkACL.VpcID = strings.TrimSuffix(key, ".default_network_acl_id")
+		}
+		switch {
+		case r.Rule == nil:
+		case r.RuleType == ingressRule:
+			networkACL.Ingress = append(networkACL.Ingress, *r.Rule)
+		default:
+			networkACL.Egress = append(networkACL.Egress, *r.Rule)
+		}
+		a.NetworkACL[key] = networkACL
+	}
+	a.standaloneNACLRules = nil
+}
+
+// subnetNetworkACL returns the key of the network ACL of a subnet ("" if unknown)
+func (a *Data) subnetNetworkACL(subnetAddress string) string {
+	if subnetAddress == "" {
+		return ""
+	}
+	for _, association := range a.NetworkACLAssociation {
+		if association.SubnetID == subnetAddress {
+			return a.networkACLKey(association.NetworkACLID)
+		}
+	}
+	for k, v := range a.NetworkACL {
+		if v.SubnetIDs == nil {
+			continue
+		}
+		if _, found := utils.Find(*v.SubnetIDs, subnetAddress); found {
+			return k
+		}
+	}
+
+	// Subnets without explicit association use the default network ACL of their VPC
+	key := a.Subnet[subnetAddress].VpcID + ".default_network_acl_id"
+	if _, found := a.NetworkACL[key]; found {
+		return key
+	}
+	return ""
+}
+
+// networkACLName returns the name of a network ACL to be displayed in a subnet label
+func networkACLName(key string) string {
+	if strings.HasSuffix(key, ".default_network_acl_id") {
+		return "default"
+	}
+	modulePath, _, name := utils.SplitAddress(key)
+	return utils.ModulePrefix(modulePath) + name
+}
+
+// protocolNumber converts a protocol name to its number ("-1" for all protocols)
+func protocolNumber(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "all":
+		return "-1"
+	case "icmp":
+		return "1"
+	case "tcp":
+		return "6"
+	case "udp":
+		return "17"
+	case "icmpv6":
+		return "58"
+	}
+	return protocol
+}
+
+// naclAllows evaluates the rules of the network ACL of a subnet (by rule number, the first matching rule
+// applying) for the traffic allowed by a SG rule from / to a peer CIDR. The traffic is allowed if at least a part
+// of it (a port, a protocol or an IP range) is allowed. Subnets without known network ACL allow everything.
+// Network ACLs are stateless but the return traffic (to the ephemeral ports, in the opposite direction) is not
+// modelled: only the direction of the SG rule is evaluated
+func (a *Data) naclAllows(subnetAddress string, egress bool, sgRule SGRule, peerCidr string) bool {
+	networkACL, found := a.NetworkACL[a.subnetNetworkACL(subnetAddress)]
+	if !found {
+		return true
+	}
+	_, peer, err := net.ParseCIDR(peerCidr)
+	if err != nil {
+		return true
+	}
+	rules := append([]NACLRule{}, networkACL.Ingress...)
+	if egress {
+		rules = append([]NACLRule{}, networkACL.Egress...)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].RuleNo < rules[j].RuleNo
+	})
+
+	protocol := protocolNumber(sgRule.Protocol)
+	fromPort, toPort := sgRule.FromPort, sgRule.ToPort
+	if protocol == "-1" {
+		fromPort, toPort = 0, 65535
+	}
+	hasPorts := protocol == "-1" || protocol == "6" || protocol == "17"
+
+	var portRanges [][2]int
+	for _, rule := range rules {
+		portRanges = append(portRanges, [2]int{rule.FromPort, rule.ToPort})
+	}
+
+	// The traffic matched by no rule is denied by the implicit deny rule (*)
+	return utils.RulesAllow(len(rules), fromPort, toPort, portRanges, func(i int, port int) (full bool, partial bool, allow bool) {
+		rule := rules[i]
+		if rule.CidrBlock == nil || *rule.CidrBlock == "" {
+			// IPv6 rules
+			return false, false, false
+		}
+		fullCidr, partialCidr := utils.CidrMatch(*rule.CidrBlock, peer)
+		if !fullCidr && !partialCidr {
+			return false, false, false
+		}
+		ruleProtocol := protocolNumber(rule.Protocol)
+		fullProtocol := ruleProtocol == "-1" || ruleProtocol == protocol
+		if !fullProtocol && protocol != "-1" {
+			return false, false, false
+		}
+		if hasPorts && (ruleProtocol == "6" || ruleProtocol == "17") && (port < rule.FromPort || port > rule.ToPort) {
+			return false, false, false
+		}
+		full = fullCidr && fullProtocol
+		return full, !full, strings.ToLower(rule.Action) == "allow"
+	})
+}
+
+// blockedByNACL returns true if the traffic allowed by a SG rule of a node is denied by the network ACLs
+// of the node subnet or of the peer subnet. The peer is identified by its CIDR or its subnet
+func (a *Data) blockedByNACL(ruleType int, nodeName string, rule SGRule, peerCidr string, peerSubnet string) bool {
+	subnet := a.nodeSubnets[nodeName]
+	if peerSubnet == subnet {
+		// Network ACLs don't filter the traffic inside a subnet
+		return false
+	}
+	if peerCidr == "" {
+		peerCidr = a.Subnet[peerSubnet].CidrBlock
+	}
+	egress := ruleType == egressRule
+	if subnet != "" && !a.naclAllows(subnet, egress, rule, peerCidr) {
+		return true
+	}
+	if peerSubnet != "" && !a.naclAllows(peerSubnet, !egress, rule, a.Subnet[subnet].CidrBlock) {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/steeve85/tfviz/provider"
+	"github.com/steeve85/tfviz/utils"
+)
+
+func cidrPtr(cidr string) *string {
+	return &cidr
+}
+
+// naclTestData returns Data with the subnet aws_subnet.app (10.0.1.0/24) filtered by a network ACL with the
+// ingress and egress rules, and the subnet aws_subnet.db (10.0.2.0/24) without network ACL
+func naclTestData(ingress []NACLRule, egress []NACLRule) *Data {
+	a := NewData(provider.Options{Log: &utils.Logger{}})
+	a.Subnet["aws_subnet.app"] = Subnet{CidrBlock: "10.0.1.0/24", VpcID: "aws_vpc.main"}
+	a.Subnet["aws_subnet.db"] = Subnet{CidrBlock: "10.0.2.0/24", VpcID: "aws_vpc.main"}
+	a.NetworkACL["aws_network_acl.app"] = NetworkACL{
+		VpcID:		"aws_vpc.main",
+		SubnetIDs:	&[]string{"aws_subnet.app"},
+		Ingress:	ingress,
+		Egress:		egress,
+	}
+	a.nodeSubnets = map[string]string{"aws_instance.app": "aws_subnet.app", "aws_instance.db": "aws_subnet.db"}
+	return a
+}
+
+func TestNACLAllows(t *testing.T) {
+	tests := []struct {
+		name		string
+		rules		[]NACLRule
+		sgRule		SGRule
+		peer		string
+		want		bool
+	}{
+		{
+			name:	"rules ordered by number, not by declaration",
+			rules:	[]NACLRule{
+				{RuleNo: 200, Action: "allow", Protocol: "-1", CidrBlock: cidrPtr("0.0.0.0/0")},
+				{RuleNo: 100, Action: "deny", Protocol: "6", FromPort: 0, ToPort: 65535, CidrBlock: cidrPtr("0.0.0.0/0")},
+			},
+			sgRule:	SGRule{Protocol: "tcp", FromPort: 443, ToPort: 443},
+			peer:	"203.0.113.0/24",
+			want:	false,
+		},
+		{
+			name:	"protocol numbers match protocol names",
+			rules:	[]NACLRule{
+				{RuleNo: 100, Action: "deny", Protocol: "6", FromPort: 22, ToPort: 22, CidrBlock: cidrPtr("0.0.0.0/0")},
+				{RuleNo: 200, Action: "allow", Protocol: "-1", CidrBlock: cidrPtr("0.0.0.0/0")},
+			},
+			sgRule:	SGRule{Protocol: "tcp", FromPort: 22, ToPort: 22},
+			peer:	"203.0.113.0/24",
+			want:	false,
+		},
+		{
+			name:	"other protocol allowed only",
+			rules:	[]NACLRule{
+				{RuleNo: 100, Action: "allow", Protocol: "udp", FromPort: 53, ToPort: 53, CidrBlock: cidrPtr("0.0.0.0/0")},
+			},
+			sgRule:	SGRule{Protocol: "tcp", FromPort: 53, ToPort: 53},
+			peer:	"0.0.0.0/0",
+			want:	false,
+		},
+		{
+			name:	"all protocols partially allowed",
+			rules:	[]NACLRule{
+				{RuleNo: 100, Action: "deny", Protocol: "tcp", FromPort: 0, ToPort: 65535, CidrBlock: cidrPtr("0.0.0.0/0")},
+				{RuleNo: 200, Action: "allow", Protocol: "udp", FromPort: 53, ToPort: 53, CidrBlock: cidrPtr("0.0.0.0/0")},
+			},
+			sgRule:	SGRule{Protocol: "-1"},
+			peer:	"0.0.0.0/0",
+			want:	true,
+		},
+		{
+			name:	"ICMP rules have no ports",
+			rules:	[]NACLRule{
+				{RuleNo: 100, Action: "allow", Protocol: "icmp", FromPort: 0, ToPort: 0, CidrBlock: cidrPtr("0.0.0.0/0")},
+			},
+			sgRule:	SGRule{Protocol: "icmp", FromPort: 8, ToPort: 0},
+			peer:	"0.0.0.0/0",
+			want:	true,
+		},
+		{
+			name:	"* rule denies the traffic matched by no rule",
+			rules:	[]NACLRule{},
+			sgRule:	SGRule{Protocol: "-1"},
+			peer:	"0.0.0.0/0",
+			want:	false,
+		},
+		{
+			name:	"IPv6 rules are ignored",
+			rules:	[]NACLRule{
+				{RuleNo: 100, Action: "allow", Protocol: "-1", IPv6CidrBlock: cidrPtr("::/0")},
+			},
+			sgRule:	SGRule{Protocol: "tcp", FromPort: 22, ToPort: 22},
+			peer:	"0.0.0.0/0",
+			want:	false,
+		},
+	}
+	for _, test := range tests {
+		a := naclTestData(test.rules, nil)
+		if got := a.naclAllows("aws_subnet.app", false, test.sgRule, test.peer); got != test.want {
+			t.Errorf("%s: naclAllows = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNACLAllowsWithoutNACL(t *testing.T) {
+	a := naclTestData(nil, nil)
+	if !a.naclAllows("aws_subnet.db", false, SGRule{Protocol: "-1"}, "0.0.0.0/0") {
+		t.Error("a subnet without network ACL must allow all the traffic")
+	}
+}
+
+func TestBlockedByNACL(t *testing.T) {
+	allowAll := []NACLRule{{RuleNo: 100, Action: "allow", Protocol: "-1", CidrBlock: cidrPtr("0.0.0.0/0")}}
+	denyDB := []NACLRule{
+		{RuleNo: 100, Action: "deny", Protocol: "-1", CidrBlock: cidrPtr("10.0.2.0/24")},
+		{RuleNo: 200, Action: "allow", Protocol: "-1", CidrBlock: cidrPtr("0.0.0.0/0")},
+	}
+	ssh := SGRule{Protocol: "tcp", FromPort: 22, ToPort: 22}
+
+	// Egress from aws_instance.app to the db subnet is denied by the NACL of the app subnet
+	a := naclTestData(allowAll, denyDB)
+	if !a.blockedByNACL(egressRule, "aws_instance.app", ssh, "", "aws_subnet.db") {
+		t.Error("egress to a denied subnet must be blocked")
+	}
+	// Ingress of aws_instance.db from the app subnet is evaluated on the egress rules of the app subnet
+	if !a.blockedByNACL(ingressRule, "aws_instance.db", ssh, "", "aws_subnet.app") {
+		t.Error("ingress from a subnet denying the egress traffic must be blocked")
+	}
+	// NACLs don't filter the traffic inside a subnet
+	if a.blockedByNACL(egressRule, "aws_instance.app", ssh, "", "aws_subnet.app") {
+		t.Error("the traffic inside a subnet must not be blocked")
+	}
+
+	// The return traffic (ephemeral ports in the opposite direction) is not modelled: egress rules denying it
+	// don't block the ingress flows
+	a = naclTestData(allowAll, []NACLRule{{RuleNo: 100, Action: "deny", Protocol: "-1", CidrBlock: cidrPtr("0.0.0.0/0")}})
+	if a.blockedByNACL(ingressRule, "aws_instance.app", ssh, "0.0.0.0/0", "") {
+		t.Error("ingress must only be evaluated on the ingress rules")
+	}
+}
@@ -0,0 +1,298 @@
+package aws
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// addConnectionEdge adds an edge for a connection between networks (VPC peering, Transit Gateway attachment,
+// VPN connection) to the graph
+func (a *Data) addConnectionEdge(graph *model.Graph, src string, dst string, label string) (error) {
+	a.log.Verbosef("AddEdge: %s -> %s", src, dst)
+	graph.AddEdge(&model.Edge{
+		Src:	src,
+		Dst:	dst,
+		Kind:	model.ConnectionEdge,
+		Label:	label,
+	})
+	return nil
+}
+
+// addGatewayNode adds a gateway node (Transit Gateway, VPN Gateway, Customer Gateway) to the graph
+func (a *Data) addGatewayNode(graph *model.Graph, parent string, address string, label string, icon string) (error) {
+	a.log.Verbosef("AddNode: %s to %s", utils.NodeID(address), parent)
+	node := iconNode(address, label, icon)
+	node.Parent = parent
+	return graph.AddNode(node)
+}
+
+// peeringEndpoint returns the node of a side of a VPC peering connection: the VPC if it is defined in TF, or a node
+// created for the peer VPC otherwise
+func (a *Data) peeringEndpoint(peeringAddress string, vpcID string) string {
+	if _, found := a.Vpc[vpcID]; found {
+		return utils.NodeID(vpcID)
+	}
+	return utils.NodeID(peeringAddress + "." + vpcID)
+}
+
+// vpnGatewayVpc returns the VPC of a VPN gateway ("" if it is not attached)
+func (a *Data) vpnGatewayVpc(vgwAddress string) string {
+	if vpcID := stringValue(a.VpnGateway[vgwAddress].VpcID); vpcID != "" {
+		return vpcID
+	}
+	for _, attachment := range a.VpnGatewayAttachment {
+		if attachment.VpnGatewayID == vgwAddress {
+			return attachment.VpcID
+		}
+	}
+	return ""
+}
+
+// vpnConnectionRoutes returns the static routes of a VPN connection (CIDRs of the customer network)
+func (a *Data) vpnConnectionRoutes(vpnAddress string) []string {
+	var routes []string
+	for _, route := range a.VpnConnectionRoute {
+		if route.VpnConnectionID == vpnAddress {
+			routes = append(routes, route.DestinationCidrBlock)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// createNetworkGateways creates the nodes of the Transit Gateways, VPN Gateways, Customer Gateways and of the
+// peer VPCs not defined in TF
+func (a *Data) createNetworkGateways(graph *model.Graph) (error) {
+	for peeringAddress, peering := range a.VpcPeeringConnection {
+		for _, vpcID := range []string{peering.VpcID, peering.PeerVpcID} {
+			if _, found := a.Vpc[vpcID]; found || vpcID == "" {
+				continue
+			}
+			// Peer VPC not defined in TF (e.g. in another account or region)
+			label := "Peer VPC: " + vpcID
+			if peering.PeerOwnerID != nil && *peering.PeerOwnerID != "" {
+				label += "\naccount: " + *peering.PeerOwnerID
+			}
+			if peering.PeerRegion != nil && *peering.PeerRegion != "" {
+				label += "\nregion: " + *peering.PeerRegion
+			}
+			id := a.peeringEndpoint(peeringAddress, vpcID)
+			a.log.Verbosef("AddNode: %s", id)
+			err := graph.AddNode(&model.Node{
+				ID:			id,
+				Kind:		model.ExternalNode,
+				Label:		label,
+				Attributes:	map[string]string{"id": vpcID},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for tgwAddress := range a.TransitGateway {
+		modulePath, _, tgwName := utils.SplitAddress(tgwAddress)
+		err := a.addGatewayNode(graph, a.moduleGroup(modulePath), tgwAddress, utils.LabelName(tgwName), "tgw.png")
+		if err != nil {
+			return err
+		}
+	}
+
+	// VPN Gateways are drawn in their VPC, like Internet Gateways
+	for vgwAddress := range a.VpnGateway {
+		modulePath, _, vgwName := utils.SplitAddress(vgwAddress)
+		parent := a.moduleGroup(modulePath)
+		if _, found := a.Vpc[a.vpnGatewayVpc(vgwAddress)]; found {
+			parent = utils.NodeID(a.vpnGatewayVpc(vgwAddress))
+		}
+		err := a.addGatewayNode(graph, parent, vgwAddress, utils.LabelName(vgwName), "vgw.png")
+		if err != nil {
+			return err
+		}
+	}
+
+	for cgwAddress, cgw := range a.CustomerGateway {
+		modulePath, _, cgwName := utils.SplitAddress(cgwAddress)
+		label := strings.Join(utils.ChunkString(cgwName, 8), "\n")
+		if cgw.IPAddress != nil && *cgw.IPAddress != "" {
+			label += "\n(" + *cgw.IPAddress + ")"
+		}
+		err := a.addGatewayNode(graph, a.moduleGroup(modulePath), cgwAddress, label, "cgw.png")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createConnectionEdges creates the edges of the VPC peering connections, Transit Gateway attachments and VPN
+// connections
+func (a *Data) createConnectionEdges(graph *model.Graph) (error) {
+	for peeringAddress, peering := range a.VpcPeeringConnection {
+		if peering.VpcID == "" || peering.PeerVpcID == "" {
+			continue
+		}
+		_, _, peeringName := utils.SplitAddress(peeringAddress)
+		err := a.addConnectionEdge(graph, a.peeringEndpoint(peeringAddress, peering.VpcID), a.peeringEndpoint(peeringAddress, peering.PeerVpcID), "peering: "+peeringName)
+		if err != nil {
+			return err
+		}
+	}
+
+	for attachmentAddress, attachment := range a.TransitGatewayVpcAttachment {
+		if _, found := a.TransitGateway[attachment.TransitGatewayID]; !found {
+			continue
+		}
+		if _, found := a.Vpc[attachment.VpcID]; !found {
+			continue
+		}
+		_, _, attachmentName := utils.SplitAddress(attachmentAddress)
+		err := a.addConnectionEdge(graph, utils.NodeID(attachment.VpcID), utils.NodeID(attachment.TransitGatewayID), attachmentName)
+		if err != nil {
+			return err
+		}
+	}
+
+	for vpnAddress, vpn := range a.VpnConnection {
+		if _, found := a.CustomerGateway[vpn.CustomerGatewayID]; !found {
+			continue
+		}
+		gateway := stringValue(vpn.VpnGatewayID)
+		if _, found := a.VpnGateway[gateway]; !found {
+			gateway = stringValue(vpn.TransitGatewayID)
+			if _, found := a.TransitGateway[gateway]; !found {
+				continue
+			}
+		}
+		_, _, vpnName := utils.SplitAddress(vpnAddress)
+		label := "VPN: " + vpnName
+		if routes := a.vpnConnectionRoutes(vpnAddress); len(routes) > 0 {
+			label += "\n" + strings.Join(routes, "\n")
+		}
+		err := a.addConnectionEdge(graph, utils.NodeID(vpn.CustomerGatewayID), utils.NodeID(gateway), label)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routeToCidr returns the target of the most specific route of a subnet to a CIDR ("" if none)
+func (a *Data) routeToCidr(subnetAddress string, cidr string) string {
+	_, peer, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return ""
+	}
+	routeTable, found := a.RouteTable[a.subnetRouteTable(subnetAddress)]
+	if !found {
+		return ""
+	}
+	target, bestOnes := "", -1
+	for _, route := range routeTable.Routes {
+		if route.CidrBlock == nil {
+			continue
+		}
+		full, _ := utils.CidrMatch(*route.CidrBlock, peer)
+		if !full {
+			continue
+		}
+		_, routeNet, _ := net.ParseCIDR(*route.CidrBlock)
+		ones, _ := routeNet.Mask.Size()
+		if ones > bestOnes {
+			target, bestOnes = routeTarget(route), ones
+		}
+	}
+	return target
+}
+
+// transitGatewayRouteToCidr returns the node reached through a Transit Gateway for a CIDR: the VPC or the Customer
+// Gateway of the attachment of the most specific route of its route tables, or the Transit Gateway itself
+func (a *Data) transitGatewayRouteToCidr(tgwAddress string, peer *net.IPNet) string {
+	attachment, bestOnes := "", -1
+	for _, route := range a.TransitGatewayRoute {
+		routeTableID := route.TransitGatewayRouteTableID
+		if routeTable, found := a.TransitGatewayRouteTable[routeTableID]; found {
+			routeTableID = routeTable.TransitGatewayID
+		} else {
+			// Default route tables of the Transit Gateway
+			routeTableID = strings.TrimSuffix(strings.TrimSuffix(routeTableID, ".association_default_route_table_id"), ".propagation_default_route_table_id")
+		}
+		if routeTableID != tgwAddress || (route.Blackhole != nil && *route.Blackhole) {
+			continue
+		}
+		full, _ := utils.CidrMatch(route.DestinationCidrBlock, peer)
+		if !full {
+			continue
+		}
+		_, routeNet, _ := net.ParseCIDR(route.DestinationCidrBlock)
+		ones, _ := routeNet.Mask.Size()
+		if ones > bestOnes {
+			attachment, bestOnes = referencedResource(stringValue(route.TransitGatewayAttachmentID)), ones
+		}
+	}
+	if vpcAttachment, found := a.TransitGatewayVpcAttachment[attachment]; found {
+		if _, found := a.Vpc[vpcAttachment.VpcID]; found {
+			return utils.NodeID(vpcAttachment.VpcID)
+		}
+	}
+	if vpn, found := a.VpnConnection[attachment]; found {
+		if _, found := a.CustomerGateway[vpn.CustomerGatewayID]; found {
+			return utils.NodeID(vpn.CustomerGatewayID)
+		}
+	}
+	return utils.NodeID(tgwAddress)
+}
+
+// routedPeer returns the node reached by a node for a CIDR outside of the VPCs defined in TF, following the routes
+// of its subnet through VPC peering connections, Transit Gateways and VPN Gateways ("" if there is no such route)
+func (a *Data) routedPeer(nodeName string, cidr string) string {
+	subnet := a.nodeSubnets[nodeName]
+	if subnet == "" {
+		return ""
+	}
+	target := a.routeToCidr(subnet, cidr)
+	_, peer, err := net.ParseCIDR(cidr)
+	if target == "" || err != nil {
+		return ""
+	}
+
+	if peering, found := a.VpcPeeringConnection[target]; found {
+		if peering.VpcID == a.Subnet[subnet].VpcID {
+			return a.peeringEndpoint(target, peering.PeerVpcID)
+		}
+		return a.peeringEndpoint(target, peering.VpcID)
+	}
+	if _, found := a.TransitGateway[target]; found {
+		return a.transitGatewayRouteToCidr(target, peer)
+	}
+	if _, found := a.VpnGateway[target]; found {
+		// Customer network reached through a VPN connection of the VPN Gateway (the one with a matching static
+		// route if any)
+		var cgws []string
+		for vpnAddress, vpn := range a.VpnConnection {
+			if stringValue(vpn.VpnGatewayID) != target {
+				continue
+			}
+			if _, found := a.CustomerGateway[vpn.CustomerGatewayID]; !found {
+				continue
+			}
+			for _, route := range a.vpnConnectionRoutes(vpnAddress) {
+				if full, _ := utils.CidrMatch(route, peer); full {
+					return utils.NodeID(vpn.CustomerGatewayID)
+				}
+			}
+			cgws = append(cgws, vpn.CustomerGatewayID)
+		}
+		if len(cgws) > 0 {
+			sort.Strings(cgws)
+			return utils.NodeID(cgws[0])
+		}
+		return utils.NodeID(target)
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package aws
+
+import (
+	"strings"
+
+	"github.com/steeve85/tfviz/provider"
+	"github.com/steeve85/tfviz/utils"
+)
+
+
+func init() {
+	provider.Register("aws", func(options provider.Options) provider.Provider {
+		return NewData(options)
+	})
+}
+
+// NewData returns an empty Data drawing a graph with these options
+func NewData(options provider.Options) *Data {
+	return &Data{
+		options:			options,
+		log:				options.Log,
+		Vpc:				make(map[string]Vpc),
+		Subnet:				make(map[string]Subnet),
+		Instance:			make(map[string]Instance),
+		SecurityGroup:		make(map[string]SecurityGroup),
+		DBInstance:			make(map[string]DBInstance),
+		DBSubnetGroup:		make(map[string]DBSubnetGroup),
+		RDSCluster:			make(map[string]RDSCluster),
+		RDSClusterInstance:	make(map[string]RDSClusterInstance),
+		ElastiCacheCluster:	make(map[string]ElastiCacheCluster),
+		ElastiCacheReplicationGroup:	make(map[string]ElastiCacheReplicationGroup),
+		ElastiCacheSubnetGroup:		make(map[string]DBSubnetGroup),
+		RedshiftCluster:	make(map[string]RedshiftCluster),
+		RedshiftSubnetGroup:	make(map[string]DBSubnetGroup),
+		OpenSearchDomain:	make(map[string]OpenSearchDomain),
+		LambdaFunction:		make(map[string]LambdaFunction),
+		LambdaEventSourceMapping:	make(map[string]LambdaEventSourceMapping),
+		LambdaPermission:	make(map[string]LambdaPermission),
+		S3BucketNotification:	make(map[string]S3BucketNotification),
+		APIGateway:			make(map[string]APIGateway),
+		APIGatewayIntegration:	make(map[string]APIGatewayIntegration),
+		ECSCluster:			make(map[string]ECSCluster),
+		ECSService:			make(map[string]ECSService),
+		ECSTaskDefinition:	make(map[string]ECSTaskDefinition),
+		EKSCluster:			make(map[string]EKSCluster),
+		EKSNodeGroup:		make(map[string]EKSNodeGroup),
+		VpcPeeringConnection:	make(map[string]VpcPeeringConnection),
+		TransitGateway:	make(map[string]TransitGateway),
+		TransitGatewayVpcAttachment:	make(map[string]TransitGatewayVpcAttachment),
+		TransitGatewayRouteTable:	make(map[string]TransitGatewayRouteTable),
+		TransitGatewayRoute:	make(map[string]TransitGatewayRoute),
+		VpnGateway:	make(map[string]VpnGateway),
+		VpnGatewayAttachment:	make(map[string]VpnGatewayAttachment),
+		CustomerGateway:	make(map[string]CustomerGateway),
+		VpnConnection:	make(map[string]VpnConnection),
+		VpnConnectionRoute:	make(map[string]VpnConnectionRoute),
+		VpcEndpoint:	make(map[string]VpcEndpoint),
+		VpcEndpointAssociation:	make(map[string]VpcEndpointAssociation),
+		VpcEndpointService:	make(map[string]VpcEndpointService),
+		S3:					make(map[string]S3),
+		InternetGateway:	make(map[string]InternetGateway),
+		EgressOnlyInternetGateway:	make(map[string]InternetGateway),
+		NatGateway:			make(map[string]NatGateway),
+		RouteTable:			make(map[string]RouteTable),
+		RouteTableAssociation:		make(map[string]RouteTableAssociation),
+		MainRouteTableAssociation:	make(map[string]MainRouteTableAssociation),
+		NetworkACL:			make(map[string]NetworkACL),
+		NetworkACLAssociation:		make(map[string]NetworkACLAssociation),
+		LB:					make(map[string]LB),
+		ELB:				make(map[string]ELB),
+		LBListener:			make(map[string]LBListener),
+		LBTargetGroup:		make(map[string]LBTargetGroup),
+		LBTargetGroupAttachment:	make(map[string]LBTargetGroupAttachment),
+		AutoscalingGroup:	make(map[string]AutoscalingGroup),
+		AutoscalingAttachment:		make(map[string]AutoscalingAttachment),
+		LaunchTemplate:		make(map[string]LaunchTemplate),
+		LaunchConfiguration:	make(map[string]LaunchConfiguration),
+		SecurityGroupNodeLinks:		make(map[string][]string),
+
+		resourceIDs:		make(map[string]string),
+	}
+}
+
+// Claims returns true for the resources of the aws provider
+func (a *Data) Claims(resourceType string) bool {
+	return strings.HasPrefix(resourceType, "aws_")
+}
+
+// ReferencedAttributes returns the computed attributes used to reference AWS resources
+func (a *Data) ReferencedAttributes() map[string][]string {
+	return referencedAttributes
+}
+
+// IDAttributes returns arn, which identifies AWS resources like their id
+func (a *Data) IDAttributes() []string {
+	return []string{"arn"}
+}
+
+// ListAttribute returns true if the attribute at path (e.g. security_groups) of a resource type is decoded as a list
+func (a *Data) ListAttribute(resourceType string, path []string) bool {
+	handler, found := resourceHandlers[resourceType]
+	return found && utils.IsListAttribute(handler.value, path)
+}
@@ -0,0 +1,225 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// Defining values for the routing of subnets
+const (
+	// No route table is known for the subnet
+	unknownSubnet = ""
+	// The subnet has a default route to an Internet Gateway
+	publicSubnet = "public"
+	// The subnet has no default route to an Internet Gateway
+	privateSubnet = "private"
+)
+
+// routeTableKey returns the key of a route table in Data.RouteTable from a route table ID.
+// The main route table of a VPC can be referenced by main_route_table_id, default_route_table_id
+// or (in plans) by the VPC itself
+func (a *Data) routeTableKey(routeTableID string) string {
+	if strings.HasSuffix(routeTableID, ".default_route_table_id") {
+		return strings.TrimSuffix(routeTableID, ".default_route_table_id") + ".main_route_table_id"
+	}
+	if _, found := a.Vpc[routeTableID]; found {
+		return routeTableID + ".main_route_table_id"
+	}
+	return routeTableID
+}
+
+// mergeRoutes adds the routes declared as standalone resources (aws_route, aws_default_route_table)
+// to their Route Table. It must be called once all resources are parsed
+func (a *Data) mergeRoutes() {
+	for _, r := range a.standaloneRoutes {
+		if r.RouteTableID == "" {
+			a.log.Error(fmt.Errorf("%s: unknown route_table_id, the route is ignored", r.Address))
+			continue
+		}
+		key := a.routeTableKey(r.RouteTableID)
+		a.log.Verbosef("Merging %s in %s", r.Address, key)
+		routeTable, found := a.RouteTable[key]
+		if !found && strings.HasSuffix(key, ".main_route_table_id") {
+			// Main route table of a VPC (not declared as a resource)
+			routeTable.VpcID = strings.TrimSuffix(key, ".main_route_table_id")
+		}
+		if r.Route != nil {
+			routeTable.Routes = append(routeTable.Routes, *r.Route)
+		}
+		a.RouteTable[key] = routeTable
+	}
+	a.standaloneRoutes = nil
+}
+
+// subnetRouteTable returns the key of the route table used by a subnet ("" if unknown)
+func (a *Data) subnetRouteTable(subnetAddress string) string {
+	for _, association := range a.RouteTableAssociation {
+		if association.SubnetID != nil && *association.SubnetID == subnetAddress {
+			return a.routeTableKey(association.RouteTableID)
+		}
+	}
+
+	// Subnets without explicit association use the main route table of their VPC
+	vpcAddress := a.Subnet[subnetAddress].VpcID
+	for _, association := range a.MainRouteTableAssociation {
+		if association.VpcID == vpcAddress {
+			return a.routeTableKey(association.RouteTableID)
+		}
+	}
+	if _, found := a.RouteTable[vpcAddress+".main_route_table_id"]; found {
+		return vpcAddress + ".main_route_table_id"
+	}
+	return ""
+}
+
+// routeTarget returns the target of a route ("" if none)
+func routeTarget(route Route) string {
+	for _, target := range []*string{route.GatewayID, route.NatGatewayID, route.EgressOnlyGatewayID, route.TransitGatewayID, route.VpcPeeringConnectionID, route.VpcEndpointID} {
+		// Targets not set are empty strings in states
+		if target != nil && *target != "" {
+			return *target
+		}
+	}
+	return ""
+}
+
+// defaultRoutes returns the default routes (0.0.0.0/0 and ::/0) of a subnet, indexed by destination.
+// known is false if the route table of the subnet is unknown
+func (a *Data) defaultRoutes(subnetAddress string) (routes map[string]string, known bool) {
+	routeTable, found := a.RouteTable[a.subnetRouteTable(subnetAddress)]
+	if !found {
+		return nil, false
+	}
+	routes = make(map[string]string)
+	for _, route := range routeTable.Routes {
+		target := routeTarget(route)
+		if target == "" {
+			continue
+		}
+		if route.CidrBlock != nil && *route.CidrBlock == "0.0.0.0/0" {
+			routes["0.0.0.0/0"] = target
+		}
+		if route.IPv6CidrBlock != nil && *route.IPv6CidrBlock == "::/0" {
+			routes["::/0"] = target
+		}
+	}
+	return routes, true
+}
+
+// subnetRouting returns publicSubnet, privateSubnet or unknownSubnet (route table unknown)
+func (a *Data) subnetRouting(subnetAddress string) string {
+	routes, known := a.defaultRoutes(subnetAddress)
+	if !known {
+		return unknownSubnet
+	}
+	if _, found := a.InternetGateway[routes["0.0.0.0/0"]]; found {
+		return publicSubnet
+	}
+	return privateSubnet
+}
+
+// isInternetGateway returns true if the node is an (Egress-only) Internet Gateway or a NAT Gateway
+func (a *Data) isInternetGateway(address string) bool {
+	_, igw := a.InternetGateway[address]
+	_, eigw := a.EgressOnlyInternetGateway[address]
+	_, nat := a.NatGateway[address]
+	return igw || eigw || nat
+}
+
+func (a *Data) createInternetGateway(graph *model.Graph, igwAddress string, igw InternetGateway, egressOnly bool, vpcs map[string]Vpc) (error) {
+	// Create Internet Gateway node in its VPC
+	igwID := utils.NodeID(igwAddress)
+	modulePath, _, igwName := utils.SplitAddress(igwAddress)
+	parent := a.moduleGroup(modulePath)
+	if igw.VpcID != nil {
+		if _, found := vpcs[*igw.VpcID]; found {
+			parent = utils.NodeID(*igw.VpcID)
+		}
+	}
+	icon := "igw.png"
+	if egressOnly {
+		icon = "egress-igw.png"
+	}
+	a.log.Verbosef("AddNode: %s to %s // Create Internet Gateway", igwID, parent)
+
+	// Splitting label if more than 8 chars
+	node := iconNode(igwAddress, utils.LabelName(igwName), icon)
+	node.Parent = parent
+	return graph.AddNode(node)
+}
+
+func (a *Data) createNatGateway(graph *model.Graph, natAddress string, nat NatGateway, subnets map[string]Subnet) (error) {
+	// Create NAT Gateway node in its subnet
+	natID := utils.NodeID(natAddress)
+	modulePath, _, natName := utils.SplitAddress(natAddress)
+	parent := a.moduleGroup(modulePath)
+	if _, found := subnets[nat.SubnetID]; found {
+		parent = utils.NodeID(nat.SubnetID)
+	}
+	a.log.Verbosef("AddNode: %s to %s // Create NAT Gateway", natID, parent)
+
+	// Splitting label if more than 8 chars
+	node := iconNode(natAddress, utils.LabelName(natName), "nat.png")
+	node.Parent = parent
+	return graph.AddNode(node)
+}
+
+// addRouteEdge adds an edge for a route to the graph, labelled with its destination
+func (a *Data) addRouteEdge(graph *model.Graph, src string, dst string, destination string) (error) {
+	a.log.Verbosef("AddEdge: %s -> %s", src, dst)
+	graph.AddEdge(&model.Edge{
+		Src:	src,
+		Dst:	dst,
+		Kind:	model.RouteEdge,
+		Label:	destination,
+	})
+	return nil
+}
+
+// createRouteEdges draws the path from subnets to the Internet: subnet -> NAT -> IGW -> Internet
+func (a *Data) createRouteEdges(graph *model.Graph) (error) {
+	// Subnets (and the NAT Gateways they contain) to their gateways
+	for subnetAddress := range a.Subnet {
+		routes, known := a.defaultRoutes(subnetAddress)
+		if !known {
+			continue
+		}
+		for destination, target := range routes {
+			if !a.isInternetGateway(target) {
+				continue
+			}
+			err := a.addRouteEdge(graph, utils.NodeID(subnetAddress), utils.NodeID(target), destination)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for natAddress, nat := range a.NatGateway {
+		routes, _ := a.defaultRoutes(nat.SubnetID)
+		if _, found := a.InternetGateway[routes["0.0.0.0/0"]]; found {
+			err := a.addRouteEdge(graph, utils.NodeID(natAddress), utils.NodeID(routes["0.0.0.0/0"]), "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Internet Gateways to the Internet
+	for igwAddress := range a.InternetGateway {
+		err := a.addRouteEdge(graph, utils.NodeID(igwAddress), "Internet", "")
+		if err != nil {
+			return err
+		}
+	}
+	for igwAddress := range a.EgressOnlyInternetGateway {
+		err := a.addRouteEdge(graph, utils.NodeID(igwAddress), "Internet", "")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "a" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.1.0/24"
+}
+
+resource "aws_subnet" "b" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.2.0/24"
+}
+
+resource "aws_security_group" "web" {
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 80
+    to_port     = 80
+    protocol    = "tcp"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+resource "aws_launch_template" "web" {
+  image_id               = "ami-123456"
+  instance_type          = "t2.micro"
+  vpc_security_group_ids = [aws_security_group.web.id]
+}
+
+resource "aws_autoscaling_group" "web" {
+  name                = "web"
+  min_size            = 2
+  max_size            = 4
+  vpc_zone_identifier = [aws_subnet.a.id, aws_subnet.b.id]
+  target_group_arns   = [aws_lb_target_group.web.arn]
+
+  launch_template {
+    id = aws_launch_template.web.id
+  }
+}
+
+resource "aws_lb" "front" {
+  name    = "front"
+  subnets = [aws_subnet.a.id]
+}
+
+resource "aws_lb_target_group" "web" {
+  port     = 80
+  protocol = "HTTP"
+  vpc_id   = aws_vpc.main.id
+}
+
+resource "aws_lb_listener" "http" {
+  load_balancer_arn = aws_lb.front.arn
+  port              = 80
+  protocol          = "HTTP"
+
+  default_action {
+    type             = "forward"
+    target_group_arn = aws_lb_target_group.web.arn
+  }
+}
+
+# Launch configuration without security group: the default SG is used
+resource "aws_launch_configuration" "batch" {
+  image_id      = "ami-123456"
+  instance_type = "t2.micro"
+}
+
+resource "aws_autoscaling_group" "batch" {
+  min_size             = 0
+  max_size             = 1
+  vpc_zone_identifier  = [aws_subnet.b.id]
+  launch_configuration = aws_launch_configuration.batch.name
+}
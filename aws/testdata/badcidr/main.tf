@@ -0,0 +1,39 @@
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+# Invalid CIDR: the subnet is drawn but can't be matched with the rules
+resource "aws_subnet" "bad" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.1.0/33"
+}
+
+resource "aws_subnet" "app" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.2.0/24"
+}
+
+resource "aws_security_group" "web" {
+  name   = "web"
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = ["10.0.2.0/24"]
+  }
+}
+
+resource "aws_instance" "web" {
+  ami                    = "ami-123456"
+  instance_type          = "t2.micro"
+  subnet_id              = aws_subnet.app.id
+  vpc_security_group_ids = [aws_security_group.web.id]
+}
+
+resource "aws_lb" "front" {
+  name            = "front"
+  subnets         = [aws_subnet.app.id]
+  security_groups = [aws_security_group.web.id]
+}
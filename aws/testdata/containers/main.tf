@@ -0,0 +1,92 @@
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "a" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.1.0/24"
+}
+
+resource "aws_subnet" "b" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.2.0/24"
+}
+
+resource "aws_security_group" "app" {
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 8080
+    to_port     = 8080
+    protocol    = "tcp"
+    cidr_blocks = ["10.0.0.0/16"]
+  }
+}
+
+resource "aws_security_group" "bastion" {
+  vpc_id = aws_vpc.main.id
+}
+
+resource "aws_instance" "bastion" {
+  ami                    = "ami-123456"
+  instance_type          = "t2.micro"
+  subnet_id              = aws_subnet.a.id
+  vpc_security_group_ids = [aws_security_group.bastion.id]
+}
+
+resource "aws_ecs_cluster" "main" {
+  name = "main"
+}
+
+resource "aws_ecs_task_definition" "app" {
+  family                = "app"
+  network_mode          = "awsvpc"
+  container_definitions = jsonencode([
+    {
+      name         = "web"
+      image        = "nginx"
+      portMappings = [{ containerPort = 8080 }]
+    },
+    {
+      name  = "sidecar"
+      image = "envoy"
+    },
+  ])
+}
+
+resource "aws_ecs_service" "app" {
+  name            = "app"
+  cluster         = aws_ecs_cluster.main.id
+  task_definition = aws_ecs_task_definition.app.arn
+
+  network_configuration {
+    subnets         = [aws_subnet.a.id]
+    security_groups = [aws_security_group.app.id]
+  }
+}
+
+resource "aws_eks_cluster" "k8s" {
+  name     = "k8s"
+  role_arn = "arn:aws:iam::123456789012:role/eks"
+
+  vpc_config {
+    subnet_ids = [aws_subnet.b.id]
+  }
+}
+
+resource "aws_eks_node_group" "workers" {
+  cluster_name    = aws_eks_cluster.k8s.name
+  node_group_name = "workers"
+  node_role_arn   = "arn:aws:iam::123456789012:role/node"
+  subnet_ids      = [aws_subnet.a.id]
+
+  scaling_config {
+    desired_size = 2
+    min_size     = 1
+    max_size     = 3
+  }
+
+  remote_access {
+    source_security_group_ids = [aws_security_group.bastion.id]
+  }
+}
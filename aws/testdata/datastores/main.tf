@@ -0,0 +1,63 @@
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "a" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.1.0/24"
+}
+
+resource "aws_db_subnet_group" "main" {
+  name       = "main"
+  subnet_ids = [aws_subnet.a.id]
+}
+
+resource "aws_elasticache_subnet_group" "main" {
+  name       = "cache"
+  subnet_ids = [aws_subnet.a.id]
+}
+
+resource "aws_security_group" "data" {
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 6379
+    to_port     = 6379
+    protocol    = "tcp"
+    cidr_blocks = ["10.0.0.0/16"]
+  }
+}
+
+resource "aws_rds_cluster" "aurora" {
+  cluster_identifier     = "aurora"
+  engine                 = "aurora-postgresql"
+  db_subnet_group_name   = aws_db_subnet_group.main.name
+  vpc_security_group_ids = [aws_security_group.data.id]
+}
+
+resource "aws_rds_cluster_instance" "aurora" {
+  count               = 2
+  cluster_identifier  = aws_rds_cluster.aurora.id
+  instance_class      = "db.r5.large"
+  engine              = "aurora-postgresql"
+  publicly_accessible = count.index == 1
+}
+
+resource "aws_elasticache_replication_group" "redis" {
+  replication_group_id = "redis"
+  description          = "redis"
+  engine               = "redis"
+  subnet_group_name    = aws_elasticache_subnet_group.main.name
+  security_group_ids   = [aws_security_group.data.id]
+}
+
+# Member of the replication group, drawn with its group
+resource "aws_elasticache_cluster" "replica" {
+  cluster_id           = "replica"
+  replication_group_id = aws_elasticache_replication_group.redis.id
+}
+
+# Public endpoint
+resource "aws_opensearch_domain" "search" {
+  domain_name = "search"
+}
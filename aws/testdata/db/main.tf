@@ -0,0 +1,54 @@
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "a" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.1.0/24"
+}
+
+resource "aws_subnet" "b" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.2.0/24"
+}
+
+resource "aws_db_subnet_group" "main" {
+  name       = "main"
+  subnet_ids = [aws_subnet.a.id, aws_subnet.b.id]
+}
+
+resource "aws_security_group" "db" {
+  vpc_id = aws_vpc.main.id
+
+  ingress {
+    from_port   = 5432
+    to_port     = 5432
+    protocol    = "tcp"
+    cidr_blocks = ["10.0.0.0/16"]
+  }
+}
+
+# DB subnet group referenced by its name
+resource "aws_db_instance" "main" {
+  identifier             = "main"
+  engine                 = "postgres"
+  instance_class         = "db.t3.micro"
+  db_subnet_group_name   = "main"
+  vpc_security_group_ids = [aws_security_group.db.id]
+}
+
+# DB subnet group referenced by its address
+resource "aws_db_instance" "replica" {
+  identifier           = "replica"
+  engine               = "postgres"
+  instance_class       = "db.t3.micro"
+  db_subnet_group_name = aws_db_subnet_group.main.name
+}
+
+# Unknown DB subnet group
+resource "aws_db_instance" "other" {
+  identifier           = "other"
+  engine               = "postgres"
+  instance_class       = "db.t3.micro"
+  db_subnet_group_name = "other"
+}
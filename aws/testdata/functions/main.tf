@@ -0,0 +1,20 @@
+variable "zones" {
+  default = ["a", "b"]
+}
+
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "zone" {
+  for_each   = toset(var.zones)
+  vpc_id     = aws_vpc.main.id
+  cidr_block = cidrsubnet("10.0.0.0/16", 8, index(var.zones, each.key))
+}
+
+resource "aws_instance" "web" {
+  count         = length(var.zones)
+  ami           = "ami-123456"
+  instance_type = "t2.micro"
+  subnet_id     = aws_subnet.zone[element(var.zones, count.index)].id
+}
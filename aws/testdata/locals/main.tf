@@ -0,0 +1,16 @@
+locals {
+  # Locals can reference locals declared after them
+  subnet_count = local.zone_count
+  zone_count   = 2
+  vpc_id       = aws_vpc.main.id
+}
+
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_subnet" "private" {
+  count      = local.subnet_count
+  vpc_id     = local.vpc_id
+  cidr_block = "10.0.${count.index}.0/24"
+}
@@ -0,0 +1,10 @@
+module "network" {
+  source = "./network"
+  cidr   = "10.0.0.0/16"
+}
+
+resource "aws_instance" "web" {
+  ami           = "ami-123456"
+  instance_type = "t2.micro"
+  subnet_id     = module.network.subnet_id
+}
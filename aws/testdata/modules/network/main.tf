@@ -0,0 +1,14 @@
+variable "cidr" {}
+
+resource "aws_vpc" "main" {
+  cidr_block = var.cidr
+}
+
+resource "aws_subnet" "public" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.1.0/24"
+}
+
+output "subnet_id" {
+  value = aws_subnet.public.id
+}
@@ -0,0 +1,89 @@
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_vpc" "shared" {
+  cidr_block = "10.1.0.0/16"
+}
+
+resource "aws_subnet" "app" {
+  vpc_id     = aws_vpc.main.id
+  cidr_block = "10.0.1.0/24"
+}
+
+resource "aws_vpc_peering_connection" "shared" {
+  vpc_id      = aws_vpc.main.id
+  peer_vpc_id = aws_vpc.shared.id
+}
+
+# Peer VPC not defined in TF
+resource "aws_vpc_peering_connection" "partner" {
+  vpc_id        = aws_vpc.main.id
+  peer_vpc_id   = "vpc-0a1b2c3d"
+  peer_owner_id = "123456789012"
+}
+
+resource "aws_ec2_transit_gateway" "tgw" {
+}
+
+resource "aws_customer_gateway" "office" {
+  bgp_asn    = 65000
+  ip_address = "203.0.113.1"
+  type       = "ipsec.1"
+}
+
+resource "aws_vpn_connection" "office" {
+  customer_gateway_id = aws_customer_gateway.office.id
+  transit_gateway_id  = aws_ec2_transit_gateway.tgw.id
+  type                = "ipsec.1"
+}
+
+resource "aws_ec2_transit_gateway_route" "office" {
+  destination_cidr_block         = "192.168.0.0/16"
+  transit_gateway_attachment_id  = aws_vpn_connection.office.transit_gateway_attachment_id
+  transit_gateway_route_table_id = aws_ec2_transit_gateway.tgw.association_default_route_table_id
+}
+
+resource "aws_route_table" "app" {
+  vpc_id = aws_vpc.main.id
+
+  route {
+    cidr_block                = "10.1.0.0/16"
+    vpc_peering_connection_id = aws_vpc_peering_connection.shared.id
+  }
+
+  route {
+    cidr_block         = "192.168.0.0/16"
+    transit_gateway_id = aws_ec2_transit_gateway.tgw.id
+  }
+}
+
+resource "aws_route_table_association" "app" {
+  subnet_id      = aws_subnet.app.id
+  route_table_id = aws_route_table.app.id
+}
+
+resource "aws_security_group" "app" {
+  vpc_id = aws_vpc.main.id
+
+  egress {
+    from_port   = 5432
+    to_port     = 5432
+    protocol    = "tcp"
+    cidr_blocks = ["10.1.2.0/24"]
+  }
+
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = ["192.168.1.0/24"]
+  }
+}
+
+resource "aws_instance" "app" {
+  ami                    = "ami-123456"
+  instance_type          = "t2.micro"
+  subnet_id              = aws_subnet.app.id
+  vpc_security_group_ids = [aws_security_group.app.id]
+}
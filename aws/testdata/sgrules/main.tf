@@ -0,0 +1,40 @@
+resource "aws_security_group" "web" {
+  name = "web"
+}
+
+resource "aws_security_group_rule" "ssh" {
+  type              = "ingress"
+  from_port         = 22
+  to_port           = 22
+  protocol          = "tcp"
+  cidr_blocks       = ["0.0.0.0/0"]
+  security_group_id = aws_security_group.web.id
+}
+
+resource "aws_vpc_security_group_egress_rule" "all" {
+  security_group_id = aws_security_group.web.id
+  ip_protocol       = "-1"
+  cidr_ipv4         = "0.0.0.0/0"
+}
+
+# Rule of a security group not defined in Terraform
+resource "aws_security_group_rule" "https" {
+  type              = "ingress"
+  from_port         = 443
+  to_port           = 443
+  protocol          = "tcp"
+  cidr_blocks       = ["0.0.0.0/0"]
+  security_group_id = "sg-0a1b2c3d"
+}
+
+resource "aws_instance" "web" {
+  ami                    = "ami-123456"
+  instance_type          = "t2.micro"
+  vpc_security_group_ids = [aws_security_group.web.id]
+}
+
+resource "aws_instance" "legacy" {
+  ami                    = "ami-123456"
+  instance_type          = "t2.micro"
+  vpc_security_group_ids = ["sg-0a1b2c3d"]
+}
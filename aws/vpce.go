@@ -0,0 +1,287 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// Defining values for the types of VPC endpoints
+const (
+	gatewayEndpoint = "Gateway"
+	interfaceEndpoint = "Interface"
+)
+
+// vpcEndpointType returns the type of a VPC endpoint (Gateway if not set)
+func vpcEndpointType(endpoint VpcEndpoint) string {
+	if endpoint.VpcEndpointType == nil || *endpoint.VpcEndpointType == "" {
+		return gatewayEndpoint
+	}
+	return *endpoint.VpcEndpointType
+}
+
+// appendOptionalID appends an optional ID to an optional list of IDs
+func appendOptionalID(ids *[]string, id *string) *[]string {
+	if id == nil || *id == "" {
+		return ids
+	}
+	var list []string
+	if ids != nil {
+		list = append(list, *ids...)
+	}
+	list = append(list, *id)
+	return &list
+}
+
+// linkVpcEndpoints merges the route table, subnet and SG associations (declared as standalone resources) in
+// their VPC endpoint and links the endpoints to their SGs. It must be called once all resources are parsed
+func (a *Data) linkVpcEndpoints() {
+	for associationAddress, association := range a.VpcEndpointAssociation {
+		endpoint, found := a.VpcEndpoint[association.VpcEndpointID]
+		if !found {
+			a.log.Error(fmt.Errorf("%s: unknown VPC endpoint %s, the association is ignored", associationAddress, association.VpcEndpointID))
+			continue
+		}
+		a.log.Verbosef("Merging %s in %s", associationAddress, association.VpcEndpointID)
+		endpoint.RouteTableIDs = appendOptionalID(endpoint.RouteTableIDs, association.RouteTableID)
+		endpoint.SubnetIDs = appendOptionalID(endpoint.SubnetIDs, association.SubnetID)
+		endpoint.SecurityGroupIDs = appendOptionalID(endpoint.SecurityGroupIDs, association.SecurityGroupID)
+		a.VpcEndpoint[association.VpcEndpointID] = endpoint
+	}
+
+	for endpointAddress, endpoint := range a.VpcEndpoint {
+		if vpcEndpointType(endpoint) == interfaceEndpoint {
+			a.linkSecurityGroups(endpointAddress, endpoint.SecurityGroupIDs)
+		}
+	}
+}
+
+// vpcEndpointServiceName returns the name of the AWS service of a VPC endpoint (e.g. s3 for
+// com.amazonaws.us-east-1.s3), or its full service name for the other services
+func vpcEndpointServiceName(endpoint VpcEndpoint) string {
+	// com.amazonaws.<region>.<service>, services hosted in endpoint services being com.amazonaws.vpce.<region>.<id>
+	parts := strings.SplitN(endpoint.ServiceName, ".", 4)
+	if len(parts) == 4 && parts[0] == "com" && parts[1] == "amazonaws" && parts[2] != "vpce" {
+		return parts[3]
+	}
+	return endpoint.ServiceName
+}
+
+// vpcEndpointLabel formats the label of a VPC endpoint node: its name, its service and its type
+func vpcEndpointLabel(endpointAddress string, endpoint VpcEndpoint) string {
+	detail := strings.ToLower(vpcEndpointType(endpoint))
+	if service := vpcEndpointServiceName(endpoint); service != endpoint.ServiceName {
+		detail = service + " " + detail
+	}
+	return dataLabel(endpointAddress, nil, detail)
+}
+
+// vpcEndpointParent returns the group of the VPC of a VPC endpoint, or of its module if the VPC is unknown
+func (a *Data) vpcEndpointParent(endpointAddress string, endpoint VpcEndpoint) string {
+	if _, found := a.Vpc[endpoint.VpcID]; found {
+		return utils.NodeID(endpoint.VpcID)
+	}
+	modulePath, _, _ := utils.SplitAddress(endpointAddress)
+	return a.moduleGroup(modulePath)
+}
+
+// gatewayEndpointSubnets returns the subnets using a Gateway endpoint (through their route table)
+func (a *Data) gatewayEndpointSubnets(endpoint VpcEndpoint) []string {
+	if endpoint.RouteTableIDs == nil {
+		return nil
+	}
+	var subnets []string
+	for subnetAddress := range a.Subnet {
+		routeTable := a.subnetRouteTable(subnetAddress)
+		for _, routeTableID := range *endpoint.RouteTableIDs {
+			if routeTable != "" && a.routeTableKey(routeTableID) == routeTable {
+				subnets = append(subnets, subnetAddress)
+			}
+		}
+	}
+	sort.Strings(subnets)
+	return subnets
+}
+
+// subnetGatewayEndpoints returns the Gateway endpoints used by a subnet
+func (a *Data) subnetGatewayEndpoints(subnetAddress string) []string {
+	var endpoints []string
+	for endpointAddress, endpoint := range a.VpcEndpoint {
+		if vpcEndpointType(endpoint) != gatewayEndpoint {
+			continue
+		}
+		if _, found := utils.Find(a.gatewayEndpointSubnets(endpoint), subnetAddress); found {
+			endpoints = append(endpoints, endpointAddress)
+		}
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// vpcEndpointDestinations returns the nodes reached through a VPC endpoint: its endpoint service, the S3
+// buckets for S3 endpoints, or a node created for the AWS service (e.g. dynamodb.amazonaws.com) otherwise
+func (a *Data) vpcEndpointDestinations(graph *model.Graph, endpoint VpcEndpoint) ([]string, error) {
+	serviceAddress := referencedResource(endpoint.ServiceName)
+	if _, found := a.VpcEndpointService[serviceAddress]; found {
+		return []string{utils.NodeID(serviceAddress)}, nil
+	}
+
+	service := vpcEndpointServiceName(endpoint)
+	if service == "s3" && len(a.S3) > 0 {
+		var buckets []string
+		for bucketAddress := range a.S3 {
+			buckets = append(buckets, utils.NodeID(bucketAddress))
+		}
+		sort.Strings(buckets)
+		return buckets, nil
+	}
+
+	// Node of the AWS service (shared with the Lambda triggers of the service), or of the endpoint service not
+	// defined in TF
+	label := strings.Join(utils.ChunkString(service, 12), "\n")
+	address := service
+	if service != endpoint.ServiceName {
+		address = service + ".amazonaws.com"
+		label = strings.Join(utils.ChunkString(address, 12), "\n")
+	} else {
+		label += "\n(endpoint service)"
+	}
+	if !graph.IsNode(utils.NodeID(address)) {
+		a.log.Verbosef("AddNode: %s // Create AWS service", utils.NodeID(address))
+		err := graph.AddNode(&model.Node{
+			ID:		utils.NodeID(address),
+			Kind:	model.ServiceNode,
+			Label:	label,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []string{utils.NodeID(address)}, nil
+}
+
+// addVpcEndpointEdge adds an edge for the traffic going through a VPC endpoint to the graph
+func (a *Data) addVpcEndpointEdge(graph *model.Graph, src string, dst string, label string) (error) {
+	a.log.Verbosef("AddEdge: %s -> %s", src, dst)
+	graph.AddEdge(&model.Edge{
+		Src:	src,
+		Dst:	dst,
+		Kind:	model.EndpointEdge,
+		Label:	label,
+	})
+	return nil
+}
+
+// createVpcEndpoints creates the nodes of the VPC endpoint services and of the VPC endpoints: Gateway endpoints
+// are drawn in their VPC, Interface and Gateway Load Balancer endpoints in their subnets
+func (a *Data) createVpcEndpoints(graph *model.Graph) (error) {
+	for serviceAddress, service := range a.VpcEndpointService {
+		modulePath, _, _ := utils.SplitAddress(serviceAddress)
+		detail := "endpoint service"
+		if service.AcceptanceRequired {
+			detail = "endpoint service, acceptance required"
+		}
+		a.log.Verbosef("AddNode: %s to %s // Create VPC endpoint service", utils.NodeID(serviceAddress), a.moduleGroup(modulePath))
+		node := iconNode(serviceAddress, dataLabel(serviceAddress, nil, detail), "vpce.png")
+		node.Parent = a.moduleGroup(modulePath)
+		err := graph.AddNode(node)
+		if err != nil {
+			return err
+		}
+	}
+
+	for endpointAddress, endpoint := range a.VpcEndpoint {
+		node := iconNode(endpointAddress, vpcEndpointLabel(endpointAddress, endpoint), "vpce.png")
+		parent := a.vpcEndpointParent(endpointAddress, endpoint)
+		if vpcEndpointType(endpoint) == gatewayEndpoint {
+			a.log.Verbosef("AddNode: %s to %s // Create VPC endpoint", utils.NodeID(endpointAddress), parent)
+			node.Parent = parent
+			err := graph.AddNode(node)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		var subnets []string
+		if endpoint.SubnetIDs != nil {
+			subnets = *endpoint.SubnetIDs
+		}
+		a.log.Verbosef("Create VPC endpoint %s in %d subnet(s)", endpointAddress, len(subnets))
+		err := a.createSubnetNodes(graph, node, subnets, parent)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createVpcEndpointEdges creates the edges of the VPC endpoints: SG rules of the Interface endpoints, subnets to
+// the Gateway endpoints of their route table, endpoints to the services / buckets they reach, and endpoint
+// services to their load balancers
+func (a *Data) createVpcEndpointEdges(graph *model.Graph) (error) {
+	for endpointAddress, endpoint := range a.VpcEndpoint {
+		switch vpcEndpointType(endpoint) {
+		case gatewayEndpoint:
+			for _, subnet := range a.gatewayEndpointSubnets(endpoint) {
+				err := a.addVpcEndpointEdge(graph, utils.NodeID(subnet), utils.NodeID(endpointAddress), vpcEndpointServiceName(endpoint))
+				if err != nil {
+					return err
+				}
+			}
+		case interfaceEndpoint:
+			var SGs []string
+			if endpoint.SecurityGroupIDs != nil {
+				SGs = *endpoint.SecurityGroupIDs
+			}
+			if len(SGs) == 0 {
+				// Interface endpoints created without SG use the default SG of their VPC
+				for _, nodeName := range a.graphNodes(endpointAddress) {
+					err := a.linkDefaultSecurityGroup(graph, nodeName)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			a.parseSGRules(endpointAddress, SGs, graph)
+		}
+
+		destinations, err := a.vpcEndpointDestinations(graph, endpoint)
+		if err != nil {
+			return err
+		}
+		for _, src := range a.graphNodes(endpointAddress) {
+			for _, dst := range destinations {
+				err := a.addVpcEndpointEdge(graph, src, dst, "")
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for serviceAddress, service := range a.VpcEndpointService {
+		var lbs []string
+		for _, list := range []*[]string{service.NetworkLoadBalancerArns, service.GatewayLoadBalancerArns} {
+			if list != nil {
+				lbs = append(lbs, *list...)
+			}
+		}
+		for _, lb := range lbs {
+			if _, found := a.LB[lb]; !found {
+				continue
+			}
+			for _, lbNode := range a.graphNodes(lb) {
+				err := a.addVpcEndpointEdge(graph, utils.NodeID(serviceAddress), lbNode, "")
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
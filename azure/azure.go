@@ -0,0 +1,789 @@
+package azure
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/dynblock"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/provider"
+	"github.com/steeve85/tfviz/utils"
+)
+
+
+// referencedAttributes lists the computed attributes (other than id) used to reference Azure resources.
+// They are set to "<resource address>.<attribute>" in the EvalContext
+var referencedAttributes = map[string][]string{
+	"azurerm_resource_group":	{"name", "location"},
+	"azurerm_virtual_network":	{"name"},
+	"azurerm_subnet":	{"name"},
+	"azurerm_network_security_group":	{"name"},
+	"azurerm_application_security_group":	{"name"},
+	"azurerm_network_interface":	{"name", "private_ip_address"},
+	"azurerm_public_ip":	{"name", "ip_address"},
+	"azurerm_lb":	{"name"},
+	"azurerm_lb_backend_address_pool":	{"name"},
+}
+
+// Defining values for the direction of NSG rules
+const inboundRule = "Inbound"
+const outboundRule = "Outbound"
+
+// moduleGroup returns the group in which the top level resources of a module are drawn ("" for the top level)
+func (a *Data) moduleGroup(modulePath string) string {
+	if !a.options.ModuleClusters || modulePath == "" {
+		return ""
+	}
+	return utils.NodeID(modulePath)
+}
+
+// referencedResource returns the address of the resource referenced by one of its referencedAttributes
+// (e.g. azurerm_virtual_network.main.name => azurerm_virtual_network.main)
+func referencedResource(reference string) string {
+	return utils.ReferencedResource(reference, referencedAttributes)
+}
+
+// Data is a structure that contain maps of TF parsed Azure resources
+type Data struct {
+	VirtualNetwork			map[string]VirtualNetwork
+	Subnet					map[string]Subnet
+	NetworkSecurityGroup	map[string]NetworkSecurityGroup
+	ApplicationSecurityGroup	map[string]ApplicationSecurityGroup
+	NetworkInterface		map[string]NetworkInterface
+	// Linux, Windows and legacy virtual machines
+	VirtualMachine			map[string]VirtualMachine
+	PublicIP				map[string]PublicIP
+	LB						map[string]LB
+	LBBackendAddressPool	map[string]LBBackendAddressPool
+	LBRule					map[string]LBRule
+	// Associations of NSGs to subnets / network interfaces, and of network interfaces to ASGs / LB backend pools
+	Association				map[string]Association
+	standaloneNSGRules		[]standaloneNSGRule
+	nsgEdges				[]*model.Edge
+	nsgEdgesIndex			map[string]*model.Edge
+	// options of the graph (ingress / egress rules, module clusters)
+	options					provider.Options
+	// log collects the diagnostics of the graph
+	log						*utils.Logger
+}
+
+// VirtualNetwork is a structure for Azure virtual network resources
+type VirtualNetwork struct {
+	// The name of the virtual network
+	Name					string `hcl:"name"`
+	// The address space that is used the virtual network
+	AddressSpace			[]string `hcl:"address_space"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// Subnet is a structure for Azure subnet resources
+type Subnet struct {
+	// The name of the subnet
+	Name					string `hcl:"name"`
+	// The name of the virtual network to which to attach the subnet
+	VirtualNetworkName		string `hcl:"virtual_network_name"`
+	// The address prefixes to use for the subnet
+	AddressPrefixes			*[]string `hcl:"address_prefixes"`
+	// The address prefix to use for the subnet (azurerm < 3.0)
+	AddressPrefix			*string `hcl:"address_prefix"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// NetworkSecurityGroup is a structure for Azure network security group resources
+type NetworkSecurityGroup struct {
+	// The name of the network security group
+	Name					string `hcl:"name"`
+	// List of security_rule objects
+	SecurityRules			[]NSGRule `hcl:"security_rule,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// NSGRule is a structure for Azure network security rules (security_rule blocks and azurerm_network_security_rule
+// resources)
+type NSGRule struct {
+	// The name of the security rule
+	Name					string `hcl:"name"`
+	// Rules are processed in priority order (100 to 4096), the lower the number, the higher the priority
+	Priority				int `hcl:"priority"`
+	// The direction specifies if rule will be evaluated on incoming or outgoing traffic: Inbound or Outbound
+	Direction				string `hcl:"direction"`
+	// Specifies whether network traffic is allowed or denied: Allow or Deny
+	Access					string `hcl:"access"`
+	// Network protocol this rule applies to: Tcp, Udp, Icmp, Esp, Ah or * (any)
+	Protocol				string `hcl:"protocol"`
+	// Source Port or Range
+	SourcePortRange			*string `hcl:"source_port_range"`
+	// List of source ports or port ranges
+	SourcePortRanges		*[]string `hcl:"source_port_ranges"`
+	// Destination Port or Range
+	DestinationPortRange	*string `hcl:"destination_port_range"`
+	// List of destination ports or port ranges
+	DestinationPortRanges	*[]string `hcl:"destination_port_ranges"`
+	// CIDR or source IP range or * to match any IP. Tags such as VirtualNetwork, AzureLoadBalancer and
+	// Internet can also be used
+	SourceAddressPrefix		*string `hcl:"source_address_prefix"`
+	// List of source address prefixes. Tags may not be used
+	SourceAddressPrefixes	*[]string `hcl:"source_address_prefixes"`
+	// A List of source Application Security Group IDs
+	SourceApplicationSecurityGroupIDs	*[]string `hcl:"source_application_security_group_ids"`
+	// CIDR or destination IP range or * to match any IP. Tags such as VirtualNetwork, AzureLoadBalancer and
+	// Internet can also be used
+	DestinationAddressPrefix	*string `hcl:"destination_address_prefix"`
+	// List of destination address prefixes. Tags may not be used
+	DestinationAddressPrefixes	*[]string `hcl:"destination_address_prefixes"`
+	// A List of destination Application Security Group IDs
+	DestinationApplicationSecurityGroupIDs	*[]string `hcl:"destination_application_security_group_ids"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// NetworkSecurityRule is a structure for Azure network security rule resources
+type NetworkSecurityRule struct {
+	// The name of the Network Security Group that we want to attach the rule to
+	NetworkSecurityGroupName	string `hcl:"network_security_group_name"`
+	// The rule itself (other arguments)
+	Rule					NSGRule `hcl:",remain"`
+}
+
+// standaloneNSGRule is a NSG rule declared as a resource (azurerm_network_security_rule).
+// It is merged in its NSG once all resources are parsed
+type standaloneNSGRule struct {
+	// Address of the rule resource
+	Address					string
+	// Name of the NSG of the rule
+	NetworkSecurityGroupName	string
+	// The rule
+	Rule					NSGRule
+}
+
+// ApplicationSecurityGroup is a structure for Azure application security group resources
+type ApplicationSecurityGroup struct {
+	// The name of the Application Security Group
+	Name					string `hcl:"name"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// NetworkInterface is a structure for Azure network interface resources
+type NetworkInterface struct {
+	// The name of the Network Interface
+	Name					string `hcl:"name"`
+	// One or more ip_configuration blocks
+	IPConfigurations		[]IPConfiguration `hcl:"ip_configuration,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// IPConfiguration is a structure for Azure network interface ip_configuration blocks
+type IPConfiguration struct {
+	// A name used for this IP Configuration
+	Name					string `hcl:"name"`
+	// The ID of the Subnet where this Network Interface should be located in
+	SubnetID				*string `hcl:"subnet_id"`
+	// The allocation method used for the Private IP Address: Dynamic or Static
+	PrivateIPAddressAllocation	*string `hcl:"private_ip_address_allocation"`
+	// The Static IP Address which should be used
+	PrivateIPAddress		*string `hcl:"private_ip_address"`
+	// Reference to a Public IP Address to associate with this NIC
+	PublicIPAddressID		*string `hcl:"public_ip_address_id"`
+	// Is this the Primary IP Configuration?
+	Primary					*bool `hcl:"primary"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// VirtualMachine is a structure for Azure Linux, Windows and legacy virtual machine resources
+type VirtualMachine struct {
+	// The name of the Virtual Machine
+	Name					string `hcl:"name"`
+	// The SKU which should be used for this Virtual Machine (Linux and Windows virtual machines)
+	Size					*string `hcl:"size"`
+	// Specifies the size of the Virtual Machine (legacy virtual machines)
+	VMSize					*string `hcl:"vm_size"`
+	// A list of Network Interface IDs which should be attached to this Virtual Machine
+	NetworkInterfaceIDs		[]string `hcl:"network_interface_ids"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// PublicIP is a structure for Azure public IP resources
+type PublicIP struct {
+	// Specifies the name of the Public IP
+	Name					string `hcl:"name"`
+	// Defines the allocation method for this IP address: Static or Dynamic
+	AllocationMethod		string `hcl:"allocation_method"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LB is a structure for Azure load balancer resources
+type LB struct {
+	// Specifies the name of the Load Balancer
+	Name					string `hcl:"name"`
+	// The SKU of the Azure Load Balancer: Basic, Standard or Gateway
+	Sku						*string `hcl:"sku"`
+	// One or multiple frontend_ip_configuration blocks
+	FrontendIPConfigurations	[]LBFrontendIPConfiguration `hcl:"frontend_ip_configuration,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBFrontendIPConfiguration is a structure for Azure load balancer frontend_ip_configuration blocks
+type LBFrontendIPConfiguration struct {
+	// Specifies the name of the frontend IP configuration
+	Name					string `hcl:"name"`
+	// The ID of the Subnet which should be associated with the IP Configuration (internal load balancers)
+	SubnetID				*string `hcl:"subnet_id"`
+	// Private IP Address to assign to the Load Balancer
+	PrivateIPAddress		*string `hcl:"private_ip_address"`
+	// The ID of a Public IP Address which should be associated with the Load Balancer
+	PublicIPAddressID		*string `hcl:"public_ip_address_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBBackendAddressPool is a structure for Azure load balancer backend address pool resources
+type LBBackendAddressPool struct {
+	// Specifies the name of the Backend Address Pool
+	Name					string `hcl:"name"`
+	// The ID of the Load Balancer in which to create the Backend Address Pool
+	LoadbalancerID			string `hcl:"loadbalancer_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// LBRule is a structure for Azure load balancer rule resources
+type LBRule struct {
+	// Specifies the name of the LB Rule
+	Name					string `hcl:"name"`
+	// The ID of the Load Balancer in which to create the Rule
+	LoadbalancerID			string `hcl:"loadbalancer_id"`
+	// The transport protocol for the external endpoint: Tcp, Udp or All
+	Protocol				string `hcl:"protocol"`
+	// The port for the external endpoint
+	FrontendPort			int `hcl:"frontend_port"`
+	// The port used for internal connections on the endpoint
+	BackendPort				int `hcl:"backend_port"`
+	// A list of reference to a Backend Address Pool over which this Load Balancing Rule operates
+	BackendAddressPoolIDs	*[]string `hcl:"backend_address_pool_ids"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// Association is a structure for Azure association resources: NSGs associated to subnets / network interfaces
+// (azurerm_subnet_network_security_group_association, azurerm_network_interface_security_group_association),
+// network interfaces associated to ASGs (azurerm_network_interface_application_security_group_association) and
+// to LB backend pools (azurerm_network_interface_backend_address_pool_association)
+type Association struct {
+	// The ID of the Subnet
+	SubnetID				*string `hcl:"subnet_id"`
+	// The ID of the Network Interface
+	NetworkInterfaceID		*string `hcl:"network_interface_id"`
+	// The ID of the Network Security Group
+	NetworkSecurityGroupID	*string `hcl:"network_security_group_id"`
+	// The ID of the Application Security Group
+	ApplicationSecurityGroupID	*string `hcl:"application_security_group_id"`
+	// The ID of the Load Balancer Backend Address Pool
+	BackendAddressPoolID	*string `hcl:"backend_address_pool_id"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// DecodeResource decodes a single instance of a TF resource and adds it to Data.
+// Resources are indexed by their address (e.g. module.network.azurerm_subnet.private[0])
+func (a *Data) DecodeResource(r provider.Resource) bool {
+	return a.parseTfResource(r.Type, r.Address, r.Body, r.Ctx)
+}
+
+// resourceHandler decodes the resources of a TF type
+type resourceHandler struct {
+	// value is an empty structure of the type the resources are decoded in (e.g. Vpc{})
+	value		interface{}
+	// add adds a decoded resource to Data
+	add			func(a *Data, resourceType string, address string, value interface{})
+}
+
+// resourceHandlers are the handlers of the supported resource types, indexed by resource type
+var resourceHandlers = map[string]resourceHandler{
+	"azurerm_virtual_network":	{VirtualNetwork{}, (*Data).addVirtualNetwork},
+	"azurerm_subnet":	{Subnet{}, (*Data).addSubnet},
+	"azurerm_network_security_group":	{NetworkSecurityGroup{}, (*Data).addNetworkSecurityGroup},
+	"azurerm_network_security_rule":	{NetworkSecurityRule{}, (*Data).addNetworkSecurityRule},
+	"azurerm_application_security_group":	{ApplicationSecurityGroup{}, (*Data).addApplicationSecurityGroup},
+	"azurerm_network_interface":	{NetworkInterface{}, (*Data).addNetworkInterface},
+	"azurerm_linux_virtual_machine":	{VirtualMachine{}, (*Data).addVirtualMachine},
+	"azurerm_windows_virtual_machine":	{VirtualMachine{}, (*Data).addVirtualMachine},
+	"azurerm_virtual_machine":	{VirtualMachine{}, (*Data).addVirtualMachine},
+	"azurerm_public_ip":	{PublicIP{}, (*Data).addPublicIP},
+	"azurerm_lb":	{LB{}, (*Data).addLB},
+	"azurerm_lb_backend_address_pool":	{LBBackendAddressPool{}, (*Data).addLBBackendAddressPool},
+	"azurerm_lb_rule":	{LBRule{}, (*Data).addLBRule},
+	"azurerm_subnet_network_security_group_association":	{Association{}, (*Data).addAssociation},
+	"azurerm_network_interface_security_group_association":	{Association{}, (*Data).addAssociation},
+	"azurerm_network_interface_application_security_group_association":	{Association{}, (*Data).addAssociation},
+	"azurerm_network_interface_backend_address_pool_association":	{Association{}, (*Data).addAssociation},
+}
+
+// parseTfResource decodes a resource and returns false if its type is not supported.
+// body can come from HCL files (with ctx used for interpolation) or from JSON plans / states
+func (a *Data) parseTfResource(resourceType string, address string, body hcl2.Body, ctx *hcl2.EvalContext) bool {
+	handler, found := resourceHandlers[resourceType]
+	if !found {
+		a.log.Verbosef("Can't decode %s (not yet supported)", address)
+		return false
+	}
+	if ctx != nil {
+		// Expanding dynamic blocks (e.g. dynamic "security_rule") so they are decoded like literal blocks
+		body = dynblock.Expand(body, ctx)
+	}
+
+	a.log.Verbosef("Decoding %s", address)
+	value, diags := utils.DecodeResource(body, ctx, handler.value)
+	a.log.Diags(diags)
+	handler.add(a, resourceType, address, value)
+	return true
+}
+
+// addVirtualNetwork adds a decoded azurerm_virtual_network to Data
+func (a *Data) addVirtualNetwork(resourceType string, address string, value interface{}) {
+	azureVirtualNetwork := value.(VirtualNetwork)
+
+	// Add VirtualNetwork to Data
+	a.VirtualNetwork[address] = azureVirtualNetwork
+}
+
+// addSubnet adds a decoded azurerm_subnet to Data
+func (a *Data) addSubnet(resourceType string, address string, value interface{}) {
+	azureSubnet := value.(Subnet)
+
+	// Add Subnet to Data
+	a.Subnet[address] = azureSubnet
+}
+
+// addNetworkSecurityGroup adds a decoded azurerm_network_security_group to Data
+func (a *Data) addNetworkSecurityGroup(resourceType string, address string, value interface{}) {
+	azureNetworkSecurityGroup := value.(NetworkSecurityGroup)
+
+	// Add NetworkSecurityGroup to Data (rules already added by azurerm_network_security_rule resources are kept)
+	if nsg, found := a.NetworkSecurityGroup[address]; found {
+		azureNetworkSecurityGroup.SecurityRules = append(azureNetworkSecurityGroup.SecurityRules, nsg.SecurityRules...)
+	}
+	a.NetworkSecurityGroup[address] = azureNetworkSecurityGroup
+}
+
+// addNetworkSecurityRule adds a decoded azurerm_network_security_rule to Data
+func (a *Data) addNetworkSecurityRule(resourceType string, address string, value interface{}) {
+	azureNSGRule := value.(NetworkSecurityRule)
+
+	// The rule is merged in its NSG once all resources are parsed
+	a.standaloneNSGRules = append(a.standaloneNSGRules, standaloneNSGRule{address, azureNSGRule.NetworkSecurityGroupName, azureNSGRule.Rule})
+}
+
+// addApplicationSecurityGroup adds a decoded azurerm_application_security_group to Data
+func (a *Data) addApplicationSecurityGroup(resourceType string, address string, value interface{}) {
+	azureApplicationSecurityGroup := value.(ApplicationSecurityGroup)
+
+	// Add ApplicationSecurityGroup to Data
+	a.ApplicationSecurityGroup[address] = azureApplicationSecurityGroup
+}
+
+// addNetworkInterface adds a decoded azurerm_network_interface to Data
+func (a *Data) addNetworkInterface(resourceType string, address string, value interface{}) {
+	azureNetworkInterface := value.(NetworkInterface)
+
+	// Add NetworkInterface to Data
+	a.NetworkInterface[address] = azureNetworkInterface
+}
+
+// addVirtualMachine adds a decoded azurerm_linux_virtual_machine, azurerm_windows_virtual_machine or azurerm_virtual_machine to Data
+func (a *Data) addVirtualMachine(resourceType string, address string, value interface{}) {
+	azureVirtualMachine := value.(VirtualMachine)
+
+	// Add VirtualMachine to Data
+	a.VirtualMachine[address] = azureVirtualMachine
+}
+
+// addPublicIP adds a decoded azurerm_public_ip to Data
+func (a *Data) addPublicIP(resourceType string, address string, value interface{}) {
+	azurePublicIP := value.(PublicIP)
+
+	// Add PublicIP to Data
+	a.PublicIP[address] = azurePublicIP
+}
+
+// addLB adds a decoded azurerm_lb to Data
+func (a *Data) addLB(resourceType string, address string, value interface{}) {
+	azureLB := value.(LB)
+
+	// Add LB to Data
+	a.LB[address] = azureLB
+}
+
+// addLBBackendAddressPool adds a decoded azurerm_lb_backend_address_pool to Data
+func (a *Data) addLBBackendAddressPool(resourceType string, address string, value interface{}) {
+	azureLBBackendAddressPool := value.(LBBackendAddressPool)
+
+	// Add LBBackendAddressPool to Data
+	a.LBBackendAddressPool[address] = azureLBBackendAddressPool
+}
+
+// addLBRule adds a decoded azurerm_lb_rule to Data
+func (a *Data) addLBRule(resourceType string, address string, value interface{}) {
+	azureLBRule := value.(LBRule)
+
+	// Add LBRule to Data
+	a.LBRule[address] = azureLBRule
+}
+
+// addAssociation adds a decoded azurerm_subnet_network_security_group_association, azurerm_network_interface_security_group_association, azurerm_network_interface_application_security_group_association or azurerm_network_interface_backend_address_pool_association to Data
+func (a *Data) addAssociation(resourceType string, address string, value interface{}) {
+	azureAssociation := value.(Association)
+
+	// Add Association to Data
+	a.Association[address] = azureAssociation
+}
+
+// ResolveResources merges the NSG rules declared as standalone resources in their NSG.
+// It must be called once all resources are parsed
+func (a *Data) ResolveResources() {
+	a.mergeNSGRules()
+}
+
+// nsgAddress returns the address of a NSG referenced by its address, its ID or its name ("" if unknown)
+func (a *Data) nsgAddress(reference string) string {
+	if _, found := a.NetworkSecurityGroup[referencedResource(reference)]; found {
+		return referencedResource(reference)
+	}
+	for nsgAddress, nsg := range a.NetworkSecurityGroup {
+		if nsg.Name == reference {
+			return nsgAddress
+		}
+	}
+	return ""
+}
+
+// mergeNSGRules adds the rules declared as standalone resources (azurerm_network_security_rule) to their NSG.
+// It must be called once all resources are parsed
+func (a *Data) mergeNSGRules() {
+	for _, r := range a.standaloneNSGRules {
+		nsgAddress := a.nsgAddress(r.NetworkSecurityGroupName)
+		if nsgAddress == "" {
+			a.log.Error(fmt.Errorf("%s: unknown network security group %s, the rule is ignored", r.Address, r.NetworkSecurityGroupName))
+			continue
+		}
+		a.log.Verbosef("Merging %s in %s", r.Address, nsgAddress)
+		nsg := a.NetworkSecurityGroup[nsgAddress]
+		nsg.SecurityRules = append(nsg.SecurityRules, r.Rule)
+		a.NetworkSecurityGroup[nsgAddress] = nsg
+	}
+	a.standaloneNSGRules = nil
+}
+
+// vnetAddress returns the address of the VNet of a subnet ("" if unknown)
+func (a *Data) vnetAddress(subnet Subnet) string {
+	if _, found := a.VirtualNetwork[referencedResource(subnet.VirtualNetworkName)]; found {
+		return referencedResource(subnet.VirtualNetworkName)
+	}
+	for vnetAddress, vnet := range a.VirtualNetwork {
+		if vnet.Name == subnet.VirtualNetworkName {
+			return vnetAddress
+		}
+	}
+	return ""
+}
+
+// subnetPrefixes returns the address prefixes of a subnet
+func subnetPrefixes(subnet Subnet) []string {
+	if subnet.AddressPrefixes != nil {
+		return *subnet.AddressPrefixes
+	}
+	if subnet.AddressPrefix != nil {
+		return []string{*subnet.AddressPrefix}
+	}
+	return nil
+}
+
+// subnetNSG returns the address of the NSG associated to a subnet ("" if none)
+func (a *Data) subnetNSG(subnetAddress string) string {
+	for _, association := range a.Association {
+		if association.SubnetID != nil && *association.SubnetID == subnetAddress && association.NetworkSecurityGroupID != nil {
+			return a.nsgAddress(*association.NetworkSecurityGroupID)
+		}
+	}
+	return ""
+}
+
+// nicNSG returns the address of the NSG associated to a network interface ("" if none)
+func (a *Data) nicNSG(nicAddress string) string {
+	for _, association := range a.Association {
+		if association.NetworkInterfaceID != nil && *association.NetworkInterfaceID == nicAddress && association.NetworkSecurityGroupID != nil {
+			return a.nsgAddress(*association.NetworkSecurityGroupID)
+		}
+	}
+	return ""
+}
+
+// nicASGs returns the ASGs a network interface is associated to
+func (a *Data) nicASGs(nicAddress string) []string {
+	var asgs []string
+	for _, association := range a.Association {
+		if association.NetworkInterfaceID != nil && *association.NetworkInterfaceID == nicAddress && association.ApplicationSecurityGroupID != nil {
+			asgs = append(asgs, *association.ApplicationSecurityGroupID)
+		}
+	}
+	sort.Strings(asgs)
+	return asgs
+}
+
+// vmNICs returns the network interfaces of a virtual machine (defined in TF)
+func (a *Data) vmNICs(vm VirtualMachine) []string {
+	var nics []string
+	for _, nic := range vm.NetworkInterfaceIDs {
+		if _, found := a.NetworkInterface[nic]; found {
+			nics = append(nics, nic)
+		}
+	}
+	return nics
+}
+
+// vmSubnet returns the subnet of a virtual machine: the subnet of the primary IP configuration of its first
+// network interface ("" if unknown)
+func (a *Data) vmSubnet(vm VirtualMachine) string {
+	for _, nic := range a.vmNICs(vm) {
+		subnet := ""
+		for _, ipConfiguration := range a.NetworkInterface[nic].IPConfigurations {
+			if ipConfiguration.SubnetID == nil {
+				continue
+			}
+			if subnet == "" || (ipConfiguration.Primary != nil && *ipConfiguration.Primary) {
+				subnet = *ipConfiguration.SubnetID
+			}
+		}
+		if _, found := a.Subnet[subnet]; found {
+			return subnet
+		}
+	}
+	return ""
+}
+
+// vmPublic returns true if a virtual machine has a public IP address
+func (a *Data) vmPublic(vm VirtualMachine) bool {
+	for _, nic := range a.vmNICs(vm) {
+		for _, ipConfiguration := range a.NetworkInterface[nic].IPConfigurations {
+			if ipConfiguration.PublicIPAddressID != nil && *ipConfiguration.PublicIPAddressID != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lbPublic returns true if a load balancer has a public frontend IP configuration
+func lbPublic(lb LB) bool {
+	for _, frontend := range lb.FrontendIPConfigurations {
+		if frontend.PublicIPAddressID != nil && *frontend.PublicIPAddressID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// lbSubnet returns the subnet of the frontend of an internal load balancer ("" if unknown)
+func (a *Data) lbSubnet(lb LB) string {
+	for _, frontend := range lb.FrontendIPConfigurations {
+		if frontend.SubnetID == nil {
+			continue
+		}
+		if _, found := a.Subnet[*frontend.SubnetID]; found {
+			return *frontend.SubnetID
+		}
+	}
+	return ""
+}
+
+func (a *Data) createVirtualNetwork(graph *model.Graph, vnetAddress string, vnet VirtualNetwork) (error) {
+	// Create VNet group
+	vnetID := utils.NodeID(vnetAddress)
+	modulePath, _, vnetName := utils.SplitAddress(vnetAddress)
+	if vnet.Name != "" {
+		vnetName = vnet.Name
+	}
+	parent := a.moduleGroup(modulePath)
+	a.log.Verbosef("AddGroup: %s to %s // Create VNet", vnetID, parent)
+	label := "VNet: " + utils.ModulePrefix(modulePath) + vnetName
+	if len(vnet.AddressSpace) > 0 {
+		label += "\n" + strings.Join(vnet.AddressSpace, ", ")
+	}
+	return graph.AddGroup(&model.Group{
+		ID:			vnetID,
+		Parent:		parent,
+		Kind:		model.NetworkGroup,
+		Label:		label,
+		Address:	vnetAddress,
+	})
+}
+
+func (a *Data) createSubnet(graph *model.Graph, subnetAddress string, subnet Subnet) (error) {
+	// Create subnet group in its VNet
+	subnetID := utils.NodeID(subnetAddress)
+	modulePath, _, subnetName := utils.SplitAddress(subnetAddress)
+	if subnet.Name != "" {
+		subnetName = subnet.Name
+	}
+	parent := a.moduleGroup(modulePath)
+	if vnetAddress := a.vnetAddress(subnet); vnetAddress != "" {
+		parent = utils.NodeID(vnetAddress)
+	}
+	a.log.Verbosef("AddGroup: %s to %s // Create Subnet", subnetID, parent)
+	label := "Subnet: " + utils.ModulePrefix(modulePath) + subnetName
+	if prefixes := subnetPrefixes(subnet); len(prefixes) > 0 {
+		label += "\n" + strings.Join(prefixes, ", ")
+	}
+	var attributes map[string]string
+	if nsg := a.subnetNSG(subnetAddress); nsg != "" {
+		label += "\nNSG: " + a.NetworkSecurityGroup[nsg].Name
+		attributes = map[string]string{"network_security_group": nsg}
+	}
+	return graph.AddGroup(&model.Group{
+		ID:			subnetID,
+		Parent:		parent,
+		Kind:		model.SubnetGroup,
+		Label:		label,
+		Address:	subnetAddress,
+		Attributes:	attributes,
+	})
+}
+
+func (a *Data) createVirtualMachine(graph *model.Graph, vmAddress string, vm VirtualMachine) (error) {
+	// Create virtual machine node in the subnet of its primary network interface
+	modulePath, resourceType, vmName := utils.SplitAddress(vmAddress)
+	if vm.Name != "" {
+		vmName = vm.Name
+	}
+	parent := a.moduleGroup(modulePath)
+	if subnet := a.vmSubnet(vm); subnet != "" {
+		parent = utils.NodeID(subnet)
+	}
+	a.log.Verbosef("AddNode: %s to %s // Create virtual machine", utils.NodeID(vmAddress), parent)
+
+	label := strings.Join(utils.ChunkString(vmName, 8), "\n")
+	switch resourceType {
+	case "azurerm_linux_virtual_machine":
+		label += "\n(Linux)"
+	case "azurerm_windows_virtual_machine":
+		label += "\n(Windows)"
+	}
+	// VMs with a public IP are reachable from the Internet
+	public := a.vmPublic(vm)
+	if public {
+		label += "\npublic IP"
+	}
+	return graph.AddNode(&model.Node{
+		ID:			utils.NodeID(vmAddress),
+		Parent:		parent,
+		Kind:		resourceType,
+		Label:		label,
+		Address:	vmAddress,
+		Icon:		"azure/icons/vm.png",
+		Public:		public,
+	})
+}
+
+func (a *Data) createLB(graph *model.Graph, lbAddress string, lb LB) (error) {
+	// Create internal load balancer nodes in the subnet of their frontend, public ones in their module
+	modulePath, resourceType, lbName := utils.SplitAddress(lbAddress)
+	if lb.Name != "" {
+		lbName = lb.Name
+	}
+	parent := a.moduleGroup(modulePath)
+	if subnet := a.lbSubnet(lb); subnet != "" && !lbPublic(lb) {
+		parent = utils.NodeID(subnet)
+	}
+	a.log.Verbosef("AddNode: %s to %s // Create load balancer", utils.NodeID(lbAddress), parent)
+
+	label := strings.Join(utils.ChunkString(lbName, 8), "\n")
+	if lbPublic(lb) {
+		label += "\n(public)"
+	} else {
+		label += "\n(internal)"
+	}
+	return graph.AddNode(&model.Node{
+		ID:			utils.NodeID(lbAddress),
+		Parent:		parent,
+		Kind:		resourceType,
+		Label:		label,
+		Address:	lbAddress,
+		Icon:		"azure/icons/lb.png",
+		Public:		lbPublic(lb),
+	})
+}
+
+// CreateGraphNodes creates the nodes for the graph
+func (a *Data) CreateGraphNodes(graph *model.Graph) (error) {
+	// Add VNet groups to graph
+	for vnetName, vnetObj := range a.VirtualNetwork {
+		err := a.createVirtualNetwork(graph, vnetName, vnetObj)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add Subnet groups to graph
+	for subnetName, subnetObj := range a.Subnet {
+		err := a.createSubnet(graph, subnetName, subnetObj)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add virtual machine nodes to graph
+	for vmName, vmObj := range a.VirtualMachine {
+		err := a.createVirtualMachine(graph, vmName, vmObj)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add load balancer nodes to graph
+	for lbName, lbObj := range a.LB {
+		err := a.createLB(graph, lbName, lbObj)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateGraphEdges creates edges for the graph
+func (a *Data) CreateGraphEdges(graph *model.Graph) (error) {
+	// Link virtual machines with the rules of their NSGs
+	for vmName, vmObj := range a.VirtualMachine {
+		// Parse Inbound NSG rules
+		if !a.options.IgnoreIngress {
+			err := a.parseNSGRules(inboundRule, vmName, vmObj, graph)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Parse Outbound NSG rules
+		if !a.options.IgnoreEgress {
+			err := a.parseNSGRules(outboundRule, vmName, vmObj, graph)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Link load balancers with the virtual machines of their backend pools
+	a.createLBEdges()
+
+	// Add the edges (merged by source / destination) to the graph
+	return a.createNSGEdges(graph)
+}
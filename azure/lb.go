@@ -0,0 +1,59 @@
+package azure
+
+import (
+	"sort"
+
+	"github.com/steeve85/tfviz/utils"
+)
+
+// poolVirtualMachines returns the virtual machines whose network interfaces are associated to a LB backend pool
+func (a *Data) poolVirtualMachines(poolAddress string) []string {
+	var vms []string
+	for _, association := range a.Association {
+		if association.BackendAddressPoolID == nil || *association.BackendAddressPoolID != poolAddress || association.NetworkInterfaceID == nil {
+			continue
+		}
+		if vm := a.vmByNIC(*association.NetworkInterfaceID); vm != "" {
+			vms = append(vms, vm)
+		}
+	}
+	sort.Strings(vms)
+	return utils.RemoveDuplicateValues(vms)
+}
+
+// createLBEdges creates the edges of the load balancer rules: from the Internet to the public load balancers
+// (frontend ports), and from the load balancers to the virtual machines of their backend pools (backend ports).
+// The client IPs being preserved, the traffic to the backend is evaluated against the NSGs of the virtual
+// machines as coming from the Internet (public load balancers) or from the VNet (internal load balancers)
+func (a *Data) createLBEdges() {
+	for ruleAddress, rule := range a.LBRule {
+		lb, found := a.LB[rule.LoadbalancerID]
+		if !found {
+			continue
+		}
+		lbNode := utils.NodeID(rule.LoadbalancerID)
+		protocol := ruleProtocol(rule.Protocol)
+
+		if lbPublic(lb) {
+			frontend := flow{protocol, rule.FrontendPort, rule.FrontendPort}
+			a.addNSGEdge("Internet", lbNode, modelFlow(frontend, inboundRule, ruleAddress), false).Public = true
+		}
+
+		if rule.BackendAddressPoolIDs == nil {
+			continue
+		}
+		backend := flow{protocol, rule.BackendPort, rule.BackendPort}
+		for _, pool := range *rule.BackendAddressPoolIDs {
+			for _, vmAddress := range a.poolVirtualMachines(pool) {
+				vm := a.VirtualMachine[vmAddress]
+				remote := internetEndpoint()
+				if !lbPublic(lb) {
+					if vnet := a.vnetAddress(a.Subnet[a.lbSubnet(lb)]); vnet != "" {
+						remote = a.vnetEndpoint(vnet)
+					}
+				}
+				a.addNSGEdge(lbNode, utils.NodeID(vmAddress), modelFlow(backend, inboundRule, ruleAddress), a.deniedByNSG(inboundRule, backend, a.vmEndpoint(vmAddress, vm), remote))
+			}
+		}
+	}
+}
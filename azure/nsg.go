@@ -0,0 +1,510 @@
+package azure
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// defaultNSGRules are the rules Azure adds to every NSG, evaluated after the rules defined in TF
+var defaultNSGRules = []NSGRule{
+	{Name: "AllowVnetInBound", Priority: 65000, Direction: inboundRule, Access: "Allow", Protocol: "*", SourceAddressPrefix: utils.StringPtr("VirtualNetwork"), DestinationAddressPrefix: utils.StringPtr("VirtualNetwork")},
+	{Name: "AllowAzureLoadBalancerInBound", Priority: 65001, Direction: inboundRule, Access: "Allow", Protocol: "*", SourceAddressPrefix: utils.StringPtr("AzureLoadBalancer"), DestinationAddressPrefix: utils.StringPtr("*")},
+	{Name: "DenyAllInBound", Priority: 65500, Direction: inboundRule, Access: "Deny", Protocol: "*", SourceAddressPrefix: utils.StringPtr("*"), DestinationAddressPrefix: utils.StringPtr("*")},
+	{Name: "AllowVnetOutBound", Priority: 65000, Direction: outboundRule, Access: "Allow", Protocol: "*", SourceAddressPrefix: utils.StringPtr("VirtualNetwork"), DestinationAddressPrefix: utils.StringPtr("VirtualNetwork")},
+	{Name: "AllowInternetOutBound", Priority: 65001, Direction: outboundRule, Access: "Allow", Protocol: "*", SourceAddressPrefix: utils.StringPtr("*"), DestinationAddressPrefix: utils.StringPtr("Internet")},
+	{Name: "DenyAllOutBound", Priority: 65500, Direction: outboundRule, Access: "Deny", Protocol: "*", SourceAddressPrefix: utils.StringPtr("*"), DestinationAddressPrefix: utils.StringPtr("*")},
+}
+
+
+// endpoint is a side of a flow evaluated against NSG rules: a VM, a subnet, a VNet, the Internet, a CIDR or a
+// service tag
+type endpoint struct {
+	// Graph node of the endpoint
+	Node					string
+	// IP ranges of the endpoint
+	Cidrs					[]string
+	// Service tags matching the endpoint (VirtualNetwork, Internet...)
+	Tags					[]string
+	// ASGs of the network interfaces of a VM
+	ASGs					[]string
+	// Network interfaces of a VM
+	NICs					[]string
+	// NSGs filtering the traffic of the endpoint (network interfaces then subnet)
+	NSGs					[]string
+}
+
+// flow is the traffic allowed by a NSG rule (or a LB rule): a protocol and a destination port range
+type flow struct {
+	Protocol				string
+	FromPort				int
+	ToPort					int
+}
+
+// ruleProtocol returns the protocol of a NSG / LB rule in lower case ("*" for all protocols)
+func ruleProtocol(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "*", "all", "":
+		return "*"
+	}
+	return strings.ToLower(protocol)
+}
+
+// destinationPortRanges returns the destination port ranges of a NSG rule
+func destinationPortRanges(rule NSGRule) []string {
+	var ports []string
+	if rule.DestinationPortRange != nil {
+		ports = append(ports, *rule.DestinationPortRange)
+	}
+	if rule.DestinationPortRanges != nil {
+		ports = append(ports, *rule.DestinationPortRanges...)
+	}
+	if len(ports) == 0 {
+		return []string{"*"}
+	}
+	return ports
+}
+
+// rulePrefixes returns the address prefixes and ASGs of the source or of the destination of a NSG rule
+func rulePrefixes(rule NSGRule, source bool) []string {
+	prefix, prefixes, asgs := rule.DestinationAddressPrefix, rule.DestinationAddressPrefixes, rule.DestinationApplicationSecurityGroupIDs
+	if source {
+		prefix, prefixes, asgs = rule.SourceAddressPrefix, rule.SourceAddressPrefixes, rule.SourceApplicationSecurityGroupIDs
+	}
+	var list []string
+	if prefix != nil {
+		list = append(list, *prefix)
+	}
+	for _, l := range []*[]string{prefixes, asgs} {
+		if l != nil {
+			list = append(list, *l...)
+		}
+	}
+	if len(list) == 0 {
+		return []string{"*"}
+	}
+	return list
+}
+
+// modelFlow returns the flow of the graph allowed by a NSG / LB rule. direction is inbound or outbound and rule the
+// address of the NSG / LB rule
+func modelFlow(f flow, direction string, rule string) *model.Flow {
+	if direction == outboundRule {
+		return model.NewFlow(f.Protocol, f.FromPort, f.ToPort, model.Egress, rule)
+	}
+	return model.NewFlow(f.Protocol, f.FromPort, f.ToPort, model.Ingress, rule)
+}
+
+// prefixMatch returns if an address prefix of a NSG rule (CIDR, service tag, ASG or network interface
+// reference) contains (full) or overlaps (partial) an endpoint
+func (a *Data) prefixMatch(prefix string, e endpoint) (full bool, partial bool) {
+	if prefix == "*" || strings.ToLower(prefix) == "any" {
+		return true, false
+	}
+	if _, found := a.ApplicationSecurityGroup[prefix]; found {
+		_, found := utils.Find(e.ASGs, prefix)
+		return found, false
+	}
+	if _, found := a.NetworkInterface[referencedResource(prefix)]; found {
+		_, found := utils.Find(e.NICs, referencedResource(prefix))
+		return found, false
+	}
+	if _, _, err := net.ParseCIDR(prefix); err == nil || net.ParseIP(prefix) != nil {
+		for _, cidr := range e.Cidrs {
+			_, peer, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+			f, p := utils.CidrMatch(prefix, peer)
+			full, partial = full || f, partial || p
+		}
+		return full, partial && !full
+	}
+	// Service tag
+	_, found := utils.Find(e.Tags, prefix)
+	return found, false
+}
+
+// prefixesMatch returns if one of the address prefixes of a NSG rule contains (full) or overlaps (partial)
+// an endpoint
+func (a *Data) prefixesMatch(prefixes []string, e endpoint) (full bool, partial bool) {
+	for _, prefix := range prefixes {
+		f, p := a.prefixMatch(prefix, e)
+		full, partial = full || f, partial || p
+	}
+	return full, partial && !full
+}
+
+// nsgAllows evaluates the rules of a NSG (by priority, the first matching rule applying) for a flow between a
+// local endpoint (filtered by the NSG) and a remote one. The traffic is allowed if at least a part of it (a port,
+// a protocol or an IP range) is allowed
+func (a *Data) nsgAllows(nsgAddress string, direction string, f flow, local endpoint, remote endpoint) bool {
+	nsg, found := a.NetworkSecurityGroup[nsgAddress]
+	if !found {
+		return true
+	}
+	var rules []NSGRule
+	for _, rule := range append(append([]NSGRule{}, nsg.SecurityRules...), defaultNSGRules...) {
+		if strings.EqualFold(rule.Direction, direction) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority < rules[j].Priority
+	})
+
+	src, dst := remote, local
+	if direction == outboundRule {
+		src, dst = local, remote
+	}
+	hasPorts := f.Protocol == "*" || f.Protocol == "tcp" || f.Protocol == "udp"
+
+	var portRanges [][2]int
+	for _, rule := range rules {
+		for _, r := range destinationPortRanges(rule) {
+			fromPort, toPort, err := utils.PortRange(r)
+			if err == nil {
+				portRanges = append(portRanges, [2]int{fromPort, toPort})
+			}
+		}
+	}
+
+	// Source ports are not evaluated, rules are expected to allow any source port
+	return utils.RulesAllow(len(rules), f.FromPort, f.ToPort, portRanges, func(i int, port int) (full bool, partial bool, allow bool) {
+		rule := rules[i]
+		fullSrc, partialSrc := a.prefixesMatch(rulePrefixes(rule, true), src)
+		fullDst, partialDst := a.prefixesMatch(rulePrefixes(rule, false), dst)
+		if (!fullSrc && !partialSrc) || (!fullDst && !partialDst) {
+			return false, false, false
+		}
+		protocol := ruleProtocol(rule.Protocol)
+		fullProtocol := protocol == "*" || protocol == f.Protocol
+		if !fullProtocol && f.Protocol != "*" {
+			return false, false, false
+		}
+		if hasPorts && protocol != "icmp" {
+			matchPort := false
+			for _, r := range destinationPortRanges(rule) {
+				fromPort, toPort, err := utils.PortRange(r)
+				if err == nil && port >= fromPort && port <= toPort {
+					matchPort = true
+				}
+			}
+			if !matchPort {
+				return false, false, false
+			}
+		}
+		full = fullSrc && fullDst && fullProtocol
+		return full, !full, strings.EqualFold(rule.Access, "Allow")
+	})
+}
+
+// deniedByNSG returns true if a flow between a local and a remote endpoint is denied by one of the NSGs of the
+// local endpoint (in direction) or of the remote one (in the opposite direction)
+func (a *Data) deniedByNSG(direction string, f flow, local endpoint, remote endpoint) bool {
+	for _, nsg := range local.NSGs {
+		if !a.nsgAllows(nsg, direction, f, local, remote) {
+			return true
+		}
+	}
+	remoteDirection := inboundRule
+	if direction == inboundRule {
+		remoteDirection = outboundRule
+	}
+	for _, nsg := range remote.NSGs {
+		if !a.nsgAllows(nsg, remoteDirection, f, remote, local) {
+			return true
+		}
+	}
+	return false
+}
+
+// vmEndpoint returns the endpoint of a virtual machine
+func (a *Data) vmEndpoint(vmAddress string, vm VirtualMachine) endpoint {
+	e := endpoint{Node: utils.NodeID(vmAddress), Tags: []string{"VirtualNetwork"}}
+	subnet := a.vmSubnet(vm)
+	for _, nic := range a.vmNICs(vm) {
+		e.NICs = append(e.NICs, nic)
+		e.ASGs = append(e.ASGs, a.nicASGs(nic)...)
+		if nsg := a.nicNSG(nic); nsg != "" {
+			e.NSGs = append(e.NSGs, nsg)
+		}
+		for _, ipConfiguration := range a.NetworkInterface[nic].IPConfigurations {
+			if ipConfiguration.PrivateIPAddress != nil && net.ParseIP(*ipConfiguration.PrivateIPAddress) != nil {
+				e.Cidrs = append(e.Cidrs, *ipConfiguration.PrivateIPAddress+"/32")
+			}
+		}
+	}
+	if len(e.Cidrs) == 0 {
+		// Dynamic private IP address
+		e.Cidrs = subnetPrefixes(a.Subnet[subnet])
+	}
+	if nsg := a.subnetNSG(subnet); nsg != "" {
+		e.NSGs = append(e.NSGs, nsg)
+	}
+	e.NSGs = utils.RemoveDuplicateValues(e.NSGs)
+	return e
+}
+
+// subnetEndpoint returns the endpoint of a subnet
+func (a *Data) subnetEndpoint(subnetAddress string) endpoint {
+	e := endpoint{Node: utils.NodeID(subnetAddress), Cidrs: subnetPrefixes(a.Subnet[subnetAddress]), Tags: []string{"VirtualNetwork"}}
+	if nsg := a.subnetNSG(subnetAddress); nsg != "" {
+		e.NSGs = []string{nsg}
+	}
+	return e
+}
+
+// vnetEndpoint returns the endpoint of a VNet
+func (a *Data) vnetEndpoint(vnetAddress string) endpoint {
+	return endpoint{Node: utils.NodeID(vnetAddress), Cidrs: a.VirtualNetwork[vnetAddress].AddressSpace, Tags: []string{"VirtualNetwork"}}
+}
+
+// internetEndpoint returns the endpoint of the Internet
+func internetEndpoint() endpoint {
+	return endpoint{Node: model.InternetID, Cidrs: []string{"0.0.0.0/0"}, Tags: []string{"Internet"}}
+}
+
+// vmByNIC returns the address of the virtual machine of a network interface ("" if none)
+func (a *Data) vmByNIC(nicAddress string) string {
+	for vmAddress, vm := range a.VirtualMachine {
+		if _, found := utils.Find(vm.NetworkInterfaceIDs, nicAddress); found {
+			return vmAddress
+		}
+	}
+	return ""
+}
+
+// cidrEndpoints returns the endpoints of a CIDR of a NSG rule: the VNets / subnets it contains, the subnet
+// containing it, or the Internet / a node created for the CIDR if it is outside of the VNets defined in TF
+func (a *Data) cidrEndpoints(graph *model.Graph, cidr string) ([]endpoint, error) {
+	if !strings.Contains(cidr, "/") {
+		cidr += "/32"
+	}
+	var endpoints []endpoint
+	contains := func(prefixes []string) bool {
+		for _, prefix := range prefixes {
+			_, network, err := net.ParseCIDR(prefix)
+			if err != nil {
+				continue
+			}
+			if full, _ := utils.CidrMatch(cidr, network); full {
+				return true
+			}
+		}
+		return false
+	}
+	var vnets []string
+	for vnetAddress, vnet := range a.VirtualNetwork {
+		if contains(vnet.AddressSpace) {
+			vnets = append(vnets, vnetAddress)
+			endpoints = append(endpoints, a.vnetEndpoint(vnetAddress))
+		}
+	}
+	for subnetAddress, subnet := range a.Subnet {
+		if _, found := utils.Find(vnets, a.vnetAddress(subnet)); !found && contains(subnetPrefixes(subnet)) {
+			endpoints = append(endpoints, a.subnetEndpoint(subnetAddress))
+		}
+	}
+	if len(endpoints) > 0 {
+		return endpoints, nil
+	}
+
+	_, peer, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, nil
+	}
+	for subnetAddress, subnet := range a.Subnet {
+		for _, prefix := range subnetPrefixes(subnet) {
+			if full, _ := utils.CidrMatch(prefix, peer); full {
+				return []endpoint{a.subnetEndpoint(subnetAddress)}, nil
+			}
+		}
+	}
+	if cidr == "0.0.0.0/0" {
+		return []endpoint{internetEndpoint()}, nil
+	}
+
+	// IP range outside of the VNets defined in TF
+	e := endpoint{Node: utils.NodeID(cidr), Cidrs: []string{cidr}, Tags: []string{"Internet"}}
+	if !graph.IsNode(e.Node) {
+		a.log.Verbosef("AddNode: %s // Create CIDR", e.Node)
+		err := graph.AddNode(&model.Node{
+			ID:		e.Node,
+			Kind:	model.CidrNode,
+			Label:	cidr,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []endpoint{e}, nil
+}
+
+// remoteEndpoints returns the endpoints of the remote side of a NSG rule of a virtual machine
+func (a *Data) remoteEndpoints(graph *model.Graph, vmAddress string, vm VirtualMachine, prefixes []string) ([]endpoint, error) {
+	vnet := a.vnetAddress(a.Subnet[a.vmSubnet(vm)])
+	var endpoints []endpoint
+	for _, prefix := range prefixes {
+		switch {
+		case prefix == "*" || strings.ToLower(prefix) == "any":
+			endpoints = append(endpoints, internetEndpoint())
+			if vnet != "" {
+				endpoints = append(endpoints, a.vnetEndpoint(vnet))
+			}
+			continue
+		case prefix == "Internet":
+			endpoints = append(endpoints, internetEndpoint())
+			continue
+		case prefix == "VirtualNetwork":
+			if vnet != "" {
+				endpoints = append(endpoints, a.vnetEndpoint(vnet))
+			}
+			continue
+		}
+
+		// Network interfaces (referenced by their private IP address) and ASGs: the VMs they are attached to
+		if _, found := a.NetworkInterface[referencedResource(prefix)]; found {
+			if peer := a.vmByNIC(referencedResource(prefix)); peer != "" && peer != vmAddress {
+				endpoints = append(endpoints, a.vmEndpoint(peer, a.VirtualMachine[peer]))
+			}
+			continue
+		}
+		if _, found := a.ApplicationSecurityGroup[prefix]; found {
+			for _, association := range a.Association {
+				if association.ApplicationSecurityGroupID == nil || *association.ApplicationSecurityGroupID != prefix || association.NetworkInterfaceID == nil {
+					continue
+				}
+				if peer := a.vmByNIC(*association.NetworkInterfaceID); peer != "" && peer != vmAddress {
+					endpoints = append(endpoints, a.vmEndpoint(peer, a.VirtualMachine[peer]))
+				}
+			}
+			continue
+		}
+
+		if _, _, err := net.ParseCIDR(prefix); err == nil || net.ParseIP(prefix) != nil {
+			cidrEndpoints, err := a.cidrEndpoints(graph, prefix)
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, cidrEndpoints...)
+			continue
+		}
+
+		// Other service tags (e.g. AzureLoadBalancer, Storage, Sql)
+		e := endpoint{Node: utils.NodeID("tag." + prefix), Tags: []string{prefix}}
+		if !graph.IsNode(e.Node) {
+			a.log.Verbosef("AddNode: %s // Create service tag", e.Node)
+			err := graph.AddNode(&model.Node{
+				ID:		e.Node,
+				Kind:	model.ServiceNode,
+				Label:	prefix + "\n(service tag)",
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
+
+// parseNSGRules creates the edges of the Allow rules of the NSGs of a virtual machine (of its network interfaces
+// and of its subnet) in a direction
+func (a *Data) parseNSGRules(direction string, vmAddress string, vm VirtualMachine, graph *model.Graph) (error) {
+	local := a.vmEndpoint(vmAddress, vm)
+	public := a.vmPublic(vm)
+
+	if len(local.NSGs) == 0 {
+		// Without NSG, all the traffic is allowed
+		if public && direction == inboundRule {
+			a.addNSGEdge("Internet", local.Node, &model.Flow{Protocol: "all", Direction: model.Ingress}, false).Public = true
+		}
+		return nil
+	}
+
+	for _, nsgAddress := range local.NSGs {
+		for _, rule := range a.NetworkSecurityGroup[nsgAddress].SecurityRules {
+			if !strings.EqualFold(rule.Direction, direction) || !strings.EqualFold(rule.Access, "Allow") {
+				continue
+			}
+			// The local side of the rule must match the VM
+			if full, partial := a.prefixesMatch(rulePrefixes(rule, direction == outboundRule), local); !full && !partial {
+				continue
+			}
+			remotes, err := a.remoteEndpoints(graph, vmAddress, vm, rulePrefixes(rule, direction == inboundRule))
+			if err != nil {
+				return err
+			}
+
+			for _, ports := range destinationPortRanges(rule) {
+				fromPort, toPort, err := utils.PortRange(ports)
+				if err != nil {
+					a.log.Error(fmt.Errorf("%s: invalid port range %s in rule %s", nsgAddress, ports, rule.Name))
+					continue
+				}
+				f := flow{ruleProtocol(rule.Protocol), fromPort, toPort}
+				for _, remote := range remotes {
+					internet := false
+					if remote.Node == "Internet" {
+						if direction == inboundRule && !public {
+							a.log.Verbosef("%s is not reachable from the Internet (no public IP)", vmAddress)
+							continue
+						}
+						// Inbound from / Outbound to the Internet
+						internet = true
+					}
+					src, dst := remote.Node, local.Node
+					if direction == outboundRule {
+						src, dst = local.Node, remote.Node
+					}
+					edge := a.addNSGEdge(src, dst, modelFlow(f, direction, nsgAddress), a.deniedByNSG(direction, f, local, remote))
+					if internet {
+						edge.Public = true
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addNSGEdge records an edge created from a NSG / LB rule. Parallel rules between the same nodes are merged
+// in a single edge, their flows are appended. denied is true if the traffic allowed by the rule is denied
+// by another NSG
+func (a *Data) addNSGEdge(src string, dst string, f *model.Flow, denied bool) *model.Edge {
+	if a.nsgEdgesIndex == nil {
+		a.nsgEdgesIndex = make(map[string]*model.Edge)
+	}
+	key := src + " -> " + dst
+	edge, found := a.nsgEdgesIndex[key]
+	if !found {
+		edge = &model.Edge{Src: src, Dst: dst, Kind: model.RuleEdge}
+		a.nsgEdgesIndex[key] = edge
+		a.nsgEdges = append(a.nsgEdges, edge)
+	}
+	if denied {
+		f.Blocked = "denied by NSG"
+	}
+	for _, existing := range edge.Flows {
+		if existing == *f {
+			return edge
+		}
+	}
+	edge.Flows = append(edge.Flows, *f)
+	return edge
+}
+
+// createNSGEdges adds the edges recorded by addNSGEdge to the graph
+func (a *Data) createNSGEdges(graph *model.Graph) (error) {
+	for _, edge := range a.nsgEdges {
+		a.log.Verbosef("AddEdge: %s -> %s", edge.Src, edge.Dst)
+		graph.AddEdge(edge)
+	}
+	return nil
+}
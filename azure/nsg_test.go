@@ -0,0 +1,147 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/steeve85/tfviz/utils"
+)
+
+// nsgTestData returns Data with the NSG azurerm_network_security_group.app and the rules
+func nsgTestData(rules []NSGRule) *Data {
+	return &Data{
+		NetworkSecurityGroup:		map[string]NetworkSecurityGroup{"azurerm_network_security_group.app": {Name: "app", SecurityRules: rules}},
+		ApplicationSecurityGroup:	map[string]ApplicationSecurityGroup{"azurerm_application_security_group.web": {}},
+	}
+}
+
+// nsgRule returns a rule from / to any address
+func nsgRule(priority int, direction string, access string, protocol string, ports string) NSGRule {
+	return NSGRule{
+		Name:						"rule",
+		Priority:					priority,
+		Direction:					direction,
+		Access:						access,
+		Protocol:					protocol,
+		DestinationPortRange:		utils.StringPtr(ports),
+		SourceAddressPrefix:		utils.StringPtr("*"),
+		DestinationAddressPrefix:	utils.StringPtr("*"),
+	}
+}
+
+// withSource returns a rule with a source address prefix
+func withSource(rule NSGRule, prefix string) NSGRule {
+	rule.SourceAddressPrefix = utils.StringPtr(prefix)
+	return rule
+}
+
+// withDestination returns a rule with a destination address prefix
+func withDestination(rule NSGRule, prefix string) NSGRule {
+	rule.DestinationAddressPrefix = utils.StringPtr(prefix)
+	return rule
+}
+
+func TestNSGAllows(t *testing.T) {
+	// The evaluation of the rules by priority and port range is tested with utils.RulesAllow: these cases cover
+	// the service tags, the ASGs and the default rules of the NSGs
+	vm := endpoint{Node: "azurerm_linux_virtual_machine.app", Cidrs: []string{"10.0.1.4/32"}, Tags: []string{"VirtualNetwork"}, ASGs: []string{"azurerm_application_security_group.web"}}
+	peer := endpoint{Node: "azurerm_subnet.db", Cidrs: []string{"10.0.2.0/24"}, Tags: []string{"VirtualNetwork"}}
+	ssh := flow{"tcp", 22, 22}
+	tests := []struct {
+		name		string
+		rules		[]NSGRule
+		direction	string
+		f			flow
+		remote		endpoint
+		want		bool
+	}{
+		{
+			name:		"VirtualNetwork tag doesn't match the Internet",
+			rules:		[]NSGRule{withSource(nsgRule(100, inboundRule, "Allow", "Tcp", "22"), "VirtualNetwork")},
+			direction:	inboundRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+		{
+			name:		"Internet tag doesn't match the VNet",
+			rules:		[]NSGRule{withSource(nsgRule(100, inboundRule, "Deny", "*", "*"), "Internet")},
+			direction:	inboundRule,
+			f:			ssh,
+			remote:		peer,
+			want:		true,
+		},
+		{
+			name:		"Internet tag",
+			rules:		[]NSGRule{withSource(nsgRule(100, inboundRule, "Allow", "Tcp", "22"), "Internet")},
+			direction:	inboundRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		true,
+		},
+		{
+			name:		"ASG of the local endpoint",
+			rules:		[]NSGRule{withDestination(nsgRule(100, inboundRule, "Allow", "Tcp", "22"), "azurerm_application_security_group.web")},
+			direction:	inboundRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		true,
+		},
+		{
+			name:		"default rules deny the inbound traffic from the Internet",
+			direction:	inboundRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+		{
+			name:		"default rules allow the inbound traffic from the VNet",
+			direction:	inboundRule,
+			f:			ssh,
+			remote:		peer,
+			want:		true,
+		},
+		{
+			name:		"default rules allow the outbound traffic to the Internet",
+			direction:	outboundRule,
+			f:			flow{"tcp", 443, 443},
+			remote:		internetEndpoint(),
+			want:		true,
+		},
+		{
+			name:		"rules evaluated before the default rules",
+			rules:		[]NSGRule{withDestination(nsgRule(4096, outboundRule, "Deny", "*", "*"), "Internet")},
+			direction:	outboundRule,
+			f:			flow{"tcp", 443, 443},
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+	}
+	for _, test := range tests {
+		a := nsgTestData(test.rules)
+		if got := a.nsgAllows("azurerm_network_security_group.app", test.direction, test.f, vm, test.remote); got != test.want {
+			t.Errorf("%s: nsgAllows = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDeniedByNSG(t *testing.T) {
+	a := nsgTestData([]NSGRule{nsgRule(100, outboundRule, "Deny", "Tcp", "22")})
+	a.NetworkSecurityGroup["azurerm_network_security_group.open"] = NetworkSecurityGroup{Name: "open", SecurityRules: []NSGRule{nsgRule(100, inboundRule, "Allow", "*", "*")}}
+	local := endpoint{Node: "azurerm_linux_virtual_machine.web", Cidrs: []string{"10.0.1.4/32"}, Tags: []string{"VirtualNetwork"}, NSGs: []string{"azurerm_network_security_group.open"}}
+	remote := endpoint{Node: "azurerm_linux_virtual_machine.app", Cidrs: []string{"10.0.2.4/32"}, Tags: []string{"VirtualNetwork"}, NSGs: []string{"azurerm_network_security_group.app"}}
+
+	// The inbound flow allowed by the local NSG is denied by the outbound rules of the NSG of the remote endpoint
+	if !a.deniedByNSG(inboundRule, flow{"tcp", 22, 22}, local, remote) {
+		t.Error("a flow denied by the remote NSG must be denied")
+	}
+	if a.deniedByNSG(inboundRule, flow{"tcp", 443, 443}, local, remote) {
+		t.Error("a flow allowed by both NSGs must not be denied")
+	}
+	// Endpoints without NSG allow everything
+	if a.deniedByNSG(inboundRule, flow{"tcp", 22, 22}, endpoint{Node: "a"}, endpoint{Node: "b"}) {
+		t.Error("a flow between endpoints without NSG must not be denied")
+	}
+	if !a.nsgAllows("azurerm_network_security_group.unknown", inboundRule, flow{"tcp", 22, 22}, local, remote) {
+		t.Error("an unknown NSG must allow everything")
+	}
+}
@@ -0,0 +1,55 @@
+package azure
+
+import (
+	"strings"
+
+	"github.com/steeve85/tfviz/provider"
+	"github.com/steeve85/tfviz/utils"
+)
+
+
+func init() {
+	provider.Register("azure", func(options provider.Options) provider.Provider {
+		return NewData(options)
+	})
+}
+
+// NewData returns an empty Data drawing a graph with these options
+func NewData(options provider.Options) *Data {
+	return &Data{
+		options:			options,
+		log:				options.Log,
+		VirtualNetwork:		make(map[string]VirtualNetwork),
+		Subnet:				make(map[string]Subnet),
+		NetworkSecurityGroup:	make(map[string]NetworkSecurityGroup),
+		ApplicationSecurityGroup:	make(map[string]ApplicationSecurityGroup),
+		NetworkInterface:	make(map[string]NetworkInterface),
+		VirtualMachine:		make(map[string]VirtualMachine),
+		PublicIP:			make(map[string]PublicIP),
+		LB:					make(map[string]LB),
+		LBBackendAddressPool:	make(map[string]LBBackendAddressPool),
+		LBRule:				make(map[string]LBRule),
+		Association:		make(map[string]Association),
+	}
+}
+
+// Claims returns true for the resources of the azurerm provider
+func (a *Data) Claims(resourceType string) bool {
+	return strings.HasPrefix(resourceType, "azurerm_")
+}
+
+// ReferencedAttributes returns the computed attributes used to reference Azure resources
+func (a *Data) ReferencedAttributes() map[string][]string {
+	return referencedAttributes
+}
+
+// IDAttributes returns nil: Azure resources are only identified by their id
+func (a *Data) IDAttributes() []string {
+	return nil
+}
+
+// ListAttribute returns true if the attribute at path (e.g. source_address_prefixes) of a resource type is decoded as a list
+func (a *Data) ListAttribute(resourceType string, path []string) bool {
+	handler, found := resourceHandlers[resourceType]
+	return found && utils.IsListAttribute(handler.value, path)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"github.com/steeve85/tfviz"
+	"github.com/steeve85/tfviz/render"
+	"github.com/steeve85/tfviz/utils"
+)
+
+var exportFormats = []string{"dot", "jpeg", "pdf", "png"}
+
+var inputTypes = []string{tfviz.HCL, tfviz.Plan, tfviz.State}
+
+func main() {
+	var options tfviz.Options
+	flag.StringVar(&options.Input, "input", ".", "Path to Terraform file or directory ")
+	flag.StringVar(&options.InputType, "inputtype", "hcl", "Type of input: hcl (Terraform files), plan (output of terraform show -json <planfile>), state (terraform.tfstate or output of terraform show -json)")
+	outputFlag := flag.String("output", "tfviz.bin", "Path to the exported file")
+	formatFlag := flag.String("format", "png", "Format for the output file: dot, jpeg, pdf, png")
+	flag.BoolVar(&options.DisableEdges, "disableedges", false, "Set to disable edges (Security Groups rules) on the graph")
+	flag.BoolVar(&options.Verbose, "verbose", false, "Set to enable verbose output")
+	flag.BoolVar(&options.IgnoreWarnings, "ignorewarnings", false, "Set to ignore warning messages")
+	flag.BoolVar(&options.IgnoreIngress, "ignoreingress", false, "Set to ignore ingress rules")
+	flag.BoolVar(&options.IgnoreEgress, "ignoreegress", false, "Set to ignore egress rules")
+	var renderOptions render.Options
+	flag.BoolVar(&renderOptions.DisableEdgeLabels, "disableedgelabels", false, "Set to disable the protocol / port labels on edges")
+	flag.StringVar(&renderOptions.IconDir, "icondir", ".", "Path to the tfviz repository directory containing the aws, azure and gcp icons")
+	flag.BoolVar(&options.ModuleClusters, "moduleclusters", false, "Set to draw each Terraform module as a cluster")
+	flag.Var(utils.InputVariablesFlag{Flag: "var", Items: &options.Variables}, "var", "Set a variable of the root module: -var 'name=value' (can be repeated)")
+	flag.Var(utils.InputVariablesFlag{Flag: "var-file", Items: &options.Variables}, "var-file", "Load variables from a .tfvars file (can be repeated)")
+	flag.Parse()
+	// TF_VAR_ environment variables set variables of the root module, like in Terraform
+	options.Environment = os.Environ()
+
+	// checking that export format is supported
+	_, found := utils.Find(exportFormats, *formatFlag)
+	if !found {
+		fmt.Printf("[ERROR] File format %s is not supported. Quitting...\n", *formatFlag)
+		os.Exit(1)
+	}
+
+	// checking that input type is supported
+	_, found = utils.Find(inputTypes, options.InputType)
+	if !found {
+		fmt.Printf("[ERROR] Input type %s is not supported. Quitting...\n", options.InputType)
+		os.Exit(1)
+	}
+
+	// the icon paths are absolute so that the exported DOT file can be rendered from any directory
+	iconDir, err := filepath.Abs(renderOptions.IconDir)
+	if err != nil {
+		fmt.Printf("[ERROR] %s\n", err)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(filepath.Join(iconDir, "aws", "icons")); err != nil && !options.IgnoreWarnings {
+		fmt.Printf("[WARNING] Icons not found in %s, use -icondir to set the path to the tfviz repository\n", iconDir)
+	}
+	renderOptions.IconDir = iconDir
+
+	// check that the export path does not already exist
+	if _, err := os.Stat(*outputFlag); err == nil {
+		fmt.Printf("[ERROR] File %s already exists. Quitting...\n", *outputFlag)
+		os.Exit(1)
+	}
+
+	stepsNb := 6
+	if options.DisableEdges {
+		stepsNb--
+	}
+	if options.InputType != tfviz.HCL {
+		// Plans and states are already evaluated by Terraform
+		stepsNb -= 2
+	}
+	step := 1
+	options.Progress = func(message string) {
+		fmt.Printf("[%d/%d] %s\n", step, stepsNb, message)
+		step++
+	}
+
+	graph, diags, err := tfviz.Render(context.Background(), options)
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	if err != nil {
+		fmt.Printf("[ERROR] %s\n", err)
+		os.Exit(1)
+	}
+
+	dot, err := render.DOT(graph, renderOptions)
+	if err != nil {
+		fmt.Printf("[ERROR] %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("[%d/%d] ", stepsNb, stepsNb)
+	err = exportGraphToFile(*outputFlag, *formatFlag, dot, options.Verbose)
+	if err != nil {
+		fmt.Printf("[ERROR] %s\n", err)
+	}
+}
+
+// exportGraphToFile exports Graph (in the DOT language) to file
+func exportGraphToFile(outputPath string, outputFormat string, dot string, verbose bool) error {
+	fmt.Println("Exporting Graph to", outputPath)
+	if outputFormat == "dot" {
+		err := ioutil.WriteFile(outputPath, []byte(dot), 0644)
+		if err != nil {
+			return err
+		}
+	} else {
+		tFlag := fmt.Sprintf("-T%s", outputFormat)
+		cmd := exec.Command("dot", tFlag, "-o", outputPath)
+		cmd.Stdin = strings.NewReader(dot)
+		if verbose == true {
+			fmt.Printf("[VERBOSE] Running command: %s\n", cmd.String())
+		}
+		err := cmd.Run()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,513 @@
+package gcp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// endpoint is a side of a flow evaluated against firewall rules: an instance / managed instance group, a
+// subnetwork, a network, the Internet or an IP range
+type endpoint struct {
+	// Graph node of the endpoint
+	Node					string
+	// Address of the instance / managed instance group ("" for the other endpoints)
+	Compute					string
+	// Key of the network of the endpoint
+	Network					string
+	// IP ranges of the endpoint
+	Cidrs					[]string
+	// Network tags of an instance
+	Tags					[]string
+	// Service accounts of an instance
+	ServiceAccounts			[]string
+}
+
+// flow is the traffic allowed by a firewall rule: a protocol and a destination port range
+type flow struct {
+	Protocol				string
+	FromPort				int
+	ToPort					int
+}
+
+// impliedFirewalls are the rules of every VPC network: egress to any destination is allowed and ingress from
+// any source is denied, with the lowest priority
+var impliedFirewalls = []Firewall{
+	{Name: "implied-allow-egress", Direction: utils.StringPtr(egressRule), Priority: intPtr(65535), Allow: []FirewallProtocol{{Protocol: "all"}}},
+	{Name: "implied-deny-ingress", Direction: utils.StringPtr(ingressRule), Priority: intPtr(65535), Deny: []FirewallProtocol{{Protocol: "all"}}},
+}
+
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// firewallDirection returns the direction of a firewall rule (INGRESS if not set)
+func firewallDirection(firewall Firewall) string {
+	if firewall.Direction == nil || *firewall.Direction == "" {
+		return ingressRule
+	}
+	return strings.ToUpper(*firewall.Direction)
+}
+
+// firewallPriority returns the priority of a firewall rule (1000 if not set)
+func firewallPriority(firewall Firewall) int {
+	if firewall.Priority == nil {
+		return 1000
+	}
+	return *firewall.Priority
+}
+
+// firewallRanges returns the source (ingress) or destination (egress) IP ranges of a firewall rule. Ingress rules
+// without any source and egress rules without destination apply to 0.0.0.0/0
+func firewallRanges(firewall Firewall) []string {
+	if firewallDirection(firewall) == egressRule {
+		if firewall.DestinationRanges == nil {
+			return []string{"0.0.0.0/0"}
+		}
+		return *firewall.DestinationRanges
+	}
+	if firewall.SourceRanges == nil {
+		if firewall.SourceTags == nil && firewall.SourceServiceAccounts == nil {
+			return []string{"0.0.0.0/0"}
+		}
+		return nil
+	}
+	return *firewall.SourceRanges
+}
+
+// optionalList returns the values of an optional list of a firewall rule (service accounts being referenced by
+// their address)
+func optionalList(list *[]string) []string {
+	var values []string
+	if list != nil {
+		for _, v := range *list {
+			values = append(values, referencedResource(v))
+		}
+	}
+	return values
+}
+
+// intersects returns true if two lists have a common value
+func intersects(list1 []string, list2 []string) bool {
+	for _, v := range list1 {
+		if _, found := utils.Find(list2, v); found {
+			return true
+		}
+	}
+	return false
+}
+
+// protocolName converts an IP protocol number of a firewall rule to its name ("*" for all protocols)
+func protocolName(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "all", "":
+		return "*"
+	case "1":
+		return "icmp"
+	case "6":
+		return "tcp"
+	case "17":
+		return "udp"
+	case "132":
+		return "sctp"
+	}
+	return strings.ToLower(protocol)
+}
+
+// protocolPorts returns the port ranges of an allow / deny block ("" for all the ports)
+func protocolPorts(protocol FirewallProtocol) []string {
+	if protocol.Ports == nil || len(*protocol.Ports) == 0 {
+		return []string{""}
+	}
+	return *protocol.Ports
+}
+
+// modelFlow returns the flow of the graph allowed by a firewall rule. direction is INGRESS or EGRESS and rule the
+// address of the firewall rule
+func modelFlow(f flow, direction string, rule string) *model.Flow {
+	if direction == egressRule {
+		return model.NewFlow(f.Protocol, f.FromPort, f.ToPort, model.Egress, rule)
+	}
+	return model.NewFlow(f.Protocol, f.FromPort, f.ToPort, model.Ingress, rule)
+}
+
+// targetMatches returns true if a firewall rule applies to an endpoint: an instance of its network with one of
+// its target tags / service accounts (all the instances of the network if none is set)
+func (a *Data) targetMatches(firewall Firewall, e endpoint) bool {
+	if e.Compute == "" || a.networkKey(firewall.Network) != e.Network {
+		return false
+	}
+	switch {
+	case firewall.TargetTags != nil:
+		return intersects(*firewall.TargetTags, e.Tags)
+	case firewall.TargetServiceAccounts != nil:
+		return intersects(optionalList(firewall.TargetServiceAccounts), e.ServiceAccounts)
+	}
+	return true
+}
+
+// remoteMatch returns if the sources (ingress) or destinations (egress) of a firewall rule contain (full) or
+// overlap (partial) a remote endpoint
+func remoteMatch(firewall Firewall, network string, e endpoint) (full bool, partial bool) {
+	for _, cidr := range e.Cidrs {
+		_, peer, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		for _, rangeCidr := range firewallRanges(firewall) {
+			f, p := utils.CidrMatch(rangeCidr, peer)
+			full, partial = full || f, partial || p
+		}
+	}
+	// Source tags and service accounts only match the instances of the network of the rule
+	if firewallDirection(firewall) == ingressRule && e.Compute != "" && e.Network == network {
+		if intersects(optionalList(firewall.SourceTags), e.Tags) || intersects(optionalList(firewall.SourceServiceAccounts), e.ServiceAccounts) {
+			full = true
+		}
+	}
+	return full, partial && !full
+}
+
+// protocolMatch returns if an allow / deny block of a firewall rule matches a flow on a port, and if it matches
+// all its protocols
+func protocolMatch(protocol FirewallProtocol, f flow, port int) (match bool, fullProtocol bool) {
+	name := protocolName(protocol.Protocol)
+	fullProtocol = name == "*" || name == f.Protocol
+	if !fullProtocol && f.Protocol != "*" {
+		return false, false
+	}
+	if name == "tcp" || name == "udp" || name == "sctp" {
+		for _, ports := range protocolPorts(protocol) {
+			fromPort, toPort, err := utils.PortRange(ports)
+			if err == nil && port >= fromPort && port <= toPort {
+				return true, fullProtocol
+			}
+		}
+		return false, false
+	}
+	return true, fullProtocol
+}
+
+// firewallAddresses returns the addresses of the firewall rules, sorted so that the rules are always evaluated in
+// the same order
+func (a *Data) firewallAddresses() []string {
+	firewallAddresses := make([]string, 0, len(a.Firewall))
+	for firewallAddress := range a.Firewall {
+		firewallAddresses = append(firewallAddresses, firewallAddress)
+	}
+	sort.Strings(firewallAddresses)
+	return firewallAddresses
+}
+
+// firewallAllows evaluates the firewall rules applying to a local endpoint (by priority, deny rules first at
+// equal priority, then by address, the first matching rule applying) for a flow from / to a remote endpoint. The
+// traffic is allowed if at least a part of it (a port, a protocol or an IP range) is allowed
+func (a *Data) firewallAllows(direction string, f flow, local endpoint, remote endpoint) bool {
+	var firewalls []Firewall
+	for _, firewallAddress := range a.firewallAddresses() {
+		firewall := a.Firewall[firewallAddress]
+		if firewallDirection(firewall) != direction || (firewall.Disabled != nil && *firewall.Disabled) {
+			continue
+		}
+		if a.targetMatches(firewall, local) {
+			firewalls = append(firewalls, firewall)
+		}
+	}
+	for _, firewall := range impliedFirewalls {
+		if firewallDirection(firewall) == direction {
+			firewalls = append(firewalls, firewall)
+		}
+	}
+	sort.SliceStable(firewalls, func(i, j int) bool {
+		if firewallPriority(firewalls[i]) != firewallPriority(firewalls[j]) {
+			return firewallPriority(firewalls[i]) < firewallPriority(firewalls[j])
+		}
+		return len(firewalls[i].Deny) > 0 && len(firewalls[j].Deny) == 0
+	})
+
+	var portRanges [][2]int
+	for _, firewall := range firewalls {
+		for _, protocol := range append(append([]FirewallProtocol{}, firewall.Allow...), firewall.Deny...) {
+			for _, r := range protocolPorts(protocol) {
+				fromPort, toPort, err := utils.PortRange(r)
+				if err == nil {
+					portRanges = append(portRanges, [2]int{fromPort, toPort})
+				}
+			}
+		}
+	}
+
+	return utils.RulesAllow(len(firewalls), f.FromPort, f.ToPort, portRanges, func(i int, port int) (full bool, partial bool, allow bool) {
+		firewall := firewalls[i]
+		fullRemote, partialRemote := remoteMatch(firewall, local.Network, remote)
+		if !fullRemote && !partialRemote {
+			return false, false, false
+		}
+		allow = len(firewall.Allow) > 0
+		protocols := firewall.Allow
+		if !allow {
+			protocols = firewall.Deny
+		}
+		match, fullProtocol := false, false
+		for _, protocol := range protocols {
+			m, full := protocolMatch(protocol, f, port)
+			match, fullProtocol = match || m, fullProtocol || (m && full)
+		}
+		if !match {
+			return false, false, false
+		}
+		full = fullRemote && fullProtocol
+		return full, !full, allow
+	})
+}
+
+// deniedByFirewall returns true if a flow between a local and a remote endpoint is denied by the firewall rules
+// applying to the local endpoint (in direction) or to the remote one if it is an instance (in the opposite
+// direction)
+func (a *Data) deniedByFirewall(direction string, f flow, local endpoint, remote endpoint) bool {
+	if !a.firewallAllows(direction, f, local, remote) {
+		return true
+	}
+	if remote.Compute == "" {
+		return false
+	}
+	remoteDirection := ingressRule
+	if direction == ingressRule {
+		remoteDirection = egressRule
+	}
+	return !a.firewallAllows(remoteDirection, f, remote, local)
+}
+
+// computeEndpoint returns the endpoint of an instance / managed instance group
+func (a *Data) computeEndpoint(address string) endpoint {
+	node := a.computeNodes[address]
+	return endpoint{
+		Node: utils.NodeID(address),
+		Compute: address,
+		Network: node.Network,
+		Cidrs: node.Cidrs,
+		Tags: node.Tags,
+		ServiceAccounts: node.ServiceAccounts,
+	}
+}
+
+// internetEndpoint returns the endpoint of the Internet
+func internetEndpoint() endpoint {
+	return endpoint{Node: model.InternetID, Cidrs: []string{"0.0.0.0/0"}}
+}
+
+// cidrEndpoints returns the endpoints of an IP range of a firewall rule: the networks / subnetworks it contains,
+// the subnetwork containing it, or the Internet / a node created for the range if it is outside of the networks
+// defined in TF
+func (a *Data) cidrEndpoints(graph *model.Graph, cidr string) ([]endpoint, error) {
+	if !strings.Contains(cidr, "/") {
+		cidr += "/32"
+	}
+	_, peer, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, nil
+	}
+	if cidr == "0.0.0.0/0" {
+		return []endpoint{internetEndpoint()}, nil
+	}
+	contains := func(prefix string) bool {
+		_, network, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return false
+		}
+		full, _ := utils.CidrMatch(cidr, network)
+		return full
+	}
+
+	var endpoints []endpoint
+	var networks []string
+	for networkAddress := range a.Network {
+		cidrs := a.networkCidrs(networkAddress)
+		all := true
+		for _, c := range cidrs {
+			all = all && contains(c)
+		}
+		if all {
+			networks = append(networks, networkAddress)
+			endpoints = append(endpoints, endpoint{Node: utils.NodeID(networkAddress), Network: networkAddress, Cidrs: cidrs})
+		}
+	}
+	for subnetworkAddress, subnetwork := range a.Subnetwork {
+		network := a.networkKey(subnetwork.Network)
+		if _, found := utils.Find(networks, network); !found && contains(subnetwork.IPCidrRange) {
+			endpoints = append(endpoints, endpoint{Node: utils.NodeID(subnetworkAddress), Network: network, Cidrs: []string{subnetwork.IPCidrRange}})
+		}
+	}
+	if len(endpoints) > 0 {
+		return endpoints, nil
+	}
+
+	for subnetworkAddress, subnetwork := range a.Subnetwork {
+		if full, _ := utils.CidrMatch(subnetwork.IPCidrRange, peer); full {
+			return []endpoint{{Node: utils.NodeID(subnetworkAddress), Network: a.networkKey(subnetwork.Network), Cidrs: []string{cidr}}}, nil
+		}
+	}
+
+	// IP range outside of the networks defined in TF
+	e := endpoint{Node: utils.NodeID(cidr), Cidrs: []string{cidr}}
+	if !graph.IsNode(e.Node) {
+		a.log.Verbosef("AddNode: %s // Create CIDR", e.Node)
+		err := graph.AddNode(&model.Node{
+			ID:		e.Node,
+			Kind:	model.CidrNode,
+			Label:	cidr,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []endpoint{e}, nil
+}
+
+// remoteEndpoints returns the endpoints of the sources (ingress) or destinations (egress) of a firewall rule of
+// an instance: the instances with its source tags / service accounts (resolved through TagNodeLinks and
+// ServiceAccountNodeLinks) and its IP ranges
+func (a *Data) remoteEndpoints(graph *model.Graph, local endpoint, firewall Firewall) ([]endpoint, error) {
+	var endpoints []endpoint
+	if firewallDirection(firewall) == ingressRule {
+		var peers []string
+		for _, tag := range optionalList(firewall.SourceTags) {
+			peers = append(peers, a.TagNodeLinks[tag]...)
+		}
+		for _, serviceAccount := range optionalList(firewall.SourceServiceAccounts) {
+			peers = append(peers, a.ServiceAccountNodeLinks[serviceAccount]...)
+		}
+		peers = utils.RemoveDuplicateValues(peers)
+		sort.Strings(peers)
+		for _, peer := range peers {
+			if peer != local.Compute && a.computeNodes[peer].Network == local.Network {
+				endpoints = append(endpoints, a.computeEndpoint(peer))
+			}
+		}
+	}
+	for _, cidr := range firewallRanges(firewall) {
+		cidrEndpoints, err := a.cidrEndpoints(graph, cidr)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, cidrEndpoints...)
+	}
+	return endpoints, nil
+}
+
+// parseFirewallRules creates the edges of the allow firewall rules applying to an instance / managed instance
+// group in a direction
+func (a *Data) parseFirewallRules(direction string, address string, graph *model.Graph) (error) {
+	local := a.computeEndpoint(address)
+	public := a.computeNodes[address].Public
+
+	for _, firewallAddress := range a.firewallAddresses() {
+		firewall := a.Firewall[firewallAddress]
+		if firewallDirection(firewall) != direction || len(firewall.Allow) == 0 || (firewall.Disabled != nil && *firewall.Disabled) {
+			continue
+		}
+		if !a.targetMatches(firewall, local) {
+			continue
+		}
+		remotes, err := a.remoteEndpoints(graph, local, firewall)
+		if err != nil {
+			return err
+		}
+
+		for _, protocol := range firewall.Allow {
+			for _, ports := range protocolPorts(protocol) {
+				fromPort, toPort, err := utils.PortRange(ports)
+				if err != nil {
+					a.log.Error(fmt.Errorf("%s: invalid port range %s", firewallAddress, ports))
+					continue
+				}
+				f := flow{protocolName(protocol.Protocol), fromPort, toPort}
+				for _, remote := range remotes {
+					internet := false
+					if remote.Node == "Internet" {
+						if direction == ingressRule && !public {
+							a.log.Verbosef("%s is not reachable from the Internet (no external IP)", address)
+							continue
+						}
+						// Ingress from / Egress to the Internet
+						internet = true
+					}
+					src, dst := remote.Node, local.Node
+					if direction == egressRule {
+						src, dst = local.Node, remote.Node
+					}
+					edge := a.addFirewallEdge(src, dst, modelFlow(f, direction, firewallAddress), a.deniedByFirewall(direction, f, local, remote))
+					if internet {
+						edge.Public = true
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// createSQLEdges creates the edges of the authorized networks of the Cloud SQL instances with a public IP
+func (a *Data) createSQLEdges(graph *model.Graph) (error) {
+	for sqlAddress, sql := range a.SQLDatabaseInstance {
+		if !sqlPublic(sql) {
+			continue
+		}
+		f := flow{"tcp", sqlPort(sql), sqlPort(sql)}
+		for _, authorizedNetwork := range sqlIPConfiguration(sql).AuthorizedNetworks {
+			remotes, err := a.cidrEndpoints(graph, authorizedNetwork.Value)
+			if err != nil {
+				return err
+			}
+			for _, remote := range remotes {
+				// The authorized networks are not firewall rules, the flow is allowed by the instance itself
+				edge := a.addFirewallEdge(remote.Node, utils.NodeID(sqlAddress), modelFlow(f, ingressRule, sqlAddress), false)
+				if remote.Node == "Internet" {
+					edge.Public = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addFirewallEdge records an edge created from a firewall rule. Parallel rules between the same nodes are merged
+// in a single edge, their flows are appended. denied is true if the traffic allowed by the rule is denied
+// by another firewall rule
+func (a *Data) addFirewallEdge(src string, dst string, f *model.Flow, denied bool) *model.Edge {
+	if a.firewallEdgesIndex == nil {
+		a.firewallEdgesIndex = make(map[string]*model.Edge)
+	}
+	key := src + " -> " + dst
+	edge, found := a.firewallEdgesIndex[key]
+	if !found {
+		edge = &model.Edge{Src: src, Dst: dst, Kind: model.RuleEdge}
+		a.firewallEdgesIndex[key] = edge
+		a.firewallEdges = append(a.firewallEdges, edge)
+	}
+	if denied {
+		f.Blocked = "denied by firewall"
+	}
+	for _, existing := range edge.Flows {
+		if existing == *f {
+			return edge
+		}
+	}
+	edge.Flows = append(edge.Flows, *f)
+	return edge
+}
+
+// createFirewallEdges adds the edges recorded by addFirewallEdge to the graph
+func (a *Data) createFirewallEdges(graph *model.Graph) (error) {
+	for _, edge := range a.firewallEdges {
+		a.log.Verbosef("AddEdge: %s -> %s", edge.Src, edge.Dst)
+		graph.AddEdge(edge)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package gcp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/steeve85/tfviz/utils"
+)
+
+// firewallTestData returns Data with the network google_compute_network.vpc and the firewall rules
+func firewallTestData(firewalls map[string]Firewall) *Data {
+	a := &Data{
+		Network:	map[string]Network{
+			"google_compute_network.vpc":	{Name: "vpc"},
+			"google_compute_network.other":	{Name: "other"},
+		},
+		Firewall:	make(map[string]Firewall),
+	}
+	for address, firewall := range firewalls {
+		a.Firewall[address] = firewall
+	}
+	return a
+}
+
+// allowRule returns a rule of google_compute_network.vpc allowing a protocol on ports
+func allowRule(direction string, priority int, protocol string, ports ...string) Firewall {
+	return Firewall{
+		Network:	"google_compute_network.vpc",
+		Direction:	utils.StringPtr(direction),
+		Priority:	intPtr(priority),
+		Allow:		[]FirewallProtocol{{Protocol: protocol, Ports: &ports}},
+	}
+}
+
+// denyRule returns a rule of google_compute_network.vpc denying a protocol on ports
+func denyRule(direction string, priority int, protocol string, ports ...string) Firewall {
+	firewall := allowRule(direction, priority, protocol, ports...)
+	firewall.Allow, firewall.Deny = nil, firewall.Allow
+	return firewall
+}
+
+func TestFirewallAllows(t *testing.T) {
+	// The evaluation of the rules by priority and port range is tested with utils.RulesAllow: these cases cover
+	// the implied rules, the order of the firewall rules, and their networks, targets and sources
+	web := endpoint{Node: "google_compute_instance.web", Compute: "google_compute_instance.web", Network: "google_compute_network.vpc", Cidrs: []string{"10.0.1.2/32"}, Tags: []string{"web"}, ServiceAccounts: []string{"google_service_account.web"}}
+	bastion := endpoint{Node: "google_compute_instance.bastion", Compute: "google_compute_instance.bastion", Network: "google_compute_network.vpc", Cidrs: []string{"10.0.2.2/32"}, Tags: []string{"bastion"}}
+	ssh := flow{"tcp", 22, 22}
+	disabled := allowRule(ingressRule, 1000, "tcp", "22")
+	disabled.Disabled = new(bool)
+	*disabled.Disabled = true
+	disabledDeny := denyRule(ingressRule, 100, "all")
+	disabledDeny.Disabled = disabled.Disabled
+	defaultPriority := denyRule(ingressRule, 0, "all")
+	defaultPriority.Priority = nil
+	targetWeb := allowRule(ingressRule, 1000, "tcp", "22")
+	targetWeb.TargetTags = &[]string{"web"}
+	targetDB := allowRule(ingressRule, 1000, "tcp", "22")
+	targetDB.TargetTags = &[]string{"db"}
+	targetAccount := allowRule(ingressRule, 1000, "tcp", "22")
+	targetAccount.TargetServiceAccounts = &[]string{"google_service_account.web.email"}
+	otherNetwork := allowRule(ingressRule, 1000, "tcp", "22")
+	otherNetwork.Network = "google_compute_network.other"
+	fromBastion := allowRule(ingressRule, 1000, "tcp", "22")
+	fromBastion.SourceTags = &[]string{"bastion"}
+
+	tests := []struct {
+		name		string
+		firewalls	map[string]Firewall
+		direction	string
+		f			flow
+		remote		endpoint
+		want		bool
+	}{
+		{
+			name:		"implied rule denies ingress",
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+		{
+			name:		"implied rule allows egress",
+			direction:	egressRule,
+			f:			flow{"tcp", 443, 443},
+			remote:		internetEndpoint(),
+			want:		true,
+		},
+		{
+			name:		"deny rule first at equal priority",
+			firewalls:	map[string]Firewall{
+				"google_compute_firewall.a_allow":	allowRule(ingressRule, 1000, "tcp", "22"),
+				"google_compute_firewall.b_deny":	denyRule(ingressRule, 1000, "tcp", "22"),
+			},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+		{
+			name:		"default priority",
+			firewalls:	map[string]Firewall{
+				"google_compute_firewall.ssh":	allowRule(ingressRule, 999, "tcp", "22"),
+				"google_compute_firewall.deny":	defaultPriority,
+			},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		true,
+		},
+		{
+			name:		"disabled allow rule",
+			firewalls:	map[string]Firewall{"google_compute_firewall.ssh": disabled},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+		{
+			name:		"disabled deny rule",
+			firewalls:	map[string]Firewall{
+				"google_compute_firewall.ssh":	allowRule(ingressRule, 1000, "tcp", "22"),
+				"google_compute_firewall.deny":	disabledDeny,
+			},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		true,
+		},
+		{
+			name:		"target tag of the instance",
+			firewalls:	map[string]Firewall{"google_compute_firewall.ssh": targetWeb},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		true,
+		},
+		{
+			name:		"target tag of another instance",
+			firewalls:	map[string]Firewall{"google_compute_firewall.ssh": targetDB},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+		{
+			name:		"target service account",
+			firewalls:	map[string]Firewall{"google_compute_firewall.ssh": targetAccount},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		true,
+		},
+		{
+			name:		"rule of another network",
+			firewalls:	map[string]Firewall{"google_compute_firewall.ssh": otherNetwork},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+		{
+			name:		"source tag of the remote instance",
+			firewalls:	map[string]Firewall{"google_compute_firewall.ssh": fromBastion},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		bastion,
+			want:		true,
+		},
+		{
+			name:		"source tag doesn't match the Internet",
+			firewalls:	map[string]Firewall{"google_compute_firewall.ssh": fromBastion},
+			direction:	ingressRule,
+			f:			ssh,
+			remote:		internetEndpoint(),
+			want:		false,
+		},
+	}
+	for _, test := range tests {
+		a := firewallTestData(test.firewalls)
+		if got := a.firewallAllows(test.direction, test.f, web, test.remote); got != test.want {
+			t.Errorf("%s: firewallAllows = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDeniedByFirewall(t *testing.T) {
+	web := endpoint{Node: "google_compute_instance.web", Compute: "google_compute_instance.web", Network: "google_compute_network.vpc", Cidrs: []string{"10.0.1.2/32"}, Tags: []string{"web"}}
+	bastion := endpoint{Node: "google_compute_instance.bastion", Compute: "google_compute_instance.bastion", Network: "google_compute_network.vpc", Cidrs: []string{"10.0.2.2/32"}, Tags: []string{"bastion"}}
+	denyEgress := denyRule(egressRule, 1000, "tcp", "22")
+	denyEgress.TargetTags = &[]string{"bastion"}
+	a := firewallTestData(map[string]Firewall{
+		"google_compute_firewall.ssh":		allowRule(ingressRule, 1000, "tcp", "22"),
+		"google_compute_firewall.bastion":	denyEgress,
+	})
+
+	// The ingress allowed on web is denied by the egress rules of the remote instance
+	if !a.deniedByFirewall(ingressRule, flow{"tcp", 22, 22}, web, bastion) {
+		t.Error("a flow denied by the rules of the remote instance must be denied")
+	}
+	// Only the rules of the local endpoint apply to the traffic from / to the Internet
+	if a.deniedByFirewall(ingressRule, flow{"tcp", 22, 22}, web, internetEndpoint()) {
+		t.Error("a flow allowed from the Internet must not be denied")
+	}
+}
+
+func TestFirewallAddressesAreSorted(t *testing.T) {
+	a := firewallTestData(map[string]Firewall{
+		"google_compute_firewall.c":	{},
+		"google_compute_firewall.a":	{},
+		"module.app.google_compute_firewall.b":	{},
+		"google_compute_firewall.b":	{},
+	})
+	want := []string{"google_compute_firewall.a", "google_compute_firewall.b", "google_compute_firewall.c", "module.app.google_compute_firewall.b"}
+	for i := 0; i < 10; i++ {
+		if got := a.firewallAddresses(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("firewallAddresses = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,792 @@
+package gcp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/dynblock"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/provider"
+	"github.com/steeve85/tfviz/utils"
+)
+
+
+// referencedAttributes lists the computed attributes (other than id) used to reference Google Cloud resources.
+// They are set to "<resource address>.<attribute>" in the EvalContext
+var referencedAttributes = map[string][]string{
+	"google_compute_network":	{"name", "self_link"},
+	"google_compute_subnetwork":	{"name", "self_link"},
+	"google_compute_instance":	{"name", "self_link"},
+	"google_compute_instance_template":	{"name", "self_link", "self_link_unique"},
+	"google_service_account":	{"email", "name"},
+	"google_sql_database_instance":	{"name", "self_link", "connection_name"},
+	"google_storage_bucket":	{"name", "url", "self_link"},
+}
+
+// Defining values for the direction of firewall rules
+const ingressRule = "INGRESS"
+const egressRule = "EGRESS"
+
+// autoModeRange is the IP range of the subnetworks created in auto mode VPC networks
+const autoModeRange = "10.128.0.0/9"
+
+// moduleGroup returns the group in which the top level resources of a module are drawn ("" for the top level)
+func (a *Data) moduleGroup(modulePath string) string {
+	if !a.options.ModuleClusters || modulePath == "" {
+		return ""
+	}
+	return utils.NodeID(modulePath)
+}
+
+// referencedResource returns the address of the resource referenced by one of its referencedAttributes
+// (e.g. google_compute_network.vpc.self_link => google_compute_network.vpc)
+func referencedResource(reference string) string {
+	return utils.ReferencedResource(reference, referencedAttributes)
+}
+
+// Data is a structure that contain maps of TF parsed Google Cloud resources
+type Data struct {
+	Network					map[string]Network
+	Subnetwork				map[string]Subnetwork
+	Firewall				map[string]Firewall
+	Instance				map[string]Instance
+	InstanceTemplate		map[string]Instance
+	// Zonal and regional managed instance groups
+	InstanceGroupManager	map[string]InstanceGroupManager
+	ServiceAccount			map[string]ServiceAccount
+	SQLDatabaseInstance		map[string]SQLDatabaseInstance
+	StorageBucket			map[string]StorageBucket
+	// Instances and managed instance groups, indexed by network tag
+	TagNodeLinks			map[string][]string
+	// Instances and managed instance groups, indexed by service account
+	ServiceAccountNodeLinks	map[string][]string
+	computeNodes			map[string]computeNode
+	firewallEdges			[]*model.Edge
+	firewallEdgesIndex		map[string]*model.Edge
+	// options of the graph (ingress / egress rules, module clusters)
+	options					provider.Options
+	// log collects the diagnostics of the graph
+	log						*utils.Logger
+}
+
+// Network is a structure for Google Cloud VPC network resources
+type Network struct {
+	// Name of the resource
+	Name					string `hcl:"name"`
+	// When set to true, the network is created in "auto subnet mode" (default)
+	AutoCreateSubnetworks	*bool `hcl:"auto_create_subnetworks"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// Subnetwork is a structure for Google Cloud subnetwork resources
+type Subnetwork struct {
+	// The name of the resource
+	Name					string `hcl:"name"`
+	// The range of internal addresses that are owned by this subnetwork
+	IPCidrRange				string `hcl:"ip_cidr_range"`
+	// The network this subnet belongs to
+	Network					string `hcl:"network"`
+	// The GCP region for this subnetwork
+	Region					*string `hcl:"region"`
+	// When enabled, VMs in this subnetwork without external IP addresses can access Google APIs and services
+	PrivateIPGoogleAccess	*bool `hcl:"private_ip_google_access"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// Firewall is a structure for Google Cloud VPC firewall rule resources
+type Firewall struct {
+	// Name of the resource
+	Name					string `hcl:"name"`
+	// The name or self_link of the network to attach this firewall to
+	Network					string `hcl:"network"`
+	// Direction of traffic to which this firewall applies: INGRESS (default) or EGRESS
+	Direction				*string `hcl:"direction"`
+	// Priority for this rule, from 0 (highest) to 65535 (default 1000)
+	Priority				*int `hcl:"priority"`
+	// Denotes whether the firewall rule is disabled
+	Disabled				*bool `hcl:"disabled"`
+	// The list of ALLOW rules specified by this firewall
+	Allow					[]FirewallProtocol `hcl:"allow,block"`
+	// The list of DENY rules specified by this firewall
+	Deny					[]FirewallProtocol `hcl:"deny,block"`
+	// If source ranges are specified, the firewall will apply only to traffic that has source IP address in
+	// these ranges (INGRESS)
+	SourceRanges			*[]string `hcl:"source_ranges"`
+	// If destination ranges are specified, the firewall will apply only to traffic that has destination IP
+	// address in these ranges (EGRESS)
+	DestinationRanges		*[]string `hcl:"destination_ranges"`
+	// If source tags are specified, the firewall will apply only to traffic with source IP that belongs to a tag
+	// listed in source tags (INGRESS)
+	SourceTags				*[]string `hcl:"source_tags"`
+	// If source service accounts are specified, the firewall will apply only to traffic originating from an
+	// instance with a service account in this list (INGRESS)
+	SourceServiceAccounts	*[]string `hcl:"source_service_accounts"`
+	// A list of instance tags indicating sets of instances located in the network that may make network
+	// connections as specified in allow / deny. If not specified, the rule applies to all instances
+	TargetTags				*[]string `hcl:"target_tags"`
+	// A list of service accounts indicating sets of instances located in the network that may make network
+	// connections as specified in allow / deny
+	TargetServiceAccounts	*[]string `hcl:"target_service_accounts"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// FirewallProtocol is a structure for Google Cloud firewall allow / deny blocks
+type FirewallProtocol struct {
+	// The IP protocol to which this rule applies: tcp, udp, icmp, esp, ah, sctp, ipip, all or an IP protocol number
+	Protocol				string `hcl:"protocol"`
+	// An optional list of ports (e.g. 22 or 12345-12349) to which this rule applies (all ports if not set)
+	Ports					*[]string `hcl:"ports"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// Instance is a structure for Google Cloud compute instance and instance template resources
+type Instance struct {
+	// A unique name for the resource (required for instances)
+	Name					*string `hcl:"name"`
+	// Creates a unique name beginning with the specified prefix (instance templates)
+	NamePrefix				*string `hcl:"name_prefix"`
+	// The machine type to create
+	MachineType				string `hcl:"machine_type"`
+	// A list of network tags to attach to the instance
+	Tags					*[]string `hcl:"tags"`
+	// Networks to attach to the instance
+	NetworkInterfaces		[]NetworkInterface `hcl:"network_interface,block"`
+	// Service account to attach to the instance
+	ServiceAccount			[]InstanceServiceAccount `hcl:"service_account,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// NetworkInterface is a structure for Google Cloud instance network_interface blocks
+type NetworkInterface struct {
+	// The name or self_link of the network to attach this interface to
+	Network					*string `hcl:"network"`
+	// The name or self_link of the subnetwork to attach this interface to
+	Subnetwork				*string `hcl:"subnetwork"`
+	// The private IP address to assign to the instance. If empty, the address will be automatically assigned
+	NetworkIP				*string `hcl:"network_ip"`
+	// Access configurations, i.e. IPs via which this instance can be accessed via the Internet
+	AccessConfigs			[]AccessConfig `hcl:"access_config,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// AccessConfig is a structure for Google Cloud instance access_config blocks (external IP address)
+type AccessConfig struct {
+	// The IP address that will be 1:1 mapped to the instance's network ip (ephemeral if not set)
+	NatIP					*string `hcl:"nat_ip"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// InstanceServiceAccount is a structure for Google Cloud instance service_account blocks
+type InstanceServiceAccount struct {
+	// The service account e-mail address (default Compute Engine service account if not set)
+	Email					*string `hcl:"email"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// ServiceAccount is a structure for Google Cloud service account resources
+type ServiceAccount struct {
+	// The account id that is used to generate the service account email address
+	AccountID				string `hcl:"account_id"`
+	// The display name for the service account
+	DisplayName				*string `hcl:"display_name"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// InstanceGroupManager is a structure for Google Cloud zonal and regional managed instance group resources
+type InstanceGroupManager struct {
+	// The name of the instance group manager
+	Name					string `hcl:"name"`
+	// The base instance name to use for instances in this group
+	BaseInstanceName		string `hcl:"base_instance_name"`
+	// The target number of running instances for this managed instance group
+	TargetSize				*int `hcl:"target_size"`
+	// Application versions managed by this instance group
+	Versions				[]InstanceGroupManagerVersion `hcl:"version,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// InstanceGroupManagerVersion is a structure for Google Cloud managed instance group version blocks
+type InstanceGroupManagerVersion struct {
+	// The full URL to an instance template from which all new instances of this version will be created
+	InstanceTemplate		string `hcl:"instance_template"`
+	// Version name
+	Name					*string `hcl:"name"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// SQLDatabaseInstance is a structure for Google Cloud SQL database instance resources
+type SQLDatabaseInstance struct {
+	// The name of the instance (random if not set)
+	Name					*string `hcl:"name"`
+	// The MySQL, PostgreSQL or SQL Server version to use (e.g. POSTGRES_15)
+	DatabaseVersion			string `hcl:"database_version"`
+	// The settings to use for the database
+	Settings				[]SQLSettings `hcl:"settings,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// SQLSettings is a structure for Google Cloud SQL database instance settings blocks
+type SQLSettings struct {
+	// The machine type to use
+	Tier					string `hcl:"tier"`
+	// IP configuration of the instance
+	IPConfiguration			[]SQLIPConfiguration `hcl:"ip_configuration,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// SQLIPConfiguration is a structure for Google Cloud SQL database instance ip_configuration blocks
+type SQLIPConfiguration struct {
+	// Whether this Cloud SQL instance should be assigned a public IPV4 address (default true)
+	IPv4Enabled				*bool `hcl:"ipv4_enabled"`
+	// The VPC network from which the Cloud SQL instance is accessible for private IP
+	PrivateNetwork			*string `hcl:"private_network"`
+	// Networks allowed to connect to the public IP of the instance
+	AuthorizedNetworks		[]SQLAuthorizedNetwork `hcl:"authorized_networks,block"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// SQLAuthorizedNetwork is a structure for Google Cloud SQL database instance authorized_networks blocks
+type SQLAuthorizedNetwork struct {
+	// A CIDR notation IPv4 or IPv6 address that is allowed to access this instance
+	Value					string `hcl:"value"`
+	// A name for this whitelist entry
+	Name					*string `hcl:"name"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// StorageBucket is a structure for Google Cloud Storage bucket resources
+type StorageBucket struct {
+	// The name of the bucket
+	Name					string `hcl:"name"`
+	// The GCS location
+	Location				string `hcl:"location"`
+	// Other arguments
+	Remain					hcl2.Body `hcl:",remain"`
+}
+
+// computeNode is an instance or a managed instance group, as seen by the firewall rules
+type computeNode struct {
+	// Key of the network of the node (see networkKey)
+	Network					string
+	// Address of the subnetwork of the node ("" if unknown)
+	Subnetwork				string
+	// IP ranges of the node
+	Cidrs					[]string
+	// Network tags of the node
+	Tags					[]string
+	// Service accounts of the node
+	ServiceAccounts			[]string
+	// true if the node has an external IP address
+	Public					bool
+}
+
+// DecodeResource decodes a single instance of a TF resource and adds it to Data.
+// Resources are indexed by their address (e.g. module.network.google_compute_subnetwork.private[0])
+func (a *Data) DecodeResource(r provider.Resource) bool {
+	return a.parseTfResource(r.Type, r.Address, r.Body, r.Ctx)
+}
+
+// resourceHandler decodes the resources of a TF type
+type resourceHandler struct {
+	// value is an empty structure of the type the resources are decoded in (e.g. Vpc{})
+	value		interface{}
+	// add adds a decoded resource to Data
+	add			func(a *Data, resourceType string, address string, value interface{})
+}
+
+// resourceHandlers are the handlers of the supported resource types, indexed by resource type
+var resourceHandlers = map[string]resourceHandler{
+	"google_compute_network":	{Network{}, (*Data).addNetwork},
+	"google_compute_subnetwork":	{Subnetwork{}, (*Data).addSubnetwork},
+	"google_compute_firewall":	{Firewall{}, (*Data).addFirewall},
+	"google_compute_instance":	{Instance{}, (*Data).addInstance},
+	"google_compute_instance_template":	{Instance{}, (*Data).addInstanceTemplate},
+	"google_compute_region_instance_template":	{Instance{}, (*Data).addInstanceTemplate},
+	"google_compute_instance_group_manager":	{InstanceGroupManager{}, (*Data).addInstanceGroupManager},
+	"google_compute_region_instance_group_manager":	{InstanceGroupManager{}, (*Data).addInstanceGroupManager},
+	"google_service_account":	{ServiceAccount{}, (*Data).addServiceAccount},
+	"google_sql_database_instance":	{SQLDatabaseInstance{}, (*Data).addSQLDatabaseInstance},
+	"google_storage_bucket":	{StorageBucket{}, (*Data).addStorageBucket},
+}
+
+// parseTfResource decodes a resource and returns false if its type is not supported.
+// body can come from HCL files (with ctx used for interpolation) or from JSON plans / states
+func (a *Data) parseTfResource(resourceType string, address string, body hcl2.Body, ctx *hcl2.EvalContext) bool {
+	handler, found := resourceHandlers[resourceType]
+	if !found {
+		a.log.Verbosef("Can't decode %s (not yet supported)", address)
+		return false
+	}
+	if ctx != nil {
+		// Expanding dynamic blocks (e.g. dynamic "allow") so they are decoded like literal blocks
+		body = dynblock.Expand(body, ctx)
+	}
+
+	a.log.Verbosef("Decoding %s", address)
+	value, diags := utils.DecodeResource(body, ctx, handler.value)
+	a.log.Diags(diags)
+	handler.add(a, resourceType, address, value)
+	return true
+}
+
+// addNetwork adds a decoded google_compute_network to Data
+func (a *Data) addNetwork(resourceType string, address string, value interface{}) {
+	gcpNetwork := value.(Network)
+
+	// Add Network to Data
+	a.Network[address] = gcpNetwork
+}
+
+// addSubnetwork adds a decoded google_compute_subnetwork to Data
+func (a *Data) addSubnetwork(resourceType string, address string, value interface{}) {
+	gcpSubnetwork := value.(Subnetwork)
+
+	// Add Subnetwork to Data
+	a.Subnetwork[address] = gcpSubnetwork
+}
+
+// addFirewall adds a decoded google_compute_firewall to Data
+func (a *Data) addFirewall(resourceType string, address string, value interface{}) {
+	gcpFirewall := value.(Firewall)
+
+	// Add Firewall to Data
+	a.Firewall[address] = gcpFirewall
+}
+
+// addInstance adds a decoded google_compute_instance to Data
+func (a *Data) addInstance(resourceType string, address string, value interface{}) {
+	gcpInstance := value.(Instance)
+
+	// Add Instance to Data
+	a.Instance[address] = gcpInstance
+}
+
+// addInstanceTemplate adds a decoded google_compute_instance_template or google_compute_region_instance_template to Data
+func (a *Data) addInstanceTemplate(resourceType string, address string, value interface{}) {
+	gcpInstanceTemplate := value.(Instance)
+
+	// Add InstanceTemplate to Data
+	a.InstanceTemplate[address] = gcpInstanceTemplate
+}
+
+// addInstanceGroupManager adds a decoded google_compute_instance_group_manager or google_compute_region_instance_group_manager to Data
+func (a *Data) addInstanceGroupManager(resourceType string, address string, value interface{}) {
+	gcpInstanceGroupManager := value.(InstanceGroupManager)
+
+	// Add InstanceGroupManager to Data
+	a.InstanceGroupManager[address] = gcpInstanceGroupManager
+}
+
+// addServiceAccount adds a decoded google_service_account to Data
+func (a *Data) addServiceAccount(resourceType string, address string, value interface{}) {
+	gcpServiceAccount := value.(ServiceAccount)
+
+	// Add ServiceAccount to Data (used to link instances and firewall rules)
+	a.ServiceAccount[address] = gcpServiceAccount
+}
+
+// addSQLDatabaseInstance adds a decoded google_sql_database_instance to Data
+func (a *Data) addSQLDatabaseInstance(resourceType string, address string, value interface{}) {
+	gcpSQLDatabaseInstance := value.(SQLDatabaseInstance)
+
+	// Add SQLDatabaseInstance to Data
+	a.SQLDatabaseInstance[address] = gcpSQLDatabaseInstance
+}
+
+// addStorageBucket adds a decoded google_storage_bucket to Data
+func (a *Data) addStorageBucket(resourceType string, address string, value interface{}) {
+	gcpStorageBucket := value.(StorageBucket)
+
+	// Add StorageBucket to Data
+	a.StorageBucket[address] = gcpStorageBucket
+}
+
+// ResolveResources links the instances and managed instance groups to their network tags and service accounts.
+// It must be called once all resources are parsed
+func (a *Data) ResolveResources() {
+	a.linkComputeNodes()
+}
+
+// networkKey returns the key of a network referenced by its address, its self_link or its name: its address if it
+// is defined in TF, its name otherwise (e.g. default)
+func (a *Data) networkKey(reference string) string {
+	if _, found := a.Network[referencedResource(reference)]; found {
+		return referencedResource(reference)
+	}
+	// projects/<project>/global/networks/<name>
+	name := reference[strings.LastIndex(reference, "/")+1:]
+	for networkAddress, network := range a.Network {
+		if network.Name == name {
+			return networkAddress
+		}
+	}
+	return name
+}
+
+// subnetworkAddress returns the address of a subnetwork referenced by its address, its self_link or its name
+// ("" if unknown)
+func (a *Data) subnetworkAddress(reference string) string {
+	if _, found := a.Subnetwork[referencedResource(reference)]; found {
+		return referencedResource(reference)
+	}
+	name := reference[strings.LastIndex(reference, "/")+1:]
+	for subnetworkAddress, subnetwork := range a.Subnetwork {
+		if subnetwork.Name == name {
+			return subnetworkAddress
+		}
+	}
+	return ""
+}
+
+// networkCidrs returns the IP ranges of a network: the ranges of its subnetworks, or the range of the
+// subnetworks created in auto mode
+func (a *Data) networkCidrs(networkKey string) []string {
+	var cidrs []string
+	for _, subnetwork := range a.Subnetwork {
+		if a.networkKey(subnetwork.Network) == networkKey && subnetwork.IPCidrRange != "" {
+			cidrs = append(cidrs, subnetwork.IPCidrRange)
+		}
+	}
+	if len(cidrs) == 0 {
+		return []string{autoModeRange}
+	}
+	sort.Strings(cidrs)
+	return cidrs
+}
+
+// newComputeNode returns the compute node of an instance or of the instances of an instance template, attached
+// to the network / subnetwork of their first network interface
+func (a *Data) newComputeNode(instance Instance) computeNode {
+	node := computeNode{Network: "default"}
+	if instance.Tags != nil {
+		node.Tags = *instance.Tags
+	}
+	for _, serviceAccount := range instance.ServiceAccount {
+		email := "default"
+		if serviceAccount.Email != nil && *serviceAccount.Email != "" {
+			email = referencedResource(*serviceAccount.Email)
+		}
+		node.ServiceAccounts = append(node.ServiceAccounts, email)
+	}
+	for i, networkInterface := range instance.NetworkInterfaces {
+		if len(networkInterface.AccessConfigs) > 0 {
+			node.Public = true
+		}
+		if i > 0 {
+			continue
+		}
+		if networkInterface.Subnetwork != nil {
+			node.Subnetwork = a.subnetworkAddress(*networkInterface.Subnetwork)
+		}
+		switch {
+		case networkInterface.Network != nil && *networkInterface.Network != "":
+			node.Network = a.networkKey(*networkInterface.Network)
+		case node.Subnetwork != "":
+			node.Network = a.networkKey(a.Subnetwork[node.Subnetwork].Network)
+		}
+		if networkInterface.NetworkIP != nil && net.ParseIP(*networkInterface.NetworkIP) != nil {
+			node.Cidrs = []string{*networkInterface.NetworkIP + "/32"}
+		}
+	}
+	if len(node.Cidrs) == 0 {
+		if node.Subnetwork != "" {
+			node.Cidrs = []string{a.Subnetwork[node.Subnetwork].IPCidrRange}
+		} else {
+			node.Cidrs = a.networkCidrs(node.Network)
+		}
+	}
+	return node
+}
+
+// groupTemplates returns the instance templates of the versions of a managed instance group
+func (a *Data) groupTemplates(igm InstanceGroupManager) []string {
+	var templates []string
+	for _, version := range igm.Versions {
+		if _, found := a.InstanceTemplate[referencedResource(version.InstanceTemplate)]; found {
+			templates = append(templates, referencedResource(version.InstanceTemplate))
+		}
+	}
+	return templates
+}
+
+// linkComputeNodes creates the network tag / service account - instance connections to facilitate the edges
+// creation for the graph. It must be called once all resources are parsed
+func (a *Data) linkComputeNodes() {
+	a.computeNodes = make(map[string]computeNode)
+	for instanceAddress, instance := range a.Instance {
+		a.computeNodes[instanceAddress] = a.newComputeNode(instance)
+	}
+	// Managed instance groups use the network, tags and service account of the template of their first version
+	for igmAddress, igm := range a.InstanceGroupManager {
+		node := computeNode{Network: "default", Cidrs: a.networkCidrs("default")}
+		if templates := a.groupTemplates(igm); len(templates) > 0 {
+			node = a.newComputeNode(a.InstanceTemplate[templates[0]])
+		}
+		a.computeNodes[igmAddress] = node
+	}
+
+	for address, node := range a.computeNodes {
+		for _, tag := range node.Tags {
+			a.TagNodeLinks[tag] = append(a.TagNodeLinks[tag], address)
+		}
+		for _, serviceAccount := range node.ServiceAccounts {
+			a.ServiceAccountNodeLinks[serviceAccount] = append(a.ServiceAccountNodeLinks[serviceAccount], address)
+		}
+	}
+}
+
+// computeParent returns the group of the subnetwork of a compute node, of its network, or of its module
+func (a *Data) computeParent(address string) string {
+	node := a.computeNodes[address]
+	if node.Subnetwork != "" {
+		return utils.NodeID(node.Subnetwork)
+	}
+	if _, found := a.Network[node.Network]; found {
+		return utils.NodeID(node.Network)
+	}
+	modulePath, _, _ := utils.SplitAddress(address)
+	return a.moduleGroup(modulePath)
+}
+
+// sqlIPConfiguration returns the IP configuration of a Cloud SQL instance
+func sqlIPConfiguration(sql SQLDatabaseInstance) SQLIPConfiguration {
+	for _, settings := range sql.Settings {
+		for _, ipConfiguration := range settings.IPConfiguration {
+			return ipConfiguration
+		}
+	}
+	return SQLIPConfiguration{}
+}
+
+// sqlPublic returns true if a Cloud SQL instance has a public IP address (default)
+func sqlPublic(sql SQLDatabaseInstance) bool {
+	ipConfiguration := sqlIPConfiguration(sql)
+	return ipConfiguration.IPv4Enabled == nil || *ipConfiguration.IPv4Enabled
+}
+
+// sqlPort returns the port of the database engine of a Cloud SQL instance
+func sqlPort(sql SQLDatabaseInstance) int {
+	switch {
+	case strings.HasPrefix(sql.DatabaseVersion, "POSTGRES"):
+		return 5432
+	case strings.HasPrefix(sql.DatabaseVersion, "SQLSERVER"):
+		return 1433
+	}
+	return 3306
+}
+
+// nodeLabel formats the label of a node: its name, a detail and "public IP" for the nodes reachable from the
+// Internet
+func nodeLabel(name string, detail string, public bool) string {
+	label := strings.Join(utils.ChunkString(name, 8), "\n")
+	if detail != "" {
+		label += "\n(" + detail + ")"
+	}
+	if public {
+		label += "\npublic IP"
+	}
+	return label
+}
+
+// addNode adds a node drawn with a Google Cloud icon to the graph
+func (a *Data) addNode(graph *model.Graph, parent string, address string, label string, icon string, public bool) (error) {
+	a.log.Verbosef("AddNode: %s to %s", utils.NodeID(address), parent)
+	_, resourceType, _ := utils.SplitAddress(address)
+	return graph.AddNode(&model.Node{
+		ID:			utils.NodeID(address),
+		Parent:		parent,
+		Kind:		resourceType,
+		Label:		label,
+		Address:	address,
+		Icon:		"gcp/icons/" + icon,
+		Public:		public,
+	})
+}
+
+func (a *Data) createNetwork(graph *model.Graph, networkAddress string, network Network) (error) {
+	// Create VPC network group
+	networkID := utils.NodeID(networkAddress)
+	modulePath, _, networkName := utils.SplitAddress(networkAddress)
+	if network.Name != "" {
+		networkName = network.Name
+	}
+	parent := a.moduleGroup(modulePath)
+	a.log.Verbosef("AddGroup: %s to %s // Create VPC network", networkID, parent)
+	label := "VPC network: " + utils.ModulePrefix(modulePath) + networkName
+	if network.AutoCreateSubnetworks == nil || *network.AutoCreateSubnetworks {
+		label += "\n(auto mode)"
+	}
+	return graph.AddGroup(&model.Group{
+		ID:			networkID,
+		Parent:		parent,
+		Kind:		model.NetworkGroup,
+		Label:		label,
+		Address:	networkAddress,
+	})
+}
+
+func (a *Data) createSubnetwork(graph *model.Graph, subnetworkAddress string, subnetwork Subnetwork) (error) {
+	// Create subnetwork group in its VPC network
+	subnetworkID := utils.NodeID(subnetworkAddress)
+	modulePath, _, subnetworkName := utils.SplitAddress(subnetworkAddress)
+	if subnetwork.Name != "" {
+		subnetworkName = subnetwork.Name
+	}
+	parent := a.moduleGroup(modulePath)
+	if _, found := a.Network[a.networkKey(subnetwork.Network)]; found {
+		parent = utils.NodeID(a.networkKey(subnetwork.Network))
+	}
+	a.log.Verbosef("AddGroup: %s to %s // Create Subnetwork", subnetworkID, parent)
+	label := "Subnet: " + utils.ModulePrefix(modulePath) + subnetworkName + "\n" + subnetwork.IPCidrRange
+	var attributes map[string]string
+	if subnetwork.Region != nil && *subnetwork.Region != "" {
+		label += "\n" + *subnetwork.Region
+		attributes = map[string]string{"region": *subnetwork.Region}
+	}
+	if subnetwork.PrivateIPGoogleAccess != nil && *subnetwork.PrivateIPGoogleAccess {
+		label += "\nPrivate Google Access"
+	}
+	return graph.AddGroup(&model.Group{
+		ID:			subnetworkID,
+		Parent:		parent,
+		Kind:		model.SubnetGroup,
+		Label:		label,
+		Address:	subnetworkAddress,
+		Attributes:	attributes,
+	})
+}
+
+// CreateGraphNodes creates the nodes for the graph
+func (a *Data) CreateGraphNodes(graph *model.Graph) (error) {
+	// Add VPC network groups to graph
+	for networkName, networkObj := range a.Network {
+		err := a.createNetwork(graph, networkName, networkObj)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add Subnetwork groups to graph
+	for subnetworkName, subnetworkObj := range a.Subnetwork {
+		err := a.createSubnetwork(graph, subnetworkName, subnetworkObj)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add compute instance nodes to graph
+	for instanceAddress, instance := range a.Instance {
+		_, _, name := utils.SplitAddress(instanceAddress)
+		if instance.Name != nil && *instance.Name != "" {
+			name = *instance.Name
+		}
+		public := a.computeNodes[instanceAddress].Public
+		err := a.addNode(graph, a.computeParent(instanceAddress), instanceAddress, nodeLabel(name, "", public), "instance.png", public)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add managed instance group nodes to graph
+	for igmAddress, igm := range a.InstanceGroupManager {
+		_, _, name := utils.SplitAddress(igmAddress)
+		if igm.Name != "" {
+			name = igm.Name
+		}
+		detail := "MIG"
+		if igm.TargetSize != nil {
+			detail = fmt.Sprintf("MIG %d", *igm.TargetSize)
+		}
+		public := a.computeNodes[igmAddress].Public
+		err := a.addNode(graph, a.computeParent(igmAddress), igmAddress, nodeLabel(name, detail, public), "mig.png", public)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add Cloud SQL nodes to graph, in the network of their private IP address
+	for sqlAddress, sql := range a.SQLDatabaseInstance {
+		modulePath, _, name := utils.SplitAddress(sqlAddress)
+		if sql.Name != nil && *sql.Name != "" {
+			name = *sql.Name
+		}
+		parent := a.moduleGroup(modulePath)
+		if privateNetwork := sqlIPConfiguration(sql).PrivateNetwork; privateNetwork != nil {
+			if _, found := a.Network[a.networkKey(*privateNetwork)]; found {
+				parent = utils.NodeID(a.networkKey(*privateNetwork))
+			}
+		}
+		err := a.addNode(graph, parent, sqlAddress, nodeLabel(name, strings.ToLower(sql.DatabaseVersion), sqlPublic(sql)), "sql.png", sqlPublic(sql))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add Cloud Storage bucket nodes to graph
+	for bucketAddress, bucket := range a.StorageBucket {
+		modulePath, _, name := utils.SplitAddress(bucketAddress)
+		if bucket.Name != "" {
+			name = bucket.Name
+		}
+		err := a.addNode(graph, a.moduleGroup(modulePath), bucketAddress, nodeLabel(name, strings.ToLower(bucket.Location), false), "bucket.png", false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateGraphEdges creates edges for the graph
+func (a *Data) CreateGraphEdges(graph *model.Graph) (error) {
+	// Link instances and managed instance groups with the firewall rules of their network
+	addresses := make([]string, 0, len(a.computeNodes))
+	for address := range a.computeNodes {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		// Parse Ingress firewall rules
+		if !a.options.IgnoreIngress {
+			err := a.parseFirewallRules(ingressRule, address, graph)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Parse Egress firewall rules
+		if !a.options.IgnoreEgress {
+			err := a.parseFirewallRules(egressRule, address, graph)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Link Cloud SQL instances with their authorized networks
+	err := a.createSQLEdges(graph)
+	if err != nil {
+		return err
+	}
+
+	// Add the edges (merged by source / destination) to the graph
+	return a.createFirewallEdges(graph)
+}
@@ -0,0 +1,55 @@
+package gcp
+
+import (
+	"strings"
+
+	"github.com/steeve85/tfviz/provider"
+	"github.com/steeve85/tfviz/utils"
+)
+
+
+func init() {
+	provider.Register("gcp", func(options provider.Options) provider.Provider {
+		return NewData(options)
+	})
+}
+
+// NewData returns an empty Data drawing a graph with these options
+func NewData(options provider.Options) *Data {
+	return &Data{
+		options:			options,
+		log:				options.Log,
+		Network:			make(map[string]Network),
+		Subnetwork:			make(map[string]Subnetwork),
+		Firewall:			make(map[string]Firewall),
+		Instance:			make(map[string]Instance),
+		InstanceTemplate:	make(map[string]Instance),
+		InstanceGroupManager:	make(map[string]InstanceGroupManager),
+		ServiceAccount:		make(map[string]ServiceAccount),
+		SQLDatabaseInstance:	make(map[string]SQLDatabaseInstance),
+		StorageBucket:		make(map[string]StorageBucket),
+		TagNodeLinks:		make(map[string][]string),
+		ServiceAccountNodeLinks:	make(map[string][]string),
+	}
+}
+
+// Claims returns true for the resources of the google provider
+func (a *Data) Claims(resourceType string) bool {
+	return strings.HasPrefix(resourceType, "google_")
+}
+
+// ReferencedAttributes returns the computed attributes used to reference Google Cloud resources
+func (a *Data) ReferencedAttributes() map[string][]string {
+	return referencedAttributes
+}
+
+// IDAttributes returns nil: Google Cloud resources are only identified by their id
+func (a *Data) IDAttributes() []string {
+	return nil
+}
+
+// ListAttribute returns true if the attribute at path (e.g. source_tags) of a resource type is decoded as a list
+func (a *Data) ListAttribute(resourceType string, path []string) bool {
+	handler, found := resourceHandlers[resourceType]
+	return found && utils.IsListAttribute(handler.value, path)
+}
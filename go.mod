@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/awalterschulze/gographviz v2.0.1+incompatible
+	github.com/hashicorp/go-version v1.2.0
 	github.com/hashicorp/hcl/v2 v2.6.0
 	github.com/hashicorp/terraform v0.12.29
 	github.com/zclconf/go-cty v1.5.1
@@ -0,0 +1,246 @@
+package model
+
+import (
+	"fmt"
+)
+
+
+// Kinds of groups
+const (
+	// ModuleGroup is a Terraform child module
+	ModuleGroup		= "module"
+	// NetworkGroup is a virtual network (AWS VPC, Azure virtual network, Google Cloud VPC network)
+	NetworkGroup	= "network"
+	// SubnetGroup is a subnet of a virtual network
+	SubnetGroup		= "subnet"
+)
+
+// Kinds of nodes not drawn with an icon
+const (
+	// InternetNode is the Internet, added to every graph
+	InternetNode		= "internet"
+	// CidrNode is an IP range outside of the networks of the graph
+	CidrNode			= "cidr"
+	// ExternalNode is a resource not defined in Terraform (e.g. the VPC of a peering connection)
+	ExternalNode		= "external"
+	// SecurityGroupNode is a security group not defined in Terraform
+	SecurityGroupNode	= "security_group"
+	// PrefixListNode is a managed prefix list
+	PrefixListNode		= "prefix_list"
+	// ServiceNode is a cloud service or an event source without icon (e.g. an Azure service tag)
+	ServiceNode			= "service"
+)
+
+// Kinds of edges
+const (
+	// RuleEdge is the traffic allowed by security rules (security groups, NSGs, firewall rules), LB listeners...
+	RuleEdge		= "rule"
+	// RouteEdge is a route from a subnet or a gateway
+	RouteEdge		= "route"
+	// ConnectionEdge is a connection between networks (VPC peering, Transit Gateway attachment, VPN)
+	ConnectionEdge	= "connection"
+	// EndpointEdge is the traffic going through a VPC endpoint
+	EndpointEdge	= "endpoint"
+	// TriggerEdge is an event source invoking a function
+	TriggerEdge		= "trigger"
+)
+
+// Directions of flows, relative to the node the rule applies to
+const (
+	Ingress		= "ingress"
+	Egress		= "egress"
+)
+
+// InternetID is the ID of the Internet node
+const InternetID = "Internet"
+
+// Group is a group of nodes: module, network or subnet. Groups can be nested
+type Group struct {
+	// ID of the group, used as parent by the nodes and groups it contains and by the edges linking the group
+	ID				string
+	// ID of the parent group ("" for top level groups)
+	Parent			string
+	// Kind of the group (module, network or subnet)
+	Kind			string
+	// Label of the group, including its details (e.g. its real ID)
+	Label			string
+	// Address of the TF resource or module ("" for groups not defined in TF)
+	Address			string
+	// Provider specific attributes (e.g. routing: public for a subnet routed to an Internet Gateway)
+	Attributes		map[string]string
+}
+
+// Node is a resource of the graph
+type Node struct {
+	// ID of the node (resources spanning several subnets have a node in each subnet)
+	ID				string
+	// ID of the parent group ("" for top level nodes)
+	Parent			string
+	// Kind of the node (e.g. ec2 or one of the kinds of nodes without icon)
+	Kind			string
+	// Label of the node, including its details (e.g. its engine)
+	Label			string
+	// Address of the TF resource ("" for nodes not defined in TF)
+	Address			string
+	// Icon is the path of the image of the node, relative to the icon directory of the renderer (e.g.
+	// aws/icons/ec2.png). "" for nodes without icon, renderers choose the image of the Internet node if it is not set
+	Icon			string
+	// Public is true for resources reachable from the Internet (e.g. publicly accessible DB)
+	Public			bool
+	// Provider specific attributes
+	Attributes		map[string]string
+}
+
+// Flow is a traffic (or an invocation) allowed between the source and destination of an edge
+type Flow struct {
+	// Protocol (e.g. tcp) or all
+	Protocol		string
+	// Ports (e.g. 22, 80-443 or all), empty for all the ports or protocols without port
+	Ports			string
+	// Description of the flows that are not a protocol / port range (e.g. LB listeners)
+	Description		string
+	// Ingress or Egress, for flows allowed by a security rule
+	Direction		string
+	// Address of the rule (or of the security group) allowing the flow
+	Rule			string
+	// Reason why the flow is denied (e.g. blocked by NACL), empty if it is allowed
+	Blocked			string
+}
+
+// NewFlow returns the flow allowed by a rule for a protocol ("*" for all the protocols) and a destination port
+// range (e.g. tcp/22, tcp/80-443 or all). Only tcp, udp and sctp flows (or any protocol on a part of the
+// ports) have ports
+func NewFlow(protocol string, fromPort int, toPort int, direction string, rule string) *Flow {
+	f := &Flow{
+		Protocol:	protocol,
+		Direction:	direction,
+		Rule:		rule,
+	}
+	allPorts := fromPort == 0 && toPort == 65535
+	switch protocol {
+	case "*":
+		if allPorts {
+			f.Protocol = "all"
+			return f
+		}
+		f.Protocol = "any"
+	case "tcp", "udp", "sctp":
+	default:
+		return f
+	}
+	switch {
+	case fromPort == toPort:
+		f.Ports = fmt.Sprintf("%d", fromPort)
+	case allPorts:
+		f.Ports = "all"
+	default:
+		f.Ports = fmt.Sprintf("%d-%d", fromPort, toPort)
+	}
+	return f
+}
+
+// Edge is a link between two nodes or groups
+type Edge struct {
+	// ID of the source node or group
+	Src				string
+	// ID of the destination node or group
+	Dst				string
+	// Kind of the edge (rule, route, connection, endpoint or trigger)
+	Kind			string
+	// Label of the edges not created from flows (e.g. the destination of a route)
+	Label			string
+	// Flows allowed by the rules merged in the edge
+	Flows			[]Flow
+	// Public is true for the traffic from / to the Internet
+	Public			bool
+}
+
+// Blocked returns true if all the flows of an edge are denied
+func (e *Edge) Blocked() bool {
+	if len(e.Flows) == 0 {
+		return false
+	}
+	for _, f := range e.Flows {
+		if f.Blocked == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Graph is the topology of the resources, independent of the output format. Groups and nodes are listed in the
+// order they are added, parent groups before their children
+type Graph struct {
+	Groups			[]*Group
+	Nodes			[]*Node
+	Edges			[]*Edge
+	groups			map[string]*Group
+	nodes			map[string]*Node
+}
+
+// New returns a graph with the Internet node
+func New() *Graph {
+	g := &Graph{
+		groups:		make(map[string]*Group),
+		nodes:		make(map[string]*Node),
+	}
+	g.AddNode(&Node{
+		ID:		InternetID,
+		Kind:	InternetNode,
+		Label:	"Internet",
+	})
+	return g
+}
+
+// AddGroup adds a group to the graph. Adding a group that already exists does nothing
+func (g *Graph) AddGroup(group *Group) (error) {
+	if _, found := g.groups[group.ID]; found {
+		return nil
+	}
+	if group.Parent != "" && !g.IsGroup(group.Parent) {
+		return fmt.Errorf("can't add group %s: unknown parent group %s", group.ID, group.Parent)
+	}
+	g.groups[group.ID] = group
+	g.Groups = append(g.Groups, group)
+	return nil
+}
+
+// AddNode adds a node to the graph. Adding a node that already exists does nothing
+func (g *Graph) AddNode(node *Node) (error) {
+	if _, found := g.nodes[node.ID]; found {
+		return nil
+	}
+	if node.Parent != "" && !g.IsGroup(node.Parent) {
+		return fmt.Errorf("can't add node %s: unknown parent group %s", node.ID, node.Parent)
+	}
+	g.nodes[node.ID] = node
+	g.Nodes = append(g.Nodes, node)
+	return nil
+}
+
+// AddEdge adds an edge to the graph
+func (g *Graph) AddEdge(edge *Edge) {
+	g.Edges = append(g.Edges, edge)
+}
+
+// IsGroup returns true if the graph has a group with this ID
+func (g *Graph) IsGroup(id string) bool {
+	_, found := g.groups[id]
+	return found
+}
+
+// IsNode returns true if the graph has a node with this ID
+func (g *Graph) IsNode(id string) bool {
+	_, found := g.nodes[id]
+	return found
+}
+
+// Group returns a group from its ID (nil if not found)
+func (g *Graph) Group(id string) *Group {
+	return g.groups[id]
+}
+
+// Node returns a node from its ID (nil if not found)
+func (g *Graph) Node(id string) *Node {
+	return g.nodes[id]
+}
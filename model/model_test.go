@@ -0,0 +1,75 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNewFlow(t *testing.T) {
+	tests := []struct {
+		protocol	string
+		fromPort	int
+		toPort		int
+		want		string
+	}{
+		{"tcp", 22, 22, "tcp/22"},
+		{"udp", 80, 443, "udp/80-443"},
+		{"sctp", 0, 65535, "sctp/all"},
+		{"*", 0, 65535, "all/"},
+		{"*", 22, 22, "any/22"},
+		{"icmp", 0, 65535, "icmp/"},
+		{"esp", 0, 65535, "esp/"},
+	}
+	for _, test := range tests {
+		f := NewFlow(test.protocol, test.fromPort, test.toPort, Egress, "rule")
+		if got := f.Protocol + "/" + f.Ports; got != test.want {
+			t.Errorf("NewFlow(%s, %d, %d) = %s, want %s", test.protocol, test.fromPort, test.toPort, got, test.want)
+		}
+		if f.Direction != Egress || f.Rule != "rule" {
+			t.Errorf("NewFlow(%s, %d, %d) = %+v, direction and rule not set", test.protocol, test.fromPort, test.toPort, f)
+		}
+	}
+}
+
+func TestGraphParents(t *testing.T) {
+	g := New()
+	if err := g.AddNode(&Node{ID: "web", Parent: "subnet"}); err == nil {
+		t.Error("node added to an unknown group")
+	}
+	if err := g.AddGroup(&Group{ID: "subnet", Parent: "vpc"}); err == nil {
+		t.Error("group added to an unknown group")
+	}
+	if err := g.AddGroup(&Group{ID: "vpc", Kind: NetworkGroup}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddGroup(&Group{ID: "subnet", Parent: "vpc", Kind: SubnetGroup}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AddNode(&Node{ID: "web", Parent: "subnet"}); err != nil {
+		t.Fatal(err)
+	}
+	// Adding a node twice keeps the first one
+	if err := g.AddNode(&Node{ID: "web", Parent: "vpc"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 2 || g.Node("web").Parent != "subnet" {
+		t.Errorf("got nodes %+v, want Internet and web in the subnet", g.Nodes)
+	}
+}
+
+func TestEdgeBlocked(t *testing.T) {
+	tests := []struct {
+		flows	[]Flow
+		blocked	bool
+	}{
+		{nil, false},
+		{[]Flow{{Protocol: "tcp", Ports: "22"}}, false},
+		{[]Flow{{Protocol: "tcp", Ports: "22", Blocked: "blocked by NACL"}}, true},
+		{[]Flow{{Protocol: "tcp", Ports: "22", Blocked: "blocked by NACL"}, {Protocol: "tcp", Ports: "443"}}, false},
+	}
+	for _, test := range tests {
+		e := &Edge{Flows: test.flows}
+		if e.Blocked() != test.blocked {
+			t.Errorf("Blocked() of %+v = %v, want %v", test.flows, e.Blocked(), test.blocked)
+		}
+	}
+}
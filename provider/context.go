@@ -0,0 +1,308 @@
+package provider
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	tfconfigs "github.com/hashicorp/terraform/configs"
+	"github.com/hashicorp/terraform/lang"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+
+	"github.com/steeve85/tfviz/utils"
+)
+
+
+// InitiateVariablesAndResources parses TF modules to create Variables / Obj references for interpolation
+// It returns the EvalContext of each module of the configuration, indexed by module path ("" for the root module)
+// inputVariables are the -var / -var-file command line arguments and environment the "key=value" environment
+// variables (e.g. os.Environ()) read for TF_VAR_ variables
+func (s *Set) InitiateVariablesAndResources(tfConfig *tfconfigs.Config, inputVariables []utils.InputVariable, environment []string) (map[string]*hcl2.EvalContext, error) {
+	inputs, err := s.rootVariables(tfConfig.Module, inputVariables, environment)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxs := make(map[string]*hcl2.EvalContext)
+	_, err = s.initiateModule(tfConfig, inputs, ctxs)
+	if err != nil {
+		return nil, err
+	}
+	return ctxs, nil
+}
+
+// rootVariables loads the values of the root module variables, from the lowest to the highest precedence:
+// TF_VAR_ environment variables, terraform.tfvars(.json), *.auto.tfvars(.json) and -var / -var-file flags
+func (s *Set) rootVariables(tfModule *tfconfigs.Module, inputVariables []utils.InputVariable, environment []string) (map[string]cty.Value, error) {
+	values := make(map[string]cty.Value)
+
+	// Environment variables (undeclared variables are ignored like Terraform does)
+	for _, env := range environment {
+		if !strings.HasPrefix(env, "TF_VAR_") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(env, "TF_VAR_"), "=", 2)
+		v, found := tfModule.Variables[parts[0]]
+		if !found || len(parts) != 2 {
+			continue
+		}
+		value, diags := v.ParsingMode.Parse(v.Name, parts[1])
+		s.log.Diags(diags)
+		if !diags.HasErrors() {
+			values[v.Name] = value
+		}
+	}
+
+	// Load variables from Variable Definitions (.tfvars) Files
+	// Start with terraform.tfvars file, then .auto.tfvars files in lexical order
+	var variablesFiles []string
+	for _, f := range []string{"terraform.tfvars", "terraform.tfvars.json"} {
+		inputVariablesFile := path.Join(tfModule.SourceDir, f)
+		if _, err := os.Stat(inputVariablesFile); err == nil {
+			variablesFiles = append(variablesFiles, inputVariablesFile)
+		}
+	}
+	files, err := ioutil.ReadDir(tfModule.SourceDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".auto.tfvars") || strings.HasSuffix(f.Name(), ".auto.tfvars.json") {
+			variablesFiles = append(variablesFiles, path.Join(tfModule.SourceDir, f.Name()))
+		}
+	}
+	for _, f := range variablesFiles {
+		vars, diags := tfconfigs.NewParser(nil).LoadValuesFile(f)
+		s.log.Diags(diags)
+		for varName, varValue := range vars {
+			values[varName] = varValue
+		}
+	}
+
+	// -var and -var-file flags, in the order they were given
+	for _, i := range inputVariables {
+		switch i.Flag {
+		case "var-file":
+			if _, err := os.Stat(i.Value); err != nil {
+				return nil, err
+			}
+			vars, diags := tfconfigs.NewParser(nil).LoadValuesFile(i.Value)
+			s.log.Diags(diags)
+			for varName, varValue := range vars {
+				values[varName] = varValue
+			}
+		case "var":
+			parts := strings.SplitN(i.Value, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid -var option %q: the value must be given as name=value", i.Value)
+			}
+			v, found := tfModule.Variables[parts[0]]
+			if !found {
+				return nil, fmt.Errorf("variable %q set with -var is not declared in the root module", parts[0])
+			}
+			value, diags := v.ParsingMode.Parse(v.Name, parts[1])
+			s.log.Diags(diags)
+			if !diags.HasErrors() {
+				values[v.Name] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+// initiateModule creates the EvalContext of a module and returns the module outputs.
+// inputs are the values of the module variables: the module call arguments for child modules,
+// or the values from the environment, .tfvars files and command line for the root module
+func (s *Set) initiateModule(tfConfig *tfconfigs.Config, inputs map[string]cty.Value, ctxs map[string]*hcl2.EvalContext) (cty.Value, error) {
+	tfModule := tfConfig.Module
+	prefix := utils.ModulePrefix(tfConfig.Path.UnkeyedInstanceShim().String())
+
+	// Terraform built-in functions (file functions are relative to the module directory)
+	functions := (&lang.Scope{BaseDir: tfModule.SourceDir}).Functions()
+
+	// Create map for EvalContext to replace variables names by their values inside HCL file using DecodeBody
+	ctxVariables := make(map[string]cty.Value)
+
+	// Prepare context with TF variables
+	for _, v := range tfModule.Variables {
+		// Handling the case there is no default value for the variable
+		if v.Default.IsNull() {
+			ctxVariables[v.Name] = cty.StringVal("var_" + v.Name)
+		} else {
+			ctxVariables[v.Name] = v.Default
+		}
+	}
+
+	// Variables set by the module call (or from the environment, .tfvars files and command line for the root module)
+	for varName, varValue := range inputs {
+		ctxVariables[varName] = varValue
+	}
+
+	// Prepare context with local values, named values to resources and module outputs
+	// locals / count / for_each / module arguments may reference each other, so they are
+	// added to the context as soon as they can be evaluated (i.e. in dependency order)
+	ctxLocals := make(map[string]cty.Value)
+	ctxResources := make(map[string]map[string]cty.Value)
+	ctxModules := make(map[string]cty.Value)
+	var pendingLocals []*tfconfigs.Local
+	for _, v := range tfModule.Locals {
+		pendingLocals = append(pendingLocals, v)
+	}
+	sort.Slice(pendingLocals, func(i, j int) bool {
+		return pendingLocals[i].Name < pendingLocals[j].Name
+	})
+	var pendingResources []*tfconfigs.Resource
+	for _, v := range tfModule.ManagedResources {
+		pendingResources = append(pendingResources, v)
+	}
+	sort.Slice(pendingResources, func(i, j int) bool {
+		return pendingResources[i].Addr().String() < pendingResources[j].Addr().String()
+	})
+	var pendingCalls []*tfconfigs.ModuleCall
+	for _, v := range tfModule.ModuleCalls {
+		if _, found := tfConfig.Children[v.Name]; !found {
+			// The module could not be loaded, its outputs are unknown
+			ctxModules[v.Name] = cty.DynamicVal
+			continue
+		}
+		pendingCalls = append(pendingCalls, v)
+	}
+	sort.Slice(pendingCalls, func(i, j int) bool {
+		return pendingCalls[i].Name < pendingCalls[j].Name
+	})
+
+	for len(pendingLocals) > 0 || len(pendingResources) > 0 || len(pendingCalls) > 0 {
+		ctx := newEvalContext(functions, ctxVariables, ctxLocals, ctxResources, ctxModules)
+		var nextLocals []*tfconfigs.Local
+		for _, v := range pendingLocals {
+			value, diags := v.Expr.Value(ctx)
+			if diags.HasErrors() {
+				nextLocals = append(nextLocals, v)
+				continue
+			}
+			ctxLocals[v.Name] = value
+		}
+		var nextResources []*tfconfigs.Resource
+		for _, v := range pendingResources {
+			instances, diags := utils.ExpandResource(v, ctx)
+			if diags.HasErrors() {
+				nextResources = append(nextResources, v)
+				continue
+			}
+			s.addResourceToContext(ctxResources, prefix, v, instances)
+		}
+		var nextCalls []*tfconfigs.ModuleCall
+		for _, v := range pendingCalls {
+			args, diags := moduleArguments(v, ctx)
+			if diags.HasErrors() {
+				nextCalls = append(nextCalls, v)
+				continue
+			}
+			outputs, err := s.initiateModule(tfConfig.Children[v.Name], args, ctxs)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			ctxModules[v.Name] = outputs
+		}
+
+		if len(nextLocals) == len(pendingLocals) && len(nextResources) == len(pendingResources) && len(nextCalls) == len(pendingCalls) {
+			// No progress: the remaining locals are unknown, the remaining resources can't be expanded
+			// and are considered as single instances, the remaining module calls only get the arguments
+			// that could be evaluated
+			for _, v := range nextLocals {
+				_, diags := v.Expr.Value(ctx)
+				s.log.Diags(diags)
+				ctxLocals[v.Name] = cty.DynamicVal
+			}
+			ctx = newEvalContext(functions, ctxVariables, ctxLocals, ctxResources, ctxModules)
+			for _, v := range nextResources {
+				instances, diags := utils.ExpandResource(v, ctx)
+				s.log.Diags(diags)
+				s.addResourceToContext(ctxResources, prefix, v, instances)
+			}
+			for _, v := range nextCalls {
+				args, diags := moduleArguments(v, ctx)
+				s.log.Diags(diags)
+				outputs, err := s.initiateModule(tfConfig.Children[v.Name], args, ctxs)
+				if err != nil {
+					return cty.NilVal, err
+				}
+				ctxModules[v.Name] = outputs
+			}
+			break
+		}
+		pendingLocals = nextLocals
+		pendingResources = nextResources
+		pendingCalls = nextCalls
+	}
+
+	ctx := newEvalContext(functions, ctxVariables, ctxLocals, ctxResources, ctxModules)
+	ctxs[tfConfig.Path.UnkeyedInstanceShim().String()] = ctx
+
+	// Module outputs used by the parent module (module.<name>.<output>)
+	outputs := make(map[string]cty.Value)
+	for _, v := range tfModule.Outputs {
+		value, diags := v.Expr.Value(ctx)
+		if diags.HasErrors() {
+			if s.log.Verbose == true {
+				s.log.Diags(diags)
+			}
+			value = cty.DynamicVal
+		}
+		outputs[v.Name] = value
+	}
+	return cty.ObjectVal(outputs), nil
+}
+
+// moduleArguments evaluates the arguments of a module call in the context of the calling module
+func moduleArguments(call *tfconfigs.ModuleCall, ctx *hcl2.EvalContext) (map[string]cty.Value, hcl2.Diagnostics) {
+	args := make(map[string]cty.Value)
+	attrs, diags := call.Config.JustAttributes()
+	for name, attr := range attrs {
+		value, moreDiags := attr.Expr.Value(ctx)
+		diags = append(diags, moreDiags...)
+		if !moreDiags.HasErrors() {
+			args[name] = value
+		}
+	}
+	return args, diags
+}
+
+func (s *Set) addResourceToContext(ctxResources map[string]map[string]cty.Value, prefix string, r *tfconfigs.Resource, instances []utils.ResourceInstance) {
+	if _, found := ctxResources[r.Type]; !found {
+		ctxResources[r.Type] = make(map[string]cty.Value)
+	}
+	idAttributes := s.idAttributes()
+	referencedAttributes := s.referencedAttributes()[r.Type]
+	ctxResources[r.Type][r.Name] = utils.ResourceValue(prefix, r, instances, func(id string) cty.Value {
+		attrs := make(map[string]cty.Value)
+		for _, attr := range idAttributes {
+			attrs[attr] = cty.StringVal(id)
+		}
+		for _, attr := range referencedAttributes {
+			attrs[attr] = cty.StringVal(id + "." + attr)
+		}
+		return cty.ObjectVal(attrs)
+	})
+}
+
+func newEvalContext(functions map[string]function.Function, ctxVariables map[string]cty.Value, ctxLocals map[string]cty.Value, ctxResources map[string]map[string]cty.Value, ctxModules map[string]cty.Value) *hcl2.EvalContext {
+	ctx := &hcl2.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(ctxVariables),
+			"local": cty.ObjectVal(ctxLocals),
+			"module": cty.ObjectVal(ctxModules),
+		},
+		Functions: functions,
+	}
+	for resourceType, resources := range ctxResources {
+		ctx.Variables[resourceType] = cty.ObjectVal(resources)
+	}
+	return ctx
+}
+
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/steeve85/tfviz/utils"
+)
+
+func TestRootVariables(t *testing.T) {
+	// Each variable is set by the source it is named after and by the sources of lower precedence
+	var environment []string
+	for _, name := range []string{"env", "tfvars", "auto", "file", "flag", "undeclared"} {
+		environment = append(environment, "TF_VAR_"+name+"=env")
+	}
+	tfConfig, err := utils.ParseTFfile(filepath.Join("testdata", "variables"), &utils.Logger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := New(Options{Log: &utils.Logger{}}).rootVariables(tfConfig.Module, []utils.InputVariable{
+		{Flag: "var", Value: "flag=flag"},
+		{Flag: "var-file", Value: filepath.Join("testdata", "variables", "extra.tfvars")},
+	}, environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"env", "tfvars", "auto", "file"} {
+		if v, found := values[name]; !found || v.AsString() != name {
+			t.Errorf("var.%s = %#v, want %q", name, v, name)
+		}
+	}
+	// -var and -var-file are applied in the order they are given
+	if v := values["flag"]; v.AsString() != "file" {
+		t.Errorf("var.flag = %#v, want %q", v, "file")
+	}
+	if _, found := values["undeclared"]; found {
+		t.Errorf("undeclared variable set from the environment")
+	}
+}
+
+func TestRootVariablesErrors(t *testing.T) {
+	tfConfig, err := utils.ParseTFfile(filepath.Join("testdata", "variables"), &utils.Logger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []utils.InputVariable{
+		{Flag: "var", Value: "flag"},
+		{Flag: "var", Value: "undeclared=value"},
+		{Flag: "var-file", Value: filepath.Join("testdata", "variables", "missing.tfvars")},
+	} {
+		if _, err := New(Options{Log: &utils.Logger{}}).rootVariables(tfConfig.Module, []utils.InputVariable{i}, nil); err == nil {
+			t.Errorf("-%s %s: no error", i.Flag, i.Value)
+		}
+	}
+}
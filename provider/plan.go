@@ -0,0 +1,466 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	hcljson "github.com/hashicorp/hcl/v2/json"
+
+	"github.com/steeve85/tfviz/utils"
+)
+
+// jsonPlan is the output of `terraform show -json <planfile>`
+type jsonPlan struct {
+	PlannedValues		jsonValues `json:"planned_values"`
+	ResourceChanges		[]jsonResourceChange `json:"resource_changes"`
+	Configuration		jsonConfiguration `json:"configuration"`
+}
+
+// jsonValues is the representation of resources values in plans and states
+type jsonValues struct {
+	RootModule			jsonModule `json:"root_module"`
+}
+
+// jsonModule is a module in planned_values (or in a state)
+type jsonModule struct {
+	Address				string `json:"address"`
+	Resources			[]jsonResource `json:"resources"`
+	ChildModules		[]jsonModule `json:"child_modules"`
+}
+
+// jsonResource is a resource instance in planned_values (or in a state)
+type jsonResource struct {
+	Address				string `json:"address"`
+	Mode				string `json:"mode"`
+	Type				string `json:"type"`
+	Name				string `json:"name"`
+	Index				interface{} `json:"index"`
+	Values				map[string]interface{} `json:"values"`
+}
+
+// jsonResourceChange is a planned change of a resource instance
+type jsonResourceChange struct {
+	Address				string `json:"address"`
+	ModuleAddress		string `json:"module_address"`
+	Change				struct {
+		Actions			[]string `json:"actions"`
+		// AfterUnknown has the same structure as the values, with true for the values known after apply only
+		AfterUnknown	map[string]interface{} `json:"after_unknown"`
+	} `json:"change"`
+}
+
+// jsonConfiguration is the configuration of the plan (expressions are not evaluated but list their references)
+type jsonConfiguration struct {
+	RootModule			jsonConfigModule `json:"root_module"`
+}
+
+// jsonConfigModule is a module in the plan configuration
+type jsonConfigModule struct {
+	Resources			[]jsonConfigResource `json:"resources"`
+	ModuleCalls			map[string]jsonModuleCall `json:"module_calls"`
+	Outputs				map[string]jsonConfigOutput `json:"outputs"`
+}
+
+// jsonConfigResource is a resource in the plan configuration
+type jsonConfigResource struct {
+	Address				string `json:"address"`
+	Mode				string `json:"mode"`
+	// Expressions of the resource arguments: attributes are {"references": [...]} objects
+	// and nested blocks are lists of expressions objects
+	Expressions			map[string]interface{} `json:"expressions"`
+}
+
+// jsonModuleCall is a module call in the plan configuration
+type jsonModuleCall struct {
+	Expressions			map[string]interface{} `json:"expressions"`
+	Module				jsonConfigModule `json:"module"`
+}
+
+// jsonConfigOutput is a module output in the plan configuration
+type jsonConfigOutput struct {
+	Expression			map[string]interface{} `json:"expression"`
+}
+
+// ParseTfPlan decodes the resources of a plan in JSON format (`terraform show -json <planfile>`).
+// Values known after apply only are replaced by the addresses of the resources they reference
+func (s *Set) ParseTfPlan(planPath string) (error) {
+	s.log.Progressf("Loading %s Terraform plan...", planPath)
+	content, err := ioutil.ReadFile(planPath)
+	if err != nil {
+		return err
+	}
+	var plan jsonPlan
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	err = decoder.Decode(&plan)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid JSON plan: %s", planPath, err)
+	}
+
+	changes := make(map[string]jsonResourceChange)
+	for _, c := range plan.ResourceChanges {
+		changes[c.Address] = c
+	}
+
+	resources := flattenJSONModule(plan.PlannedValues.RootModule)
+	instances := make(map[string][]string)
+	for _, r := range resources {
+		base := resourceBaseAddress(r.Address)
+		instances[base] = append(instances[base], r.Address)
+	}
+
+	for _, r := range resources {
+		change, found := changes[r.Address]
+		if !found || len(change.Change.AfterUnknown) == 0 {
+			continue
+		}
+		modulePath, _, _ := utils.SplitAddress(r.Address)
+		configModule, found := plan.Configuration.RootModule.descendant(modulePath)
+		if !found {
+			continue
+		}
+		configResource, found := configModule.resource(strings.TrimPrefix(resourceBaseAddress(r.Address), utils.ModulePrefix(modulePath)))
+		if !found {
+			continue
+		}
+		resolve := func(refs []string) []string {
+			return plan.Configuration.RootModule.resolveReferences(modulePath, refs, r.Index, instances)
+		}
+		isList := func(path []string) bool {
+			return s.listAttribute(r.Type, path)
+		}
+		fillUnknownValues(r.Values, change.Change.AfterUnknown, configResource.Expressions, resolve, isList, nil)
+	}
+
+	return s.parseJSONResources(resources)
+}
+
+// parseJSONResources decodes resources values from a plan or a state and dispatches them to the providers.
+// The real IDs / ARNs referenced by resources are replaced by the addresses of these resources
+func (s *Set) parseJSONResources(resources []jsonResource) (error) {
+	ids := make(map[string]string)
+	idAttributes := s.idAttributes()
+	for _, r := range resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		for _, attr := range idAttributes {
+			if id, ok := r.Values[attr].(string); ok && id != "" {
+				ids[id] = r.Address
+			}
+		}
+	}
+	// Computed attributes referencing other resources (e.g. the main route table of a VPC) take precedence
+	// over the resources having the same ID (e.g. aws_default_route_table)
+	attrIDs := make(map[string]bool)
+	referencedAttributes := s.referencedAttributes()
+	for _, r := range resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		for _, attr := range referencedAttributes[r.Type] {
+			if id, ok := r.Values[attr].(string); ok && id != "" && !attrIDs[id] {
+				ids[id] = r.Address + "." + attr
+				attrIDs[id] = true
+			}
+		}
+	}
+
+	for _, r := range resources {
+		if r.Mode != "managed" || r.Values == nil {
+			continue
+		}
+		values := replaceIDs(removeNullValues(r.Values), ids).(map[string]interface{})
+		// The own ID / ARN / name of the resource must not be replaced by its address
+		for _, attr := range append(idAttributes, referencedAttributes[r.Type]...) {
+			if v, found := r.Values[attr]; found && v != nil {
+				values[attr] = v
+			}
+		}
+		content, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		file, diags := hcljson.Parse(content, r.Address)
+		if diags.HasErrors() {
+			s.log.Diags(diags)
+			continue
+		}
+		id, _ := r.Values["id"].(string)
+		s.decodeResource(Resource{
+			Type:		r.Type,
+			Address:	r.Address,
+			Body:		file.Body,
+			ID:			id,
+		})
+	}
+	s.resolveResources()
+	return nil
+}
+
+// flattenJSONModule returns the resources of a module and its child modules
+func flattenJSONModule(module jsonModule) []jsonResource {
+	resources := module.Resources
+	for _, c := range module.ChildModules {
+		resources = append(resources, flattenJSONModule(c)...)
+	}
+	return resources
+}
+
+// resourceBaseAddress removes the instance key from a resource address (aws_subnet.private[0] => aws_subnet.private)
+func resourceBaseAddress(address string) string {
+	modulePath, resourceType, name := utils.SplitAddress(address)
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return utils.ModulePrefix(modulePath) + resourceType + "." + name
+}
+
+// descendant returns the configuration of a child module from its path (e.g. module.vpc.module.subnets)
+func (m jsonConfigModule) descendant(modulePath string) (jsonConfigModule, bool) {
+	if modulePath == "" {
+		return m, true
+	}
+	tokens := strings.Split(modulePath, ".")
+	current := m
+	for i := 1; i < len(tokens); i += 2 {
+		// Module instance keys (module.vpc[0]) are not part of the configuration
+		name := tokens[i]
+		if j := strings.Index(name, "["); j >= 0 {
+			name = name[:j]
+		}
+		call, found := current.ModuleCalls[name]
+		if !found {
+			return jsonConfigModule{}, false
+		}
+		current = call.Module
+	}
+	return current, true
+}
+
+// resource returns the configuration of a resource from its address in the module
+func (m jsonConfigModule) resource(address string) (jsonConfigResource, bool) {
+	for _, r := range m.Resources {
+		if r.Address == address && r.Mode == "managed" {
+			return r, true
+		}
+	}
+	return jsonConfigResource{}, false
+}
+
+// resolveReferences follows the references of an expression in a module (through module variables and
+// outputs) and returns the addresses of the resource instances they point to.
+// index is the instance key of the resource using the expression and is used to pick the instance of
+// resources referenced with count.index / each.key
+func (m jsonConfigModule) resolveReferences(modulePath string, refs []string, index interface{}, instances map[string][]string) []string {
+	var addresses []string
+	for _, ref := range refs {
+		tokens := strings.Split(ref, ".")
+		switch {
+		case tokens[0] == "var" && len(tokens) > 1 && modulePath != "":
+			// Module variable: following the argument of the module call in the parent module
+			parts := strings.Split(modulePath, ".")
+			parentPath := strings.Join(parts[:len(parts)-2], ".")
+			parent, found := m.descendant(parentPath)
+			if !found {
+				continue
+			}
+			call := parent.ModuleCalls[parts[len(parts)-1]]
+			addresses = append(addresses, m.resolveReferences(parentPath, expressionReferences(call.Expressions[tokens[1]]), index, instances)...)
+		case tokens[0] == "module" && len(tokens) > 2:
+			// Module output: following the output expression in the child module
+			childPath := utils.ModulePrefix(modulePath) + "module." + tokens[1]
+			child, found := m.descendant(childPath)
+			if !found {
+				continue
+			}
+			output := child.Outputs[tokens[2]]
+			addresses = append(addresses, m.resolveReferences(childPath, expressionReferences(output.Expression), index, instances)...)
+		case tokens[0] == "var" || tokens[0] == "local" || tokens[0] == "data" || tokens[0] == "count" ||
+			tokens[0] == "each" || tokens[0] == "path" || tokens[0] == "terraform" || tokens[0] == "self":
+			continue
+		case len(tokens) > 1:
+			// Resource (instance) reference
+			address := utils.ModulePrefix(modulePath) + tokens[0] + "." + tokens[1]
+			if strings.Contains(tokens[1], "[") {
+				addresses = append(addresses, address)
+				continue
+			}
+			// The same reference is listed for each step (aws_subnet.a, aws_subnet.a[0]...), skipping the
+			// resource if one of its instances is already referenced
+			if hasReferencedInstance(refs, tokens[0]+"."+tokens[1]) {
+				continue
+			}
+			candidates := instances[address]
+			if len(candidates) == 1 {
+				addresses = append(addresses, candidates[0])
+				continue
+			}
+			// Instance with the same key as the resource using it (count.index / each.key)
+			matched := false
+			if index != nil {
+				for _, c := range candidates {
+					if c == address+instanceKey(index) {
+						addresses = append(addresses, c)
+						matched = true
+					}
+				}
+			}
+			if !matched {
+				addresses = append(addresses, candidates...)
+			}
+		}
+	}
+	return utils.RemoveDuplicateValues(addresses)
+}
+
+func hasReferencedInstance(refs []string, resource string) bool {
+	for _, ref := range refs {
+		if strings.HasPrefix(ref, resource+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceKey formats an instance index from a plan or state as in a resource address ([0] or ["a"])
+func instanceKey(index interface{}) string {
+	switch i := index.(type) {
+	case json.Number:
+		return "[" + i.String() + "]"
+	case float64:
+		return fmt.Sprintf("[%d]", int(i))
+	case int:
+		return fmt.Sprintf("[%d]", i)
+	case string:
+		return fmt.Sprintf("[%q]", i)
+	}
+	return ""
+}
+
+// expressionReferences returns the references of an expression of the plan configuration
+func expressionReferences(expression interface{}) []string {
+	var refs []string
+	e, ok := expression.(map[string]interface{})
+	if !ok {
+		return refs
+	}
+	list, _ := e["references"].([]interface{})
+	for _, r := range list {
+		if s, ok := r.(string); ok {
+			refs = append(refs, s)
+		}
+	}
+	return refs
+}
+
+// fillUnknownValues replaces the values known after apply only by the addresses of the resources
+// referenced in their expressions. Whether an attribute is a list comes from the shape of its value or of
+// after_unknown, or from isList (called with the path of the attribute) if the whole value is unknown
+func fillUnknownValues(values map[string]interface{}, unknown map[string]interface{}, expressions map[string]interface{}, resolve func([]string) []string, isList func([]string) bool, path []string) {
+	for attr, u := range unknown {
+		attrPath := append(append([]string{}, path...), attr)
+		switch u := u.(type) {
+		case bool:
+			if u {
+				var list bool
+				switch values[attr].(type) {
+				case []interface{}:
+					list = true
+				case nil:
+					// Terraform omits the wholly unknown values, lists included
+					list = isList(attrPath)
+				}
+				setResolvedValue(values, attr, expressions[attr], resolve, list)
+			}
+		case []interface{}:
+			// Nested blocks are lists of objects, attributes are lists of values
+			blocks, _ := values[attr].([]interface{})
+			blockExpressions, _ := expressions[attr].([]interface{})
+			partial := false
+			for i, element := range u {
+				switch element := element.(type) {
+				case map[string]interface{}:
+					if i < len(blocks) && i < len(blockExpressions) {
+						block, ok1 := blocks[i].(map[string]interface{})
+						blockExpression, ok2 := blockExpressions[i].(map[string]interface{})
+						if ok1 && ok2 {
+							fillUnknownValues(block, element, blockExpression, resolve, isList, attrPath)
+						}
+					}
+				case bool:
+					partial = partial || element
+				}
+			}
+			if partial {
+				setResolvedValue(values, attr, expressions[attr], resolve, true)
+			}
+		}
+	}
+}
+
+// setResolvedValue sets an attribute to the addresses referenced by its expression: the first one for a scalar
+func setResolvedValue(values map[string]interface{}, attr string, expression interface{}, resolve func([]string) []string, list bool) {
+	addresses := resolve(expressionReferences(expression))
+	if len(addresses) == 0 {
+		return
+	}
+	if list {
+		elements := make([]interface{}, 0, len(addresses))
+		for _, address := range addresses {
+			elements = append(elements, address)
+		}
+		values[attr] = elements
+	} else {
+		values[attr] = addresses[0]
+	}
+}
+
+// removeNullValues removes null attributes so that they are decoded as unset
+func removeNullValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			if element != nil {
+				result[key] = removeNullValues(element)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, element := range v {
+			if element != nil {
+				result = append(result, removeNullValues(element))
+			}
+		}
+		return result
+	}
+	return value
+}
+
+// replaceIDs replaces the strings matching a resource ID or ARN by the address of the resource
+func replaceIDs(value interface{}, ids map[string]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			result[key] = replaceIDs(element, ids)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, element := range v {
+			result = append(result, replaceIDs(element, ids))
+		}
+		return result
+	case string:
+		if address, found := ids[v]; found {
+			return address
+		}
+	}
+	return value
+}
@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// testInstance is the structure the test_instance resources are decoded in
+type testInstance struct {
+	Name				string `hcl:"name"`
+	SubnetID			*string `hcl:"subnet_id"`
+	// Scalar attribute with a plural-looking name
+	Address				*string `hcl:"address"`
+	VpcSecurityGroupIDs	*[]string `hcl:"vpc_security_group_ids"`
+	SecurityGroups		*[]string `hcl:"security_groups"`
+	Rule				[]testRule `hcl:"rule,block"`
+	Remain				hcl2.Body `hcl:",remain"`
+}
+
+type testRule struct {
+	Port				int `hcl:"port"`
+	// List attribute with a singular name
+	SourceGroup			[]string `hcl:"source_group,optional"`
+}
+
+// testProvider decodes the test_instance resources and ignores the other test_ resources
+type testProvider struct {
+	instances			map[string]testInstance
+	diags				hcl2.Diagnostics
+}
+
+func (p *testProvider) Claims(resourceType string) bool {
+	return strings.HasPrefix(resourceType, "test_")
+}
+
+func (p *testProvider) ReferencedAttributes() map[string][]string {
+	return nil
+}
+
+func (p *testProvider) IDAttributes() []string {
+	return nil
+}
+
+func (p *testProvider) ListAttribute(resourceType string, path []string) bool {
+	return resourceType == "test_instance" && utils.IsListAttribute(testInstance{}, path)
+}
+
+func (p *testProvider) DecodeResource(r Resource) bool {
+	if r.Type != "test_instance" {
+		return true
+	}
+	value, diags := utils.DecodeResource(r.Body, r.Ctx, testInstance{})
+	p.diags = append(p.diags, diags...)
+	p.instances[r.Address] = value.(testInstance)
+	return true
+}
+
+func (p *testProvider) ResolveResources() {}
+
+func (p *testProvider) CreateGraphNodes(graph *model.Graph) error {
+	return nil
+}
+
+func (p *testProvider) CreateGraphEdges(graph *model.Graph) error {
+	return nil
+}
+
+func TestParseTfPlanResolvesUnknownValues(t *testing.T) {
+	p := &testProvider{instances: make(map[string]testInstance)}
+	s := &Set{
+		log:		&utils.Logger{},
+		providers:	[]Provider{p},
+		active:		make(map[Provider]bool),
+	}
+	err := s.ParseTfPlan("testdata/plan_modules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.diags) > 0 {
+		t.Fatalf("decoding diagnostics: %s", p.diags)
+	}
+
+	for i, subnet := range []string{"module.net.test_subnet.private[0]", "module.net.test_subnet.private[1]"} {
+		address := "module.app.test_instance.web" + instanceKey(i)
+		instance, found := p.instances[address]
+		if !found {
+			t.Fatalf("%s not decoded", address)
+		}
+		// var.subnet_ids => module.net.subnet_ids => test_subnet.private, instance picked with count.index
+		if instance.SubnetID == nil || *instance.SubnetID != subnet {
+			t.Errorf("%s subnet_id = %v, want %s", address, instance.SubnetID, subnet)
+		}
+		if instance.Address == nil || *instance.Address != "test_lb.front" {
+			t.Errorf("%s address = %v, want test_lb.front", address, instance.Address)
+		}
+		want := []string{"test_security_group.web"}
+		if instance.VpcSecurityGroupIDs == nil || !reflect.DeepEqual(*instance.VpcSecurityGroupIDs, want) {
+			t.Errorf("%s vpc_security_group_ids = %v, want %v", address, instance.VpcSecurityGroupIDs, want)
+		}
+		if instance.SecurityGroups == nil || !reflect.DeepEqual(*instance.SecurityGroups, want) {
+			t.Errorf("%s security_groups = %v, want %v", address, instance.SecurityGroups, want)
+		}
+		if len(instance.Rule) != 1 || instance.Rule[0].Port != 22 || !reflect.DeepEqual(instance.Rule[0].SourceGroup, want) {
+			t.Errorf("%s rule = %+v, want port 22 from %v", address, instance.Rule, want)
+		}
+	}
+}
+
+func TestFillUnknownValues(t *testing.T) {
+	resolve := func(refs []string) []string {
+		var addresses []string
+		for _, ref := range refs {
+			addresses = append(addresses, strings.TrimSuffix(ref, ".id"))
+		}
+		return addresses
+	}
+	lists := map[string]bool{"subnets": true}
+	isList := func(path []string) bool {
+		return lists[strings.Join(path, ".")]
+	}
+	tests := []struct {
+		name		string
+		values		map[string]interface{}
+		unknown		map[string]interface{}
+		expressions	map[string]interface{}
+		want		map[string]interface{}
+	}{
+		{
+			name:			"wholly unknown scalar ending in s",
+			values:			map[string]interface{}{},
+			unknown:		map[string]interface{}{"dns": true},
+			expressions:	map[string]interface{}{"dns": map[string]interface{}{"references": []interface{}{"a.b.id"}}},
+			want:			map[string]interface{}{"dns": "a.b"},
+		},
+		{
+			name:			"wholly unknown list known by the provider",
+			values:			map[string]interface{}{},
+			unknown:		map[string]interface{}{"subnets": true},
+			expressions:	map[string]interface{}{"subnets": map[string]interface{}{"references": []interface{}{"a.b.id", "a.c.id"}}},
+			want:			map[string]interface{}{"subnets": []interface{}{"a.b", "a.c"}},
+		},
+		{
+			name:			"partially unknown list",
+			values:			map[string]interface{}{"zone_identifier": []interface{}{nil}},
+			unknown:		map[string]interface{}{"zone_identifier": []interface{}{true}},
+			expressions:	map[string]interface{}{"zone_identifier": map[string]interface{}{"references": []interface{}{"a.b.id"}}},
+			want:			map[string]interface{}{"zone_identifier": []interface{}{"a.b"}},
+		},
+		{
+			name:			"known values are kept",
+			values:			map[string]interface{}{"name": "x", "ports": []interface{}{"22"}},
+			unknown:		map[string]interface{}{"name": false, "ports": []interface{}{false}},
+			expressions:	map[string]interface{}{},
+			want:			map[string]interface{}{"name": "x", "ports": []interface{}{"22"}},
+		},
+		{
+			name:			"unknown value without references",
+			values:			map[string]interface{}{},
+			unknown:		map[string]interface{}{"arn": true},
+			expressions:	map[string]interface{}{},
+			want:			map[string]interface{}{},
+		},
+	}
+	for _, test := range tests {
+		fillUnknownValues(test.values, test.unknown, test.expressions, resolve, isList, nil)
+		if !reflect.DeepEqual(test.values, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, test.values, test.want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tfconfigs "github.com/hashicorp/terraform/configs"
+	hcl2 "github.com/hashicorp/hcl/v2"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+
+// Options are the options of a graph, shared by all the providers
+type Options struct {
+	// IgnoreIngress can be used to not create edges for ingress / inbound rules
+	IgnoreIngress		bool
+	// IgnoreEgress can be used to not create edges for egress / outbound rules
+	IgnoreEgress		bool
+	// ModuleClusters draws each TF module as its own cluster if set to true
+	ModuleClusters		bool
+	// Log collects the diagnostics and the progress of the graph
+	Log					*utils.Logger
+}
+
+// Resource is a single instance of a TF resource to decode
+type Resource struct {
+	// Type of the resource (e.g. aws_instance)
+	Type		string
+	// Address of the resource instance (e.g. module.vpc.aws_subnet.private[0])
+	Address		string
+	// Body of the resource, from HCL files or from JSON plans / states
+	Body		hcl2.Body
+	// Ctx is used for interpolation in HCL files (nil for plans / states)
+	Ctx			*hcl2.EvalContext
+	// ID is the real ID of the resource (plans and states only)
+	ID			string
+}
+
+// Provider is implemented by the packages drawing the resources of a Terraform provider (aws, azure, gcp...)
+type Provider interface {
+	// Claims returns true if the provider decodes the resources of this type
+	Claims(resourceType string) bool
+	// ReferencedAttributes lists, by resource type, the computed attributes (other than id) used to reference
+	// the resources. They are set to "<resource address>.<attribute>" in the EvalContext
+	ReferencedAttributes() map[string][]string
+	// IDAttributes lists the attributes other than id identifying all the resources of the provider (e.g. arn).
+	// They are set to the resource address in the EvalContext
+	IDAttributes() []string
+	// DecodeResource decodes a resource claimed by the provider. It returns false if the resource type is
+	// not supported
+	DecodeResource(r Resource) bool
+	// ListAttribute returns true if the attribute at path (e.g. ingress, security_groups) of a resource type is a
+	// list. It is used for the values of plans known after apply only
+	ListAttribute(resourceType string, path []string) bool
+	// ResolveResources is called once all resources are decoded, to merge the resources declared separately
+	// from the resource they belong to and link the resources referencing each other
+	ResolveResources()
+	// CreateGraphNodes creates the nodes and clusters of the decoded resources
+	CreateGraphNodes(graph *model.Graph) error
+	// CreateGraphEdges creates the edges between the nodes
+	CreateGraphEdges(graph *model.Graph) error
+}
+
+// registry lists the providers factories, indexed by provider name
+var registry = make(map[string]func(options Options) Provider)
+
+// Register makes a provider available to draw graphs. It is called by the init function of the provider package
+func Register(name string, factory func(options Options) Provider) {
+	if _, found := registry[name]; found {
+		panic(fmt.Sprintf("provider %s is already registered", name))
+	}
+	registry[name] = factory
+}
+
+// Set is the list of registered providers used to draw a graph. Resources are dispatched to the provider
+// claiming their type, so that a configuration mixing providers is drawn in a single graph
+type Set struct {
+	options			Options
+	log				*utils.Logger
+	// providers in the alphabetical order of their names
+	providers		[]Provider
+	// providers having decoded at least one resource
+	active			map[Provider]bool
+	// list of child modules (module paths)
+	modules			[]string
+	// list of unsupported resources
+	unsupportedResources	[]string
+}
+
+// New creates a Set with an instance of each registered provider
+func New(options Options) *Set {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := &Set{
+		options:	options,
+		log:		options.Log,
+		active:		make(map[Provider]bool),
+	}
+	for _, name := range names {
+		s.providers = append(s.providers, registry[name](options))
+	}
+	return s
+}
+
+// referencedAttributes returns the ReferencedAttributes of all providers
+func (s *Set) referencedAttributes() map[string][]string {
+	attributes := make(map[string][]string)
+	for _, p := range s.providers {
+		for resourceType, attrs := range p.ReferencedAttributes() {
+			attributes[resourceType] = append(attributes[resourceType], attrs...)
+		}
+	}
+	return attributes
+}
+
+// idAttributes returns id and the IDAttributes of all providers
+func (s *Set) idAttributes() []string {
+	attributes := []string{"id"}
+	for _, p := range s.providers {
+		attributes = append(attributes, p.IDAttributes()...)
+	}
+	return utils.RemoveDuplicateValues(attributes)
+}
+
+// decodeResource dispatches a resource to the provider claiming its type
+func (s *Set) decodeResource(r Resource) {
+	for _, p := range s.providers {
+		if !p.Claims(r.Type) {
+			continue
+		}
+		s.active[p] = true
+		if !p.DecodeResource(r) {
+			s.unsupportedResources = append(s.unsupportedResources, r.Address)
+		}
+		return
+	}
+	s.log.Verbosef("Can't decode %s (no provider for %s)", r.Address, r.Type)
+	s.unsupportedResources = append(s.unsupportedResources, r.Address)
+}
+
+// listAttribute asks the provider claiming a resource type if one of its attributes is a list
+func (s *Set) listAttribute(resourceType string, path []string) bool {
+	for _, p := range s.providers {
+		if p.Claims(resourceType) {
+			return p.ListAttribute(resourceType, path)
+		}
+	}
+	return false
+}
+
+// resolveResources calls ResolveResources of the providers once all resources are decoded
+func (s *Set) resolveResources() {
+	for _, p := range s.providers {
+		if s.active[p] {
+			p.ResolveResources()
+		}
+	}
+}
+
+// ParseTfResources parse the TF file / module to identify resources that will be used later on to create the graph
+func (s *Set) ParseTfResources(tfConfig *tfconfigs.Config, ctxs map[string]*hcl2.EvalContext) (error) {
+	// Parsing the root module and its child modules
+	for _, c := range tfConfig.AllModules() {
+		modulePath := c.Path.UnkeyedInstanceShim().String()
+		ctx, found := ctxs[modulePath]
+		if !found {
+			return fmt.Errorf("no EvalContext for module %s", modulePath)
+		}
+		if !c.Path.IsRoot() {
+			s.modules = append(s.modules, modulePath)
+		}
+
+		for _, v := range c.Module.ManagedResources {
+			// Expanding count / for_each: each instance is decoded with its own count.index / each.* values
+			instances, diags := utils.ExpandResource(v, ctx)
+			s.log.Diags(diags)
+			for _, i := range instances {
+				s.decodeResource(Resource{
+					Type:		v.Type,
+					Address:	utils.ModulePrefix(modulePath)+v.Type+"."+v.Name+i.Key,
+					Body:		v.Config,
+					Ctx:		i.Ctx,
+				})
+			}
+		}
+	}
+	s.resolveResources()
+
+	return nil
+}
+
+// CreateGraphNodes creates the module clusters and the nodes of each provider
+func (s *Set) CreateGraphNodes(graph *model.Graph) (error) {
+	// Add module clusters to graph (parent modules are listed before their children)
+	if s.options.ModuleClusters {
+		for _, modulePath := range s.modules {
+			err := s.createModule(graph, modulePath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range s.providers {
+		if !s.active[p] {
+			continue
+		}
+		err := p.CreateGraphNodes(graph)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateGraphEdges creates the edges of each provider
+func (s *Set) CreateGraphEdges(graph *model.Graph) (error) {
+	for _, p := range s.providers {
+		if !s.active[p] {
+			continue
+		}
+		err := p.CreateGraphEdges(graph)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Set) createModule(graph *model.Graph, modulePath string) (error) {
+	// Create module group in its parent module
+	groupID := utils.NodeID(modulePath)
+	parentPath, _, _ := utils.SplitAddress(modulePath)
+	parent := ""
+	if parentPath != "" {
+		parent = utils.NodeID(parentPath)
+	}
+	s.log.Verbosef("AddGroup: %s to %s // Create Module", groupID, parent)
+	return graph.AddGroup(&model.Group{
+		ID:			groupID,
+		Parent:		parent,
+		Kind:		model.ModuleGroup,
+		Label:		modulePath,
+		Address:	modulePath,
+	})
+}
+
+// ReportUnsupportedResources adds a warning listing all resources currently unsupported by tfviz
+func (s *Set) ReportUnsupportedResources() {
+	if len(s.unsupportedResources) > 0 {
+		s.log.Warningf("Unsupported resources:\n - %s", strings.Join(s.unsupportedResources, "\n - "))
+	}
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// hostProvider draws the test_network and test_host resources, the hosts being linked to their network
+type hostProvider struct {
+	// network of each decoded resource, indexed by address
+	resources	map[string]string
+}
+
+func init() {
+	Register("test", func(options Options) Provider {
+		return &hostProvider{resources: make(map[string]string)}
+	})
+}
+
+func (p *hostProvider) Claims(resourceType string) bool {
+	return resourceType == "test_network" || resourceType == "test_host" || resourceType == "test_unsupported"
+}
+
+func (p *hostProvider) ReferencedAttributes() map[string][]string {
+	return nil
+}
+
+func (p *hostProvider) IDAttributes() []string {
+	return nil
+}
+
+func (p *hostProvider) ListAttribute(resourceType string, path []string) bool {
+	return false
+}
+
+func (p *hostProvider) DecodeResource(r Resource) bool {
+	if r.Type == "test_unsupported" {
+		return false
+	}
+	attrs, _ := r.Body.JustAttributes()
+	network := ""
+	if attr, found := attrs["network"]; found {
+		value, diags := attr.Expr.Value(r.Ctx)
+		if !diags.HasErrors() {
+			network = value.AsString()
+		}
+	}
+	p.resources[r.Address] = network
+	return true
+}
+
+func (p *hostProvider) ResolveResources() {}
+
+func (p *hostProvider) CreateGraphNodes(graph *model.Graph) error {
+	for address := range p.resources {
+		if err := graph.AddNode(&model.Node{ID: utils.NodeID(address), Label: address, Address: address}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *hostProvider) CreateGraphEdges(graph *model.Graph) error {
+	for address, network := range p.resources {
+		if network == "" {
+			continue
+		}
+		graph.AddEdge(&model.Edge{Src: utils.NodeID(address), Dst: utils.NodeID(network), Kind: model.RouteEdge})
+	}
+	return nil
+}
+
+func TestSet(t *testing.T) {
+	log := &utils.Logger{}
+	tfConfig, err := utils.ParseTFfile(filepath.Join("testdata", "providers"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Options{Log: log})
+	ctxs, err := s.InitiateVariablesAndResources(tfConfig, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ParseTfResources(tfConfig, ctxs); err != nil {
+		t.Fatal(err)
+	}
+	graph := model.New()
+	if err := s.CreateGraphNodes(graph); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateGraphEdges(graph); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resources are dispatched to the provider claiming their type, and the references between them (id) are
+	// resolved to their addresses
+	if len(graph.Edges) != 1 || graph.Edges[0].Src != utils.NodeID("test_host.web") || graph.Edges[0].Dst != utils.NodeID("test_network.main") {
+		t.Errorf("got edges %+v, want an edge from the host to its network", graph.Edges)
+	}
+
+	// Resources not decoded by their provider, or claimed by no provider, are unsupported
+	unsupported := append([]string{}, s.unsupportedResources...)
+	sort.Strings(unsupported)
+	if want := []string{"other_host.y", "test_unsupported.x"}; !reflect.DeepEqual(unsupported, want) {
+		t.Errorf("got unsupported resources %v, want %v", unsupported, want)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a provider twice doesn't panic")
+		}
+	}()
+	Register("test", func(options Options) Provider {
+		return &hostProvider{}
+	})
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/steeve85/tfviz/utils"
+)
+
+// jsonState is a state file (terraform.tfstate, format version 4) or the output of `terraform show -json`
+type jsonState struct {
+	// Version is set for state files only
+	Version				int `json:"version"`
+	Resources			[]jsonStateResource `json:"resources"`
+	// Values is set for the output of terraform show -json only
+	Values				*jsonValues `json:"values"`
+}
+
+// jsonStateResource is a resource of a state file
+type jsonStateResource struct {
+	// Module path of the resource (empty for the root module)
+	Module				string `json:"module"`
+	Mode				string `json:"mode"`
+	Type				string `json:"type"`
+	Name				string `json:"name"`
+	Instances			[]struct {
+		// IndexKey is the count index or the for_each key of the instance (null without count / for_each)
+		IndexKey		interface{} `json:"index_key"`
+		Attributes		map[string]interface{} `json:"attributes"`
+	} `json:"instances"`
+}
+
+// ParseTfState decodes the resources of a Terraform state: a local state file (terraform.tfstate)
+// or the output of `terraform show -json`
+func (s *Set) ParseTfState(statePath string) (error) {
+	s.log.Progressf("Loading %s Terraform state...", statePath)
+	content, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		return err
+	}
+	var state jsonState
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	err = decoder.Decode(&state)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid JSON state: %s", statePath, err)
+	}
+
+	var resources []jsonResource
+	switch {
+	case state.Values != nil:
+		// terraform show -json
+		resources = flattenJSONModule(state.Values.RootModule)
+	case state.Version == 4:
+		// terraform.tfstate
+		for _, r := range state.Resources {
+			for _, i := range r.Instances {
+				resources = append(resources, jsonResource{
+					Address:	utils.ModulePrefix(r.Module) + r.Type + "." + r.Name + instanceKey(i.IndexKey),
+					Mode:		r.Mode,
+					Type:		r.Type,
+					Name:		r.Name,
+					Index:		i.IndexKey,
+					Values:		i.Attributes,
+				})
+			}
+		}
+	default:
+		return fmt.Errorf("%s: state version %d is not supported (only version 4 states are)", statePath, state.Version)
+	}
+
+	return s.parseJSONResources(resources)
+}
@@ -0,0 +1,13 @@
+resource "test_network" "main" {
+  name = "main"
+}
+
+resource "test_host" "web" {
+  network = test_network.main.id
+}
+
+resource "test_unsupported" "x" {
+}
+
+resource "other_host" "y" {
+}
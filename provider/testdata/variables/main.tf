@@ -0,0 +1,5 @@
+variable "env" {}
+variable "tfvars" {}
+variable "auto" {}
+variable "file" {}
+variable "flag" {}
@@ -0,0 +1,214 @@
+package render
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/awalterschulze/gographviz"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+
+// Options are the rendering options shared by the renderers
+type Options struct {
+	// DisableEdgeLabels can be used to not label edges with the protocols / ports of the rules
+	DisableEdgeLabels	bool
+	// IconDir is the directory the icons of the nodes are relative to: the root of the tfviz repository, containing
+	// the aws/icons, azure/icons and gcp/icons directories (current directory if empty)
+	IconDir				string
+}
+
+// internetIcon is the image of the Internet node, unless a provider sets its own
+const internetIcon = "aws/icons/internet.png"
+
+// subnetColors are the background colors of the subnets, by routing
+var subnetColors = map[string]string{
+	"public":	"#F2F6E8",
+	"private":	"#E6F2F8",
+}
+
+// DOT renders a graph in the Graphviz DOT language. Groups are drawn as clusters with an invisible node
+// used to link them
+func DOT(graph *model.Graph, options Options) (string, error) {
+	g := gographviz.NewEscape()
+	g.SetName("G")
+	g.SetDir(true)
+
+	for _, group := range graph.Groups {
+		err := g.AddSubGraph(clusterID(group.Parent), "cluster_"+group.ID, groupAttrs(group))
+		if err != nil {
+			return "", err
+		}
+		if group.Kind == model.ModuleGroup {
+			continue
+		}
+		// Adding invisible node to the group for links
+		err = g.AddNode("cluster_"+group.ID, group.ID, map[string]string{
+			"shape": "point",
+			"style": "invis",
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, node := range graph.Nodes {
+		err := g.AddNode(clusterID(node.Parent), node.ID, nodeAttrs(node, options))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		err := g.AddEdge(edge.Src, edge.Dst, true, edgeAttrs(edge, options))
+		if err != nil {
+			return "", err
+		}
+	}
+	return g.String(), nil
+}
+
+// clusterID returns the ID of the cluster of a group (G for the top level)
+func clusterID(groupID string) string {
+	if groupID == "" {
+		return "G"
+	}
+	return "cluster_" + groupID
+}
+
+func groupAttrs(group *model.Group) map[string]string {
+	attrs := map[string]string{
+		"label": utils.QuoteString(group.Label),
+	}
+	switch {
+	case group.Kind == model.ModuleGroup:
+		attrs["style"] = "dashed"
+		attrs["labeljust"] = "l"
+	case group.Address == "":
+		// Default network / subnet, not defined in TF
+	case group.Kind == model.NetworkGroup:
+		attrs["style"] = "rounded"
+		attrs["bgcolor"] = "#EDF1F2"
+		attrs["labeljust"] = "l"
+	case group.Kind == model.SubnetGroup:
+		attrs["style"] = "rounded"
+		attrs["bgcolor"] = "white"
+		if color, found := subnetColors[group.Attributes["routing"]]; found {
+			attrs["bgcolor"] = color
+		}
+		attrs["labeljust"] = "l"
+	}
+	return attrs
+}
+
+// iconPath returns the path of an icon in the icon directory (absolute paths are kept)
+func iconPath(icon string, options Options) string {
+	if filepath.IsAbs(icon) {
+		return icon
+	}
+	return filepath.Join(options.IconDir, icon)
+}
+
+func nodeAttrs(node *model.Node, options Options) map[string]string {
+	attrs := map[string]string{
+		"label": utils.QuoteString(node.Label),
+	}
+	switch {
+	case node.Kind == model.InternetNode:
+		attrs["shape"] = "none"
+		attrs["labelloc"] = "b"
+		attrs["image"] = iconPath(internetIcon, options)
+		if node.Icon != "" {
+			attrs["image"] = iconPath(node.Icon, options)
+		}
+	case node.Icon != "":
+		attrs["image"] = iconPath(node.Icon, options)
+		attrs["width"] = "1"
+		attrs["height"] = "1"
+		attrs["fixedsize"] = "true"
+		attrs["shape"] = "none"
+		if node.Public {
+			// Resources reachable from the Internet are highlighted in red
+			attrs["fontcolor"] = "red"
+		}
+	case node.Kind == model.CidrNode || node.Kind == model.ExternalNode:
+		attrs["shape"] = "box"
+		attrs["style"] = "dotted"
+	case node.Kind == model.SecurityGroupNode:
+		attrs["style"] = "dotted"
+	case node.Kind == model.ServiceNode:
+		attrs["shape"] = "box"
+		attrs["style"] = "rounded"
+	}
+	return attrs
+}
+
+func edgeAttrs(edge *model.Edge, options Options) map[string]string {
+	attrs := make(map[string]string)
+	switch edge.Kind {
+	case model.RouteEdge:
+		// Dashed to differentiate routes from rules
+		attrs["style"] = "dashed"
+	case model.ConnectionEdge:
+		// Dashed and bidirectional to differentiate connections from rules and routes
+		attrs["style"] = "dashed"
+		attrs["dir"] = "both"
+		attrs["color"] = "purple"
+		attrs["fontcolor"] = "purple"
+	case model.EndpointEdge:
+		// Blue to differentiate the traffic to cloud services that doesn't go through the Internet
+		attrs["style"] = "dashed"
+		attrs["color"] = "blue"
+		attrs["fontcolor"] = "blue"
+	case model.TriggerEdge:
+		// Bold to differentiate invocations from network flows
+		attrs["style"] = "bold"
+		attrs["color"] = "darkorange"
+		attrs["fontcolor"] = "darkorange"
+	}
+	if edge.Public {
+		// Traffic from / to the Internet is highlighted in red
+		attrs["color"] = "red"
+	}
+	if edge.Blocked() {
+		// All the flows of this edge are denied
+		attrs["color"] = "gray"
+		attrs["fontcolor"] = "gray"
+		attrs["style"] = "dotted"
+	}
+	if label := EdgeLabel(edge); !options.DisableEdgeLabels && label != "" {
+		attrs["label"] = utils.QuoteString(label)
+	}
+	return attrs
+}
+
+// FlowLabel formats a flow (e.g. tcp/22, tcp/80-443, all or tcp/22 (blocked by NACL))
+func FlowLabel(flow model.Flow) string {
+	label := flow.Description
+	if label == "" {
+		label = flow.Protocol
+		if flow.Ports != "" {
+			label += "/" + flow.Ports
+		}
+	}
+	if flow.Blocked != "" && label != "" {
+		label += " (" + flow.Blocked + ")"
+	}
+	return label
+}
+
+// EdgeLabel returns the label of an edge: its label and its flows, one per line
+func EdgeLabel(edge *model.Edge) string {
+	var lines []string
+	if edge.Label != "" {
+		lines = append(lines, edge.Label)
+	}
+	for _, f := range edge.Flows {
+		if label := FlowLabel(f); label != "" {
+			lines = append(lines, label)
+		}
+	}
+	return strings.Join(utils.RemoveDuplicateValues(lines), "\n")
+}
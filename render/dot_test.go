@@ -0,0 +1,122 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/utils"
+)
+
+func TestDOTKeepsInstanceKeysDistinct(t *testing.T) {
+	graph := model.New()
+	addresses := []string{"aws_instance.web[0]", "aws_instance.web[1]", `aws_instance.web["a-b"]`, `aws_instance.web["a_b"]`}
+	for _, address := range addresses {
+		err := graph.AddNode(&model.Node{ID: utils.NodeID(address), Kind: "aws_instance", Label: address})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(graph.Nodes) != len(addresses)+1 {
+		t.Fatalf("got %d nodes, want %d", len(graph.Nodes), len(addresses)+1)
+	}
+
+	dot, err := DOT(graph, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, address := range addresses {
+		if !strings.Contains(dot, "\t"+utils.NodeID(address)+" [") {
+			t.Errorf("node %s not found in:\n%s", address, dot)
+		}
+	}
+}
+
+func TestDOTGroups(t *testing.T) {
+	graph := model.New()
+	groups := []*model.Group{
+		{ID: "vpc", Kind: model.NetworkGroup, Label: "vpc", Address: "aws_vpc.vpc"},
+		{ID: "public", Parent: "vpc", Kind: model.SubnetGroup, Label: "public", Address: "aws_subnet.public", Attributes: map[string]string{"routing": "public"}},
+	}
+	for _, group := range groups {
+		if err := graph.AddGroup(group); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := graph.AddNode(&model.Node{ID: "web", Parent: "public", Kind: "ec2", Label: "web", Icon: "aws/icons/ec2.png", Public: true}); err != nil {
+		t.Fatal(err)
+	}
+	graph.AddEdge(&model.Edge{Src: model.InternetID, Dst: "web", Kind: model.RuleEdge, Public: true, Flows: []model.Flow{{Protocol: "tcp", Ports: "443"}}})
+	graph.AddEdge(&model.Edge{Src: "web", Dst: "vpc", Kind: model.RuleEdge, Flows: []model.Flow{{Protocol: "tcp", Ports: "22", Blocked: "blocked by NACL"}}})
+
+	dot, err := DOT(graph, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"subgraph cluster_vpc {",
+		"subgraph cluster_public {",
+		`bgcolor="#F2F6E8"`,
+		`fontcolor=red`,
+		`Internet->web[ color=red, label="tcp/443" ];`,
+		`web->vpc[ color=gray, fontcolor=gray, label="tcp/22 (blocked by NACL)", style=dotted ];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("%s not found in:\n%s", want, dot)
+		}
+	}
+
+	dot, err = DOT(graph, Options{DisableEdgeLabels: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dot, "tcp/443") {
+		t.Errorf("edge labels not disabled:\n%s", dot)
+	}
+}
+
+func TestDOTInternetIcon(t *testing.T) {
+	graph := model.New()
+	if icon := graph.Node(model.InternetID).Icon; icon != "" {
+		t.Errorf("the model must not set the icon of the Internet node, got %s", icon)
+	}
+	dot, err := DOT(graph, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dot, `image="`+internetIcon+`"`) {
+		t.Errorf("default Internet icon not found in:\n%s", dot)
+	}
+
+	graph.Node(model.InternetID).Icon = "gcp/icons/internet.png"
+	dot, err = DOT(graph, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dot, `image="gcp/icons/internet.png"`) {
+		t.Errorf("Internet icon set by the provider not found in:\n%s", dot)
+	}
+}
+
+func TestDOTIconDir(t *testing.T) {
+	graph := model.New()
+	nodes := []*model.Node{
+		{ID: "web", Kind: "ec2", Label: "web", Icon: "aws/icons/ec2.png"},
+		{ID: "custom", Kind: "custom", Label: "custom", Icon: "/usr/share/icons/custom.png"},
+	}
+	for _, node := range nodes {
+		if err := graph.AddNode(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dot, err := DOT(graph, Options{IconDir: "/opt/tfviz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The icons are relative to IconDir, absolute paths are kept
+	for _, image := range []string{"/opt/tfviz/aws/icons/internet.png", "/opt/tfviz/aws/icons/ec2.png", "/usr/share/icons/custom.png"} {
+		if !strings.Contains(dot, `image="`+image+`"`) {
+			t.Errorf("image %s not found in:\n%s", image, dot)
+		}
+	}
+}
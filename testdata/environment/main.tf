@@ -0,0 +1,9 @@
+variable "instances" {
+  default = 1
+}
+
+resource "aws_instance" "web" {
+  count         = var.instances
+  ami           = "ami-0123456789"
+  instance_type = "t3.micro"
+}
@@ -0,0 +1,149 @@
+// Package tfviz draws the network topology of Terraform configurations, plans and states.
+//
+// Render returns a provider-agnostic graph (see the model package) that can be rendered in DOT with the render
+// package. The options are given explicitly and the diagnostics are returned as values, so that several graphs
+// can be drawn at the same time from different goroutines.
+package tfviz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/provider"
+	"github.com/steeve85/tfviz/utils"
+	// Providers register themselves in their init function
+	_ "github.com/steeve85/tfviz/aws"
+	_ "github.com/steeve85/tfviz/azure"
+	_ "github.com/steeve85/tfviz/gcp"
+)
+
+// Types of input
+const (
+	// HCL is a Terraform file or a directory containing a Terraform module
+	HCL		= "hcl"
+	// Plan is a plan in JSON format (output of terraform show -json <planfile>)
+	Plan	= "plan"
+	// State is a state file (terraform.tfstate) or the output of terraform show -json
+	State	= "state"
+)
+
+// Graph is the topology of the resources, independent of the output format
+type Graph = model.Graph
+
+// Diagnostic is an error, a warning or a verbose message raised while drawing a graph
+type Diagnostic = utils.Diagnostic
+
+// Diagnostics is the list of diagnostics of a graph, in the order they were raised
+type Diagnostics = utils.Diagnostics
+
+// InputVariable is a variable of the root module, set like -var name=value or -var-file path
+type InputVariable = utils.InputVariable
+
+// Options are the options of a graph
+type Options struct {
+	// Input is the path of the Terraform file or directory, plan or state to draw
+	Input			string
+	// InputType is the type of Input: hcl (default), plan or state
+	InputType		string
+	// Variables of the root module, applied in order (hcl input only)
+	Variables		[]InputVariable
+	// Environment is the environment (e.g. os.Environ()) in which the TF_VAR_ variables of the root module are
+	// read (hcl input only). They are ignored if it is nil
+	Environment		[]string
+	// IgnoreIngress can be used to not create edges for ingress / inbound rules
+	IgnoreIngress	bool
+	// IgnoreEgress can be used to not create edges for egress / outbound rules
+	IgnoreEgress	bool
+	// DisableEdges can be used to draw the nodes only
+	DisableEdges	bool
+	// ModuleClusters draws each TF module as its own group if set to true
+	ModuleClusters	bool
+	// Verbose adds the verbose messages to the diagnostics if set to true
+	Verbose			bool
+	// IgnoreWarnings drops the warnings from the diagnostics if set to true
+	IgnoreWarnings	bool
+	// Progress is called at each step of the graph creation (nil to ignore the progress)
+	Progress		func(message string)
+}
+
+// Render parses the input and returns its graph with the diagnostics raised while drawing it. An error is
+// returned if the input can't be parsed or if ctx is done before the graph is complete
+func Render(ctx context.Context, options Options) (*Graph, Diagnostics, error) {
+	log := &utils.Logger{
+		Verbose:		options.Verbose,
+		IgnoreWarnings:	options.IgnoreWarnings,
+		Progress:		options.Progress,
+	}
+	// Resources are dispatched to the registered providers (aws, azure, gcp...) and drawn in the same graph
+	providers := provider.New(provider.Options{
+		IgnoreIngress:	options.IgnoreIngress,
+		IgnoreEgress:	options.IgnoreEgress,
+		ModuleClusters:	options.ModuleClusters,
+		Log:			log,
+	})
+
+	switch options.InputType {
+	case HCL, "":
+		tfConfig, err := utils.ParseTFfile(options.Input, log)
+		if err != nil {
+			// invalid input directory/file
+			return nil, log.Diagnostics(), err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, log.Diagnostics(), err
+		}
+
+		log.Progressf("Initiating variables and Terraform references")
+		ctxs, err := providers.InitiateVariablesAndResources(tfConfig, options.Variables, options.Environment)
+		if err != nil {
+			return nil, log.Diagnostics(), err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, log.Diagnostics(), err
+		}
+
+		log.Progressf("Parsing TF resources")
+		err = providers.ParseTfResources(tfConfig, ctxs)
+		if err != nil {
+			log.Error(err)
+		}
+	case Plan:
+		err := providers.ParseTfPlan(options.Input)
+		if err != nil {
+			return nil, log.Diagnostics(), err
+		}
+	case State:
+		err := providers.ParseTfState(options.Input)
+		if err != nil {
+			return nil, log.Diagnostics(), err
+		}
+	default:
+		return nil, nil, fmt.Errorf("input type %s is not supported", options.InputType)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, log.Diagnostics(), err
+	}
+
+	// The providers fill the graph model
+	graph := model.New()
+	log.Progressf("Creating Graph nodes")
+	err := providers.CreateGraphNodes(graph)
+	if err != nil {
+		log.Error(err)
+	}
+
+	if !options.DisableEdges {
+		if err := ctx.Err(); err != nil {
+			return nil, log.Diagnostics(), err
+		}
+		log.Progressf("Creating Graph edges")
+		err = providers.CreateGraphEdges(graph)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	providers.ReportUnsupportedResources()
+	return graph, log.Diagnostics(), nil
+}
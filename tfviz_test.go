@@ -0,0 +1,147 @@
+package tfviz
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/steeve85/tfviz/model"
+	"github.com/steeve85/tfviz/render"
+	"github.com/steeve85/tfviz/utils"
+)
+
+// renderDOT draws an example and returns the lines of its DOT output, sorted as the providers add the edges in
+// map order, and its diagnostics
+func renderDOT(input string) (string, Diagnostics, error) {
+	graph, diags, err := Render(context.Background(), Options{Input: input})
+	if err != nil {
+		return "", diags, err
+	}
+	if graph.Node(model.InternetID) == nil {
+		return "", diags, fmt.Errorf("%s: Internet node not found", input)
+	}
+	if len(graph.Nodes) < 2 {
+		return "", diags, fmt.Errorf("%s: no resource drawn", input)
+	}
+	dot, err := render.DOT(graph, render.Options{})
+	lines := strings.Split(dot, "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), diags, err
+}
+
+func TestRenderConcurrently(t *testing.T) {
+	inputs, err := filepath.Glob("examples/tf_0_1*/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no example found")
+	}
+
+	// Reference outputs, drawn one at a time
+	dots := make(map[string]string)
+	diagCounts := make(map[string]int)
+	for _, input := range inputs {
+		dot, diags, err := renderDOT(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diags.HasErrors() {
+			t.Errorf("%s: unexpected errors: %v", input, diags)
+		}
+		for _, d := range diags {
+			if d.Severity == utils.VerboseSeverity {
+				t.Errorf("%s: verbose message without Verbose: %s", input, d)
+			}
+		}
+		dots[input] = dot
+		diagCounts[input] = len(diags)
+	}
+
+	// The same examples drawn at the same time must give the same graphs and diagnostics
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*len(inputs))
+	for i := 0; i < 4; i++ {
+		for _, input := range inputs {
+			wg.Add(1)
+			go func(input string) {
+				defer wg.Done()
+				dot, diags, err := renderDOT(input)
+				switch {
+				case err != nil:
+					errs <- err
+				case dot != dots[input]:
+					errs <- fmt.Errorf("%s: graph differs from the one drawn alone", input)
+				case len(diags) != diagCounts[input]:
+					errs <- fmt.Errorf("%s: got %d diagnostics, want %d: %v", input, len(diags), diagCounts[input], diags)
+				}
+			}(input)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestRenderReturnsDiagnostics(t *testing.T) {
+	_, diags, err := Render(context.Background(), Options{Input: "examples/tf_0_12/two-tier", Verbose: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verbose := false
+	for _, d := range diags {
+		verbose = verbose || d.Severity == utils.VerboseSeverity
+	}
+	if !verbose {
+		t.Error("verbose messages must be returned in the diagnostics with Verbose")
+	}
+
+	_, _, err = Render(context.Background(), Options{Input: "examples/unknown"})
+	if err == nil {
+		t.Error("an unknown input must return an error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = Render(ctx, Options{Input: "examples/tf_0_12/two-tier"})
+	if err != context.Canceled {
+		t.Errorf("a cancelled context must return context.Canceled, got %v", err)
+	}
+}
+
+func TestRenderEnvironment(t *testing.T) {
+	// The TF_VAR_ variables are read from Options.Environment only, not from the environment of the process
+	os.Setenv("TF_VAR_instances", "3")
+	defer os.Unsetenv("TF_VAR_instances")
+	for _, test := range []struct {
+		environment	[]string
+		instances	int
+	}{
+		{nil, 1},
+		{[]string{"PATH=/bin", "TF_VAR_instances=2"}, 2},
+	} {
+		graph, diags, err := Render(context.Background(), Options{Input: filepath.Join("testdata", "environment"), Environment: test.environment})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diags.HasErrors() {
+			t.Errorf("unexpected errors: %v", diags)
+		}
+		instances := 0
+		for _, node := range graph.Nodes {
+			if strings.HasPrefix(node.Address, "aws_instance.web[") {
+				instances++
+			}
+		}
+		if instances != test.instances {
+			t.Errorf("environment %v: got %d instances, want %d", test.environment, instances, test.instances)
+		}
+	}
+}
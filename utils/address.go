@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeID converts a TF resource address (e.g. aws_instance.web[0]) into a valid Graphviz ID. Letters and digits
+// are kept, "_" is doubled and the other bytes are replaced by "_" and their hexadecimal value, so that two
+// addresses (e.g. web["a-b"] and web["a_b"]) never share an ID
+func NodeID(address string) string {
+	var id strings.Builder
+	for i := 0; i < len(address); i++ {
+		c := address[i]
+		switch {
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'):
+			id.WriteByte(c)
+		case c == '_':
+			id.WriteString("__")
+		default:
+			fmt.Fprintf(&id, "_%02x", c)
+		}
+	}
+	return id.String()
+}
+
+// LabelName splits a name in lines of 8 characters to be used as a node label
+func LabelName(name string) string {
+	return strings.Join(ChunkString(name, 8), "\n")
+}
+
+// SplitAddress splits a TF resource address (e.g. module.vpc.aws_subnet.private[0])
+// into its module path (module.vpc), resource type (aws_subnet) and name (private[0])
+func SplitAddress(address string) (string, string, string) {
+	var modulePath []string
+	rest := address
+	for strings.HasPrefix(rest, "module.") {
+		parts := strings.SplitN(rest, ".", 3)
+		if len(parts) < 3 {
+			break
+		}
+		modulePath = append(modulePath, parts[0]+"."+parts[1])
+		rest = parts[2]
+	}
+	parts := strings.SplitN(rest, ".", 2)
+	if len(parts) < 2 {
+		return strings.Join(modulePath, "."), "", rest
+	}
+	return strings.Join(modulePath, "."), parts[0], parts[1]
+}
+
+// ModulePrefix returns the prefix used in resource addresses for a module path ("" for the root module)
+func ModulePrefix(modulePath string) string {
+	if modulePath == "" {
+		return ""
+	}
+	return modulePath + "."
+}
+
+// ReferencedResource returns the address of the resource referenced by one of its attributes listed in
+// referencedAttributes (e.g. aws_launch_template.web.name => aws_launch_template.web)
+func ReferencedResource(reference string, referencedAttributes map[string][]string) string {
+	_, resourceType, _ := SplitAddress(reference)
+	for _, attr := range referencedAttributes[resourceType] {
+		if strings.HasSuffix(reference, "."+attr) {
+			return strings.TrimSuffix(reference, "."+attr)
+		}
+	}
+	return reference
+}
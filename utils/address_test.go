@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestNodeIDIsUnique(t *testing.T) {
+	addresses := []string{
+		"aws_instance.web",
+		"aws_instance.web[0]",
+		"aws_instance.web[1]",
+		"aws_instance.web_0",
+		"aws_instance.web__0",
+		`aws_instance.web["a-b"]`,
+		`aws_instance.web["a_b"]`,
+		`aws_instance.web["a.b"]`,
+		`aws_instance.web["a b"]`,
+		`aws_instance.web["a_2eb"]`,
+	}
+	ids := make(map[string]string)
+	for _, address := range addresses {
+		id := NodeID(address)
+		if other, found := ids[id]; found {
+			t.Errorf("NodeID(%q) = NodeID(%q) = %q", address, other, id)
+		}
+		ids[id] = address
+	}
+}
+
+func TestNodeIDIsAGraphvizID(t *testing.T) {
+	// Graphviz IDs are made of letters, digits and underscores (gographviz doesn't quote them)
+	for _, address := range []string{"aws_instance.web", `aws_instance.web["a-b"]`, `aws_instance.web["é"]`} {
+		id := NodeID(address)
+		for _, c := range id {
+			if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '_' {
+				t.Errorf("NodeID(%q) = %q is not a valid Graphviz ID", address, id)
+				break
+			}
+		}
+	}
+}
+
+func TestSplitAddress(t *testing.T) {
+	tests := []struct {
+		address		string
+		modulePath	string
+		resourceType	string
+		name		string
+	}{
+		{"aws_instance.web", "", "aws_instance", "web"},
+		{`aws_instance.web["a.b"]`, "", "aws_instance", `web["a.b"]`},
+		{"module.vpc.aws_subnet.private[0]", "module.vpc", "aws_subnet", "private[0]"},
+		{"module.app.module.db.aws_db_instance.main", "module.app.module.db", "aws_db_instance", "main"},
+		{"aws_vpc_default", "", "", "aws_vpc_default"},
+	}
+	for _, test := range tests {
+		modulePath, resourceType, name := SplitAddress(test.address)
+		if modulePath != test.modulePath || resourceType != test.resourceType || name != test.name {
+			t.Errorf("SplitAddress(%q) = %q, %q, %q, want %q, %q, %q", test.address, modulePath, resourceType, name, test.modulePath, test.resourceType, test.name)
+		}
+	}
+}
+
+func TestReferencedResource(t *testing.T) {
+	referencedAttributes := map[string][]string{
+		"aws_launch_template":	{"name", "latest_version"},
+	}
+	tests := map[string]string{
+		"aws_launch_template.web.name":			"aws_launch_template.web",
+		"module.app.aws_launch_template.web.latest_version":	"module.app.aws_launch_template.web",
+		"aws_launch_template.web":			"aws_launch_template.web",
+		// Attributes not listed are part of the address
+		"aws_launch_template.web.arn":			"aws_launch_template.web.arn",
+		"aws_instance.web.name":			"aws_instance.web.name",
+	}
+	for reference, want := range tests {
+		if got := ReferencedResource(reference, referencedAttributes); got != want {
+			t.Errorf("ReferencedResource(%q) = %q, want %q", reference, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+)
+
+// DecodeResource decodes the body of a resource in a new structure of the same type as value (e.g. Vpc{}) and
+// returns it
+func DecodeResource(body hcl2.Body, ctx *hcl2.EvalContext, value interface{}) (interface{}, hcl2.Diagnostics) {
+	v := reflect.New(reflect.TypeOf(value))
+	diags := gohcl.DecodeBody(body, ctx, v.Interface())
+	return v.Elem().Interface(), diags
+}
+
+// IsListAttribute returns true if the attribute at path (e.g. ingress, security_groups) is decoded as a list in a
+// structure of the same type as value. Nested blocks are followed by their block type
+func IsListAttribute(value interface{}, path []string) bool {
+	t := reflect.TypeOf(value)
+	for i, name := range path {
+		if t == nil || t.Kind() != reflect.Struct {
+			return false
+		}
+		fieldType, found := hclField(t, name)
+		if !found {
+			return false
+		}
+		fieldType = indirect(fieldType)
+		if i == len(path)-1 {
+			return fieldType.Kind() == reflect.Slice
+		}
+		// Nested block (or list of nested blocks)
+		if fieldType.Kind() == reflect.Slice {
+			fieldType = indirect(fieldType.Elem())
+		}
+		t = fieldType
+	}
+	return false
+}
+
+// hclField returns the type of the field decoding an attribute or a block, including the fields of a structure
+// decoding the remaining arguments
+func hclField(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("hcl"), ",")
+		if tag[0] == name && name != "" {
+			return field.Type, true
+		}
+		if len(tag) > 1 && tag[1] == "remain" && indirect(field.Type).Kind() == reflect.Struct {
+			if fieldType, found := hclField(indirect(field.Type), name); found {
+				return fieldType, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
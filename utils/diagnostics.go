@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+)
+
+// Severities of the diagnostics
+const (
+	// ErrorSeverity is an error that didn't prevent drawing the graph (e.g. an invalid CIDR)
+	ErrorSeverity		= "error"
+	// WarningSeverity is a warning (e.g. a Terraform diagnostic or an unsupported resource)
+	WarningSeverity		= "warning"
+	// VerboseSeverity is a message of the verbose mode
+	VerboseSeverity		= "verbose"
+)
+
+// Diagnostic is an error, a warning or a verbose message raised while drawing a graph
+type Diagnostic struct {
+	Severity	string
+	Message		string
+}
+
+// String formats a diagnostic like the command line does (e.g. [WARNING] Unsupported resources: ...)
+func (d Diagnostic) String() string {
+	return "[" + strings.ToUpper(d.Severity) + "] " + d.Message
+}
+
+// Diagnostics is the list of diagnostics of a graph, in the order they were raised
+type Diagnostics []Diagnostic
+
+// HasErrors returns true if one of the diagnostics is an error
+func (diags Diagnostics) HasErrors() bool {
+	for _, d := range diags {
+		if d.Severity == ErrorSeverity {
+			return true
+		}
+	}
+	return false
+}
+
+// Logger collects the diagnostics and reports the progress of a graph instead of printing them. Each graph has its
+// own Logger, so that several graphs can be drawn at the same time
+type Logger struct {
+	// Verbose records the verbose messages if set to true
+	Verbose			bool
+	// IgnoreWarnings drops the warnings if set to true
+	IgnoreWarnings	bool
+	// Progress is called at each step of the graph creation (nil to ignore the progress)
+	Progress		func(message string)
+
+	mu				sync.Mutex
+	diags			Diagnostics
+}
+
+func (l *Logger) add(severity string, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.diags = append(l.diags, Diagnostic{Severity: severity, Message: message})
+}
+
+// Error records an error
+func (l *Logger) Error(err error) {
+	l.add(ErrorSeverity, err.Error())
+}
+
+// Warningf records a warning
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	if !l.IgnoreWarnings {
+		l.add(WarningSeverity, fmt.Sprintf(format, args...))
+	}
+}
+
+// Diags records the diagnostics of HCL / Terraform as warnings
+func (l *Logger) Diags(diags hcl2.Diagnostics) {
+	for _, d := range diags {
+		l.Warningf("Diagnostics: %s", d.Error())
+	}
+}
+
+// Verbosef records a message of the verbose mode
+func (l *Logger) Verbosef(format string, args ...interface{}) {
+	if l.Verbose == true {
+		l.add(VerboseSeverity, fmt.Sprintf(format, args...))
+	}
+}
+
+// Progressf reports a step of the graph creation
+func (l *Logger) Progressf(format string, args ...interface{}) {
+	if l.Progress != nil {
+		l.Progress(fmt.Sprintf(format, args...))
+	}
+}
+
+// Diagnostics returns the diagnostics recorded so far
+func (l *Logger) Diagnostics() Diagnostics {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append(Diagnostics(nil), l.diags...)
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	tfconfigs "github.com/hashicorp/terraform/configs"
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// ResourceInstance is a single instance of a TF resource once count / for_each have been expanded
+type ResourceInstance struct {
+	// Key is the instance key as written in a Terraform address: "", "[0]" or "[\"a\"]"
+	Key						string
+	// Ctx is the EvalContext used to decode this instance (it defines count.index / each.key / each.value)
+	Ctx						*hcl2.EvalContext
+}
+
+// ExpandResource evaluates the count or for_each meta-argument of a resource and returns its instances.
+// If the expression can't be evaluated, a single (non indexed) instance is returned with the diagnostics.
+func ExpandResource(r *tfconfigs.Resource, ctx *hcl2.EvalContext) ([]ResourceInstance, hcl2.Diagnostics) {
+	switch {
+	case r.Count != nil:
+		return expandCount(r.Count, ctx)
+	case r.ForEach != nil:
+		return expandForEach(r.ForEach, ctx)
+	}
+	return []ResourceInstance{{Key: "", Ctx: ctx}}, nil
+}
+
+func expandCount(expr hcl2.Expression, ctx *hcl2.EvalContext) ([]ResourceInstance, hcl2.Diagnostics) {
+	fallback := []ResourceInstance{{Key: "", Ctx: ctx}}
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return fallback, diags
+	}
+	val, err := convert.Convert(val, cty.Number)
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		return fallback, append(diags, &hcl2.Diagnostic{
+			Severity: hcl2.DiagError,
+			Summary:  "Invalid count argument",
+			Detail:   "The count value must be a known whole number.",
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+	count, accuracy := val.AsBigFloat().Int64()
+	if accuracy != big.Exact || count < 0 {
+		return fallback, append(diags, &hcl2.Diagnostic{
+			Severity: hcl2.DiagError,
+			Summary:  "Invalid count argument",
+			Detail:   fmt.Sprintf("The count value %s is not a positive whole number.", val.AsBigFloat().String()),
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+
+	instances := make([]ResourceInstance, 0, count)
+	for i := int64(0); i < count; i++ {
+		child := ctx.NewChild()
+		child.Variables = map[string]cty.Value{
+			"count": cty.ObjectVal(map[string]cty.Value{
+				"index": cty.NumberIntVal(i),
+			}),
+		}
+		instances = append(instances, ResourceInstance{Key: fmt.Sprintf("[%d]", i), Ctx: child})
+	}
+	return instances, diags
+}
+
+func expandForEach(expr hcl2.Expression, ctx *hcl2.EvalContext) ([]ResourceInstance, hcl2.Diagnostics) {
+	fallback := []ResourceInstance{{Key: "", Ctx: ctx}}
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return fallback, diags
+	}
+	if val.IsNull() || !val.IsWhollyKnown() {
+		return fallback, append(diags, &hcl2.Diagnostic{
+			Severity: hcl2.DiagError,
+			Summary:  "Invalid for_each argument",
+			Detail:   "The for_each value must be a known map or set of strings.",
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+
+	// for_each accepts maps/objects (each.key is the map key) or sets of strings (each.key == each.value).
+	// Like in Terraform, lists and tuples are rejected (toset() must be used)
+	elements := make(map[string]cty.Value)
+	ty := val.Type()
+	switch {
+	case ty.IsMapType() || ty.IsObjectType():
+		for k, v := range val.AsValueMap() {
+			elements[k] = v
+		}
+	case ty.IsSetType():
+		for _, v := range val.AsValueSlice() {
+			s, err := convert.Convert(v, cty.String)
+			if err != nil || s.IsNull() {
+				return fallback, append(diags, &hcl2.Diagnostic{
+					Severity: hcl2.DiagError,
+					Summary:  "Invalid for_each argument",
+					Detail:   "A set used in for_each must only contain strings.",
+					Subject:  expr.Range().Ptr(),
+				})
+			}
+			elements[s.AsString()] = s
+		}
+	default:
+		return fallback, append(diags, &hcl2.Diagnostic{
+			Severity: hcl2.DiagError,
+			Summary:  "Invalid for_each argument",
+			Detail:   fmt.Sprintf("The for_each value must be a map or a set of strings, not %s.", ty.FriendlyName()),
+			Subject:  expr.Range().Ptr(),
+		})
+	}
+
+	keys := make([]string, 0, len(elements))
+	for k := range elements {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	instances := make([]ResourceInstance, 0, len(keys))
+	for _, k := range keys {
+		child := ctx.NewChild()
+		child.Variables = map[string]cty.Value{
+			"each": cty.ObjectVal(map[string]cty.Value{
+				"key":   cty.StringVal(k),
+				"value": elements[k],
+			}),
+		}
+		instances = append(instances, ResourceInstance{Key: fmt.Sprintf("[%q]", k), Ctx: child})
+	}
+	return instances, diags
+}
+
+// ResourceValue builds the value exposed in the EvalContext for a resource and its instances:
+// an object for single resources, a tuple for count and an object keyed by each.key for for_each.
+// attrs returns the attributes of an instance from its address (prefixed by the module path)
+func ResourceValue(prefix string, r *tfconfigs.Resource, instances []ResourceInstance, attrs func(address string) cty.Value) cty.Value {
+	switch {
+	case r.Count != nil && (len(instances) == 0 || instances[0].Key != ""):
+		if len(instances) == 0 {
+			return cty.EmptyTupleVal
+		}
+		values := make([]cty.Value, 0, len(instances))
+		for _, i := range instances {
+			values = append(values, attrs(prefix+r.Type+"."+r.Name+i.Key))
+		}
+		return cty.TupleVal(values)
+	case r.ForEach != nil && (len(instances) == 0 || instances[0].Key != ""):
+		if len(instances) == 0 {
+			return cty.EmptyObjectVal
+		}
+		values := make(map[string]cty.Value, len(instances))
+		for _, i := range instances {
+			values[i.Ctx.Variables["each"].GetAttr("key").AsString()] = attrs(prefix+r.Type+"."+r.Name+i.Key)
+		}
+		return cty.ObjectVal(values)
+	}
+	return attrs(prefix + r.Type + "." + r.Name)
+}
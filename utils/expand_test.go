@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	hcl2 "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfconfigs "github.com/hashicorp/terraform/configs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// testResource returns an aws_instance.web resource with the count or for_each expression
+func testResource(t *testing.T, count string, forEach string) *tfconfigs.Resource {
+	r := &tfconfigs.Resource{Type: "aws_instance", Name: "web"}
+	for _, e := range []struct {
+		src		string
+		expr	*hcl2.Expression
+	}{{count, &r.Count}, {forEach, &r.ForEach}} {
+		if e.src == "" {
+			continue
+		}
+		expr, diags := hclsyntax.ParseExpression([]byte(e.src), "test.tf", hcl2.InitialPos)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		*e.expr = expr
+	}
+	return r
+}
+
+// testEvalContext returns an EvalContext defining var.n, var.zones, var.names, var.list and var.unknown
+func testEvalContext() *hcl2.EvalContext {
+	return &hcl2.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{
+				"n":		cty.NumberIntVal(2),
+				"zones":	cty.MapVal(map[string]cty.Value{"b": cty.StringVal("eu-west-1b"), "a": cty.StringVal("eu-west-1a")}),
+				"names":	cty.SetVal([]cty.Value{cty.StringVal("a-b"), cty.StringVal("a_b")}),
+				"list":		cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+				"unknown":	cty.UnknownVal(cty.Number),
+			}),
+		},
+	}
+}
+
+func instanceKeys(instances []ResourceInstance) []string {
+	keys := []string{}
+	for _, i := range instances {
+		keys = append(keys, i.Key)
+	}
+	return keys
+}
+
+func TestExpandResource(t *testing.T) {
+	tests := []struct {
+		name		string
+		count		string
+		forEach		string
+		keys		[]string
+		err			bool
+	}{
+		{name: "single instance", keys: []string{""}},
+		{name: "count", count: "3", keys: []string{"[0]", "[1]", "[2]"}},
+		{name: "count from a variable", count: "var.n", keys: []string{"[0]", "[1]"}},
+		{name: "count string", count: `"2"`, keys: []string{"[0]", "[1]"}},
+		{name: "count 0", count: "0", keys: []string{}},
+		{name: "negative count", count: "-1", keys: []string{""}, err: true},
+		{name: "fractional count", count: "1.5", keys: []string{""}, err: true},
+		{name: "unknown count", count: "var.unknown", keys: []string{""}, err: true},
+		{name: "count of an undefined variable", count: "var.undefined", keys: []string{""}, err: true},
+		{name: "for_each map, sorted by key", forEach: "var.zones", keys: []string{`["a"]`, `["b"]`}},
+		{name: "for_each object", forEach: `{ db = 1, app = 2 }`, keys: []string{`["app"]`, `["db"]`}},
+		{name: "for_each set of strings", forEach: "var.names", keys: []string{`["a-b"]`, `["a_b"]`}},
+		{name: "for_each empty map", forEach: "{}", keys: []string{}},
+		{name: "for_each of a number", forEach: "3", keys: []string{""}, err: true},
+		{name: "for_each list", forEach: "var.list", keys: []string{""}, err: true},
+		{name: "for_each tuple", forEach: `["a", "b"]`, keys: []string{""}, err: true},
+	}
+	for _, test := range tests {
+		instances, diags := ExpandResource(testResource(t, test.count, test.forEach), testEvalContext())
+		if diags.HasErrors() != test.err {
+			t.Errorf("%s: got diagnostics %v, want errors %t", test.name, diags, test.err)
+		}
+		if keys := instanceKeys(instances); !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("%s: got instances %v, want %v", test.name, keys, test.keys)
+		}
+	}
+}
+
+func TestExpandResourceEvalContext(t *testing.T) {
+	// count.index and each.key / each.value are defined in the EvalContext of each instance
+	tests := []struct {
+		count		string
+		forEach		string
+		expr		string
+		want		[]cty.Value
+	}{
+		{count: "2", expr: `"web-${count.index}"`, want: []cty.Value{cty.StringVal("web-0"), cty.StringVal("web-1")}},
+		{forEach: "var.zones", expr: `"${each.key}:${each.value}"`, want: []cty.Value{cty.StringVal("a:eu-west-1a"), cty.StringVal("b:eu-west-1b")}},
+		{forEach: "var.names", expr: "each.value", want: []cty.Value{cty.StringVal("a-b"), cty.StringVal("a_b")}},
+		{count: "1", expr: "var.n", want: []cty.Value{cty.NumberIntVal(2)}},
+	}
+	for _, test := range tests {
+		instances, diags := ExpandResource(testResource(t, test.count, test.forEach), testEvalContext())
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		expr, diags := hclsyntax.ParseExpression([]byte(test.expr), "test.tf", hcl2.InitialPos)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		var got []cty.Value
+		for _, i := range instances {
+			v, diags := expr.Value(i.Ctx)
+			if diags.HasErrors() {
+				t.Fatalf("%s: %s", test.expr, diags)
+			}
+			got = append(got, v)
+		}
+		if len(got) != len(test.want) {
+			t.Fatalf("%s: got %d values, want %d", test.expr, len(got), len(test.want))
+		}
+		for i := range got {
+			if !got[i].RawEquals(test.want[i]) {
+				t.Errorf("%s: instance %d = %#v, want %#v", test.expr, i, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func TestResourceValue(t *testing.T) {
+	attrs := func(address string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal(address)})
+	}
+	tests := []struct {
+		name		string
+		count		string
+		forEach		string
+		want		cty.Value
+	}{
+		{
+			name:	"single instance",
+			want:	attrs("module.app.aws_instance.web"),
+		},
+		{
+			name:	"count",
+			count:	"2",
+			want:	cty.TupleVal([]cty.Value{attrs("module.app.aws_instance.web[0]"), attrs("module.app.aws_instance.web[1]")}),
+		},
+		{
+			name:	"count 0",
+			count:	"0",
+			want:	cty.EmptyTupleVal,
+		},
+		{
+			name:		"for_each",
+			forEach:	"var.zones",
+			want:		cty.ObjectVal(map[string]cty.Value{"a": attrs(`module.app.aws_instance.web["a"]`), "b": attrs(`module.app.aws_instance.web["b"]`)}),
+		},
+		{
+			name:		"for_each empty map",
+			forEach:	"{}",
+			want:		cty.EmptyObjectVal,
+		},
+		{
+			name:	"count that can't be evaluated",
+			count:	"var.unknown",
+			want:	attrs("module.app.aws_instance.web"),
+		},
+	}
+	for _, test := range tests {
+		r := testResource(t, test.count, test.forEach)
+		instances, _ := ExpandResource(r, testEvalContext())
+		if got := ResourceValue("module.app.", r, instances, attrs); !got.RawEquals(test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// StringPtr returns a pointer to a string, to set the optional attributes of resources
+func StringPtr(s string) *string {
+	return &s
+}
+
+// CidrMatch returns if the CIDR (or IP address) of a rule contains (full) or overlaps (partial) the peer CIDR
+func CidrMatch(ruleCidr string, peer *net.IPNet) (full bool, partial bool) {
+	if !strings.Contains(ruleCidr, "/") {
+		// Single IP address
+		ruleCidr += "/32"
+	}
+	_, ruleNet, err := net.ParseCIDR(ruleCidr)
+	if err != nil {
+		return false, false
+	}
+	ruleOnes, _ := ruleNet.Mask.Size()
+	peerOnes, _ := peer.Mask.Size()
+	if ruleNet.Contains(peer.IP) && ruleOnes <= peerOnes {
+		return true, false
+	}
+	return false, peer.Contains(ruleNet.IP)
+}
+
+// RulesAllow evaluates a list of filtering rules sorted by precedence (e.g. network ACL rules), the first
+// matching rule applying, for the traffic on the ports fromPort to toPort. portRanges are the port ranges of the
+// rules: the decision can only change at their boundaries. match returns if the rule i matches all the traffic on
+// a port (full) or a part of it only (partial, e.g. a part of the IP range or of the protocols), and if it allows
+// it. The traffic is allowed if at least a part of it is allowed, it is denied if no rule matches
+func RulesAllow(count int, fromPort int, toPort int, portRanges [][2]int, match func(i int, port int) (full bool, partial bool, allow bool)) bool {
+	ports := []int{fromPort}
+	for _, r := range portRanges {
+		for _, p := range []int{r[0], r[1] + 1} {
+			if p > fromPort && p <= toPort {
+				ports = append(ports, p)
+			}
+		}
+	}
+
+	for _, port := range ports {
+		for i := 0; i < count; i++ {
+			full, partial, allow := match(i, port)
+			if !full && !partial {
+				continue
+			}
+			if allow {
+				return true
+			}
+			if full {
+				break
+			}
+			// The deny rule matches a part of the traffic only, the next rules may allow the rest
+		}
+	}
+	return false
+}
+
+// PortRange parses a port range of a rule ("22" or "80-443", all the ports if "*" or empty)
+func PortRange(ports string) (int, int, error) {
+	if ports == "*" || ports == "" {
+		return 0, 65535, nil
+	}
+	bounds := strings.SplitN(ports, "-", 2)
+	fromPort, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	toPort := fromPort
+	if len(bounds) == 2 {
+		toPort, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return fromPort, toPort, nil
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestCidrMatch(t *testing.T) {
+	tests := []struct {
+		rule		string
+		peer		string
+		full		bool
+		partial		bool
+	}{
+		{"0.0.0.0/0", "10.0.1.0/24", true, false},
+		{"10.0.0.0/16", "10.0.1.0/24", true, false},
+		{"10.0.1.0/24", "10.0.1.0/24", true, false},
+		{"10.0.1.0/24", "10.0.0.0/16", false, true},
+		{"10.0.1.10", "10.0.1.0/24", false, true},
+		{"10.0.1.10", "10.0.1.10/32", true, false},
+		{"10.1.0.0/16", "10.0.0.0/16", false, false},
+		{"invalid", "10.0.0.0/16", false, false},
+	}
+	for _, test := range tests {
+		_, peer, err := net.ParseCIDR(test.peer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		full, partial := CidrMatch(test.rule, peer)
+		if full != test.full || partial != test.partial {
+			t.Errorf("CidrMatch(%s, %s) = %t, %t, want %t, %t", test.rule, test.peer, full, partial, test.full, test.partial)
+		}
+	}
+}
+
+func TestPortRange(t *testing.T) {
+	tests := []struct {
+		ports		string
+		fromPort	int
+		toPort		int
+		err			bool
+	}{
+		{"", 0, 65535, false},
+		{"*", 0, 65535, false},
+		{"22", 22, 22, false},
+		{"80-443", 80, 443, false},
+		{"80 - 443", 80, 443, false},
+		{"http", 0, 0, true},
+		{"80-", 0, 0, true},
+	}
+	for _, test := range tests {
+		fromPort, toPort, err := PortRange(test.ports)
+		if (err != nil) != test.err || fromPort != test.fromPort || toPort != test.toPort {
+			t.Errorf("PortRange(%q) = %d, %d, %v", test.ports, fromPort, toPort, err)
+		}
+	}
+}
+
+// testRule is a rule of the RulesAllow tests matching all (full) or a part (partial) of the traffic on a port range
+type testRule struct {
+	fromPort	int
+	toPort		int
+	full		bool
+	allow		bool
+}
+
+func TestRulesAllow(t *testing.T) {
+	tests := []struct {
+		name		string
+		rules		[]testRule
+		fromPort	int
+		toPort		int
+		want		bool
+	}{
+		{"no rule", nil, 22, 22, false},
+		{"first matching rule allows", []testRule{{22, 22, true, true}, {0, 65535, true, false}}, 22, 22, true},
+		{"first matching rule denies", []testRule{{22, 22, true, false}, {0, 65535, true, true}}, 22, 22, false},
+		{"rule of other ports skipped", []testRule{{80, 80, true, false}, {0, 65535, true, true}}, 22, 22, true},
+		{"partial deny, the rest allowed", []testRule{{0, 65535, false, false}, {0, 65535, true, true}}, 22, 22, true},
+		{"partial allow", []testRule{{0, 65535, false, true}, {0, 65535, true, false}}, 22, 22, true},
+		{"range split, the rest allowed", []testRule{{0, 1023, true, false}, {0, 65535, true, true}}, 1000, 2000, true},
+		{"range inside a deny rule", []testRule{{0, 1023, true, false}, {0, 65535, true, true}}, 80, 443, false},
+		{"range partially allowed", []testRule{{443, 443, true, true}}, 80, 8080, true},
+	}
+	for _, test := range tests {
+		var portRanges [][2]int
+		for _, rule := range test.rules {
+			portRanges = append(portRanges, [2]int{rule.fromPort, rule.toPort})
+		}
+		got := RulesAllow(len(test.rules), test.fromPort, test.toPort, portRanges, func(i int, port int) (bool, bool, bool) {
+			rule := test.rules[i]
+			if port < rule.fromPort || port > rule.toPort {
+				return false, false, false
+			}
+			return rule.full, !rule.full, rule.allow
+		})
+		if got != test.want {
+			t.Errorf("%s: RulesAllow = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
@@ -1,68 +1,55 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
 
+	version "github.com/hashicorp/go-version"
 	tfconfigs "github.com/hashicorp/terraform/configs"
 	hcl2 "github.com/hashicorp/hcl/v2"
-	"github.com/awalterschulze/gographviz"
 )
 
-// Ignorewarnings is used to ignore warnings if set to true. Default is false (warnings will be displayed)
-var Ignorewarnings bool
-
-// Verbose enables verbose mode if set to true
-var Verbose bool
+// InputVariable is a variable given on the command line with -var name=value or -var-file path
+type InputVariable struct {
+	// Flag is "var" or "var-file"
+	Flag	string
+	// Value is name=value for -var or a file path for -var-file
+	Value	string
+}
 
-// PrintError displays errors
-func PrintError(err error) {
-	e := fmt.Errorf("[ERROR] %s", err)
-	fmt.Println(e)
+// InputVariablesFlag is a repeatable command line flag. -var and -var-file share the same list
+// so that the variables are applied in the order they were given (like Terraform does)
+type InputVariablesFlag struct {
+	Flag	string
+	Items	*[]InputVariable
 }
 
-// PrintDiags diaplays warning messages
-func PrintDiags(diags hcl2.Diagnostics) {
-	if !Ignorewarnings {
-		if len(diags) == 1 {
-			fmt.Println("[WARNING] Diagnostics:", diags[0].Error())
-		} else if len(diags) > 1 {
-			fmt.Println("[WARNING] Diagnostics:")
-			for _, d := range diags {
-				fmt.Println("\t", d.Error())
-			}
+// String implements flag.Value
+func (f InputVariablesFlag) String() string {
+	if f.Items == nil {
+		return ""
+	}
+	var values []string
+	for _, i := range *f.Items {
+		if i.Flag == f.Flag {
+			values = append(values, i.Value)
 		}
 	}
+	return strings.Join(values, ", ")
 }
 
-// ExportGraphToFile exports Graph to file
-func ExportGraphToFile(outputPath string, outputFormat string, graph *gographviz.Escape) error {
-	fmt.Println("Exporting Graph to", outputPath)
-	if outputFormat == "dot" {
-		err := ioutil.WriteFile(outputPath, []byte(graph.String()), 0644)
-		if err != nil {
-			return err
-		}
-	} else {
-		tFlag := fmt.Sprintf("-T%s", outputFormat)
-		cmd := exec.Command("dot", tFlag, "-o", outputPath)
-		cmd.Stdin = strings.NewReader(graph.String())
-		if Verbose == true {
-			fmt.Printf("[VERBOSE] Running command: %s\n", cmd.String())
-		}
-		err := cmd.Run()
-		if err != nil {
-			return err
-		}
-	}
+// Set implements flag.Value
+func (f InputVariablesFlag) Set(value string) error {
+	*f.Items = append(*f.Items, InputVariable{Flag: f.Flag, Value: value})
 	return nil
 }
 
-// ParseTFfile loads a file path and returns a TF module
-func ParseTFfile(configpath string) (*tfconfigs.Module, error) {
+// ParseTFfile loads a file path and returns a TF configuration: the root module and the tree of its child modules
+func ParseTFfile(configpath string, log *Logger) (*tfconfigs.Config, error) {
 	f, err := os.Stat(configpath);
 	if err != nil {
 		return nil, err
@@ -70,49 +57,96 @@ func ParseTFfile(configpath string) (*tfconfigs.Module, error) {
 
 	tfparser := tfconfigs.NewParser(nil)
 
+	var module *tfconfigs.Module
+	var rootDir string
 	switch {
 	  case f.IsDir():
-		fmt.Println("Parsing", configpath, "Terraform module...")
+		log.Progressf("Parsing %s Terraform module...", configpath)
 		if tfparser.IsConfigDir(configpath) == false {
-			err := fmt.Errorf("[ERROR] Directory %s does not contain valid Terraform configuration files", configpath)
+			err := fmt.Errorf("Directory %s does not contain valid Terraform configuration files", configpath)
 			return nil, err
 		}
-		module, diags := tfparser.LoadConfigDir(configpath)
-		PrintDiags(diags)
-		return module, nil
+		var diags hcl2.Diagnostics
+		module, diags = tfparser.LoadConfigDir(configpath)
+		log.Diags(diags)
+		rootDir = configpath
 	  default:
-		fmt.Println("Parsing", configpath, "Terraform file...")
+		log.Progressf("Parsing %s Terraform file...", configpath)
 		file, diags := tfparser.LoadConfigFile(configpath)
 		// Return error if the TF file doesn't contain resources
 		if len(file.ManagedResources) == 0 {
-			err := fmt.Errorf("[ERROR] File %s does not contain valid Terraform configuration", configpath)
+			err := fmt.Errorf("File %s does not contain valid Terraform configuration", configpath)
 			return nil, err
 		}
-		module, moreDiags := tfconfigs.NewModule([]*tfconfigs.File{file}, nil)
+		var moreDiags hcl2.Diagnostics
+		module, moreDiags = tfconfigs.NewModule([]*tfconfigs.File{file}, nil)
 		diags = append(diags, moreDiags...)
-		PrintDiags(diags)
-		return module, nil
+		log.Diags(diags)
+		// Variable files and local modules are looked up next to the TF file
+		rootDir = filepath.Dir(configpath)
+		module.SourceDir = rootDir
 	}
+
+	// Loading child modules (module "x" { source = ... } blocks)
+	config, diags := tfconfigs.BuildConfig(module, moduleWalker(tfparser, rootDir, log))
+	log.Diags(diags)
+	return config, nil
 }
 
-// InitiateGraph initializes the graph
-func InitiateGraph() (*gographviz.Escape, error) {
-	// Graph initialization
-	g := gographviz.NewEscape()
-	g.SetName("G")
-	g.SetDir(true)
+// moduleManifest is the list of modules installed by terraform init in .terraform/modules/modules.json
+type moduleManifest struct {
+	Modules []struct {
+		// Module path joined with dots (e.g. vpc.subnets)
+		Key		string `json:"Key"`
+		// Source address of the module
+		Source	string `json:"Source"`
+		// Directory where the module was installed, relative to the root module
+		Dir		string `json:"Dir"`
+	} `json:"Modules"`
+}
 
-	if Verbose == true {
-		fmt.Println("[VERBOSE] AddNode: Internet to G")
+// moduleWalker returns a ModuleWalker loading local modules and modules already downloaded by terraform init
+func moduleWalker(tfparser *tfconfigs.Parser, rootDir string, log *Logger) tfconfigs.ModuleWalker {
+	// Loading modules.json (if terraform init has been run)
+	installed := make(map[string]string)
+	content, err := ioutil.ReadFile(filepath.Join(rootDir, ".terraform", "modules", "modules.json"))
+	if err == nil {
+		var manifest moduleManifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			log.Error(fmt.Errorf("can't read modules.json: %s", err))
+		}
+		for _, m := range manifest.Modules {
+			installed[m.Key] = m.Dir
+		}
 	}
-	// Adding node for Internet representation
-	err := g.AddNode("G", "Internet", map[string]string{
-		"shape": "none",
-		"label": "Internet",
-		"labelloc": "b",
-		"image": "./aws/icons/internet.png",
+
+	return tfconfigs.ModuleWalkerFunc(func(req *tfconfigs.ModuleRequest) (*tfconfigs.Module, *version.Version, hcl2.Diagnostics) {
+		var dir string
+		if d, found := installed[strings.Join(req.Path, ".")]; found {
+			dir = filepath.Join(rootDir, d)
+		} else if strings.HasPrefix(req.SourceAddr, "./") || strings.HasPrefix(req.SourceAddr, "../") {
+			dir = filepath.Join(req.Parent.Module.SourceDir, req.SourceAddr)
+		} else {
+			return nil, nil, hcl2.Diagnostics{{
+				Severity: hcl2.DiagWarning,
+				Summary:  "Module not installed",
+				Detail:   fmt.Sprintf("Module %s (%s) is not a local module and has not been downloaded, run terraform init to include it.", req.Path, req.SourceAddr),
+				Subject:  req.SourceAddrRange.Ptr(),
+			}}
+		}
+
+		log.Verbosef("Loading %s from %s", req.Path, dir)
+		if !tfparser.IsConfigDir(dir) {
+			return nil, nil, hcl2.Diagnostics{{
+				Severity: hcl2.DiagWarning,
+				Summary:  "Module not found",
+				Detail:   fmt.Sprintf("Directory %s of module %s does not contain valid Terraform configuration files.", dir, req.Path),
+				Subject:  req.SourceAddrRange.Ptr(),
+			}}
+		}
+		module, diags := tfparser.LoadConfigDir(dir)
+		return module, nil, diags
 	})
-	return g, err
 }
 
 // RemoveDuplicateValues removes duplicate strings in []string slices
@@ -164,3 +198,9 @@ func ChunkString(s string, chunkSize int) []string {
 	}
 	return chunks
 }
+
+// QuoteString wraps a string in double quotes so that Graphviz accepts any character in it
+// (gographviz leaves strings that are already quoted untouched)
+func QuoteString(s string) string {
+	return "\"" + strings.Replace(s, "\"", "\\\"", -1) + "\""
+}